@@ -1,22 +1,24 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
+	"p2pderivatives-oracle/internal/api"
 	"p2pderivatives-oracle/internal/database/entity"
 
 	stdlog "log"
 	"p2pderivatives-oracle/internal/dlccrypto"
 	"p2pderivatives-oracle/internal/oracle"
+	"p2pderivatives-oracle/internal/timeformat"
 
 	conf "github.com/cryptogarageinc/server-common-go/pkg/configuration"
 	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
 	"github.com/cryptogarageinc/server-common-go/pkg/log"
-	"github.com/jinzhu/gorm"
 )
 
 // "p2pderivatives-oracle/internal/api/asset_controller"
@@ -34,11 +36,6 @@ func (s *stringList) Set(value string) error {
 	return nil
 }
 
-const (
-	// TimeFormatISO8601 time format of the api using ISO8601
-	TimeFormatISO8601 = "2006-01-02T15:04:05Z"
-)
-
 var (
 	configPath  = flag.String("config", "", "Path to the configuration file to use.")
 	appName     = flag.String("appname", "", "The name of the application. Will be use as a prefix for environment variables.")
@@ -46,9 +43,16 @@ var (
 	migrate     = flag.Bool("migrate", false, "If set performs a db migration before starting.")
 	action      = flag.String("action", "", "Action")
 	asset       = flag.String("asset", "", "Asset")
-	publishdate = flag.String("publishdate", "", "Publish Date")
+	publishdate = flag.String("publishdate", "", "Publish Date (the event published at or after it is used)")
 	eventtype   = flag.String("eventtype", "", "Event Type")
 	outcome     = flag.String("outcome", "", "Outcome")
+	outOfRange  = flag.Bool("allowoutofrange", false, "Allow the create and sign actions on events published after the asset range")
+	keyname     = flag.String("name", "", "API key name")
+	role        = flag.String("role", "reader", "API key role (reader, attester or admin)")
+	rate        = flag.Int("rate", 0, "API key requests per minute (0 for the api default)")
+	burst       = flag.Int("burst", 0, "API key requests allowed at once (0 for the api default)")
+	quota       = flag.Int("quota", 0, "API key maximum announcements per day (0 for unlimited)")
+	days        = flag.Int("days", 7, "Number of days of usage to list")
 )
 
 // Config contains the configuration parameters for the server.
@@ -104,136 +108,139 @@ func main() {
 	}
 
 	if *action == "create" {
-		asset, err := entity.FindAsset(db, *asset)
+		asset, requestedPublishDate := findAssetAndDate(ormInstance)
+
+		// the oracle key is only required to sign
+		service := oracle.NewService(nil, db, nil, dlccrypto.NewCfdgoCryptoService())
+		dlcData, err := service.Announce(context.Background(), asset, *eventtype, *requestedPublishDate)
 		if err != nil {
+			fmt.Println("Could not announce the event: ", err)
 			countCommand.PrintDefaults()
 			os.Exit(1)
 		}
 
-		requestedPublishDate, err := ParseTime(*publishdate)
+		fmt.Println("dlcData", dlcData)
+	}
+
+	if *action == "sign" {
+		asset, requestedPublishDate := findAssetAndDate(ormInstance)
+
+		// Setup Oracle
+		oracleConfig := &oracle.Config{}
+		config.InitializeComponentConfig(oracleConfig)
+		oracleInstance, err := oracle.FromConfig(oracleConfig)
 		if err != nil {
-			countCommand.PrintDefaults()
+			fmt.Println("Could not create a oracle instance, Error: ", err)
 			os.Exit(1)
 		}
+		service := oracle.NewService(oracleInstance, db, nil, dlccrypto.NewCfdgoCryptoService())
 
-		fmt.Println(asset)
-		fmt.Println(asset.Description)
-
-		dlcData, err := entity.FindDLCDataPublishedAt(db, asset.AssetID, *requestedPublishDate, *eventtype)
-		if err == nil {
-			fmt.Println("Found a matchin DLC Data in db")
-			countCommand.PrintDefaults()
+		publishDate, err := service.PublishDate(asset, *requestedPublishDate)
+		if err != nil {
+			fmt.Println(err)
 			os.Exit(1)
 		}
-		if err != nil && !gorm.IsRecordNotFoundError(err) {
-			fmt.Println("Unknown DB Error")
-			countCommand.PrintDefaults()
+		eventType := *eventtype
+		if eventType == "" {
+			eventType = oracle.DefaultEventType
+		}
+		dlcData, err := entity.FindDLCDataPublishedAt(db, asset.ID, publishDate, eventType)
+		if err != nil {
+			fmt.Println("Unknown find DLC Error: ", err)
 			os.Exit(1)
 		}
-
-		cryptoInstance := dlccrypto.NewCfdgoCryptoService()
-
-		// if record is not found, need to create the record in db
-		if err != nil && gorm.IsRecordNotFoundError(err) {
-			fmt.Println("Generating new DLC data Rvalue")
-
-			signingK, rvalue, err := cryptoInstance.GenerateSchnorrKeyPair()
-
-			if err != nil {
-				fmt.Println("Unknown Crypto Service Error: ", err)
-				countCommand.PrintDefaults()
-				os.Exit(1)
-			}
-			fmt.Println("signingK", signingK)
+		if !dlcData.IsSigned() {
+			fmt.Println("Computing Signature")
+			dlcData, err = service.AttestOutcome(context.Background(), dlcData, *outcome)
 			if err != nil {
-				fmt.Println("Unknown Crypto Service Error: ", err)
-				countCommand.PrintDefaults()
+				fmt.Println("Could not attest the event: ", err)
 				os.Exit(1)
 			}
-			dlcData, err = entity.CreateDLCData(
-				db,
-				asset.AssetID,
-				*requestedPublishDate,
-				*eventtype,
-				signingK.EncodeToString(),
-				rvalue.EncodeToString())
-			if err != nil {
-				// need to retry to be sure a concurrent didn't try to create same DLCData
-				inDb, errFind := entity.FindDLCDataPublishedAt(db, asset.AssetID, *requestedPublishDate, *eventtype)
-				if errFind != nil {
-					fmt.Println("Unknown DB Error: ", err)
-					countCommand.PrintDefaults()
-					os.Exit(1)
-				}
-				dlcData = inDb
-			}
 		}
 
 		fmt.Println("dlcData", dlcData)
 	}
 
-	if *action == "sign" {
-		asset, err := entity.FindAsset(db, *asset)
+	if *action == "createkey" {
+		key, apiKey, err := api.NewIssuedAPIKey(*keyname, api.Role(*role), api.Limit{PerMinute: *rate, Burst: *burst}, *quota)
 		if err != nil {
-			countCommand.PrintDefaults()
+			fmt.Println(err)
 			os.Exit(1)
 		}
-
-		requestedPublishDate, err := ParseTime(*publishdate)
-		if err != nil {
-			countCommand.PrintDefaults()
+		if err := entity.CreateAPIKey(db, apiKey); err != nil {
+			fmt.Println("Could not create the api key: ", err)
 			os.Exit(1)
 		}
+		// only the hash is stored, the key cannot be retrieved later
+		fmt.Println("API key", apiKey.Name, "created, send it with the X-API-Key header:")
+		fmt.Println(key)
+	}
 
-		fmt.Println(asset)
-		fmt.Println(asset.Description)
-
-		dlcData, err := entity.FindDLCDataPublishedAt(db, asset.AssetID, *requestedPublishDate, *eventtype)
+	if *action == "listkeys" {
+		keys, err := entity.FindAPIKeys(db)
 		if err != nil {
-			fmt.Println("Unknown find DLC Error: ", err)
+			fmt.Println("Unknown DB Error: ", err)
 			os.Exit(1)
 		}
-		if !dlcData.IsSigned() {
-			fmt.Println("Computing Signature")
-
-			var valueMessage string
-			valueMessage = *outcome
-
-			// Setup Oracle
-			oracleConfig := &oracle.Config{}
-			config.InitializeComponentConfig(oracleConfig)
-			oracleInstance, err := oracle.FromConfig(oracleConfig)
-			if err != nil {
-				fmt.Println("Could not create a oracle instance, Error: ", err)
-				os.Exit(1)
+		for _, key := range keys {
+			status := "active"
+			if key.IsRevoked() {
+				status = "revoked at " + key.RevokedAt.UTC().Format(timeformat.ISO8601)
 			}
+			fmt.Printf("%s\trole=%s\trate=%d/min\tburst=%d\tquota=%d/day\tcreated=%s\t%s\n",
+				key.Name, key.Role, key.RatePerMinute, key.Burst, key.DailyQuota,
+				key.CreatedAt.UTC().Format(timeformat.ISO8601), status)
+		}
+	}
 
-			cryptoInstance := dlccrypto.NewCfdgoCryptoService()
-
-			kvalue, err := dlccrypto.NewPrivateKey(dlcData.Kvalue)
-			if err != nil {
-				fmt.Println("Unknown Crypto Service Error: ", err)
-				os.Exit(1)
-			}
-			sig, err := cryptoInstance.ComputeSchnorrSignature(oracleInstance.PrivateKey, kvalue, valueMessage)
-			if err != nil {
-				fmt.Println("Unknown Crypto Service Error: ", err)
-				os.Exit(1)
-			}
+	if *action == "revokekey" {
+		if err := entity.RevokeAPIKey(db, *keyname, time.Now().UTC()); err != nil {
+			fmt.Println("Could not revoke the api key: ", err)
+			os.Exit(1)
+		}
+		fmt.Println("API key", *keyname, "revoked")
+	}
 
-			dlcData, err = entity.UpdateDLCDataSignatureAndValue(
-				db,
-				dlcData.AssetID,
-				dlcData.PublishedDate,
-				dlcData.EventType,
-				sig.EncodeToString(),
-				valueMessage)
+	if *action == "usage" {
+		from := entity.UsageDay(time.Now()).AddDate(0, 0, 1-*days)
+		usages, err := entity.FindAPIUsages(db, *keyname, from)
+		if err != nil {
+			fmt.Println("Unknown DB Error: ", err)
+			os.Exit(1)
+		}
+		for _, usage := range usages {
+			fmt.Printf("%s\t%s\trequests=%d\tannouncements=%d\tattestations=%d\n",
+				usage.Day.UTC().Format("2006-01-02"), usage.Principal, usage.Requests, usage.Announcements, usage.Attestations)
+		}
+	}
+}
 
-			if err != nil {
-				fmt.Println("Unknown DB Error: ", err)
-			}
+// findAssetAndDate returns the asset and the date requested with the asset and publishdate flags,
+// the asset range is extended to the requested date if the allowoutofrange flag is set
+func findAssetAndDate(ormInstance *orm.ORM) (*oracle.Asset, *time.Time) {
+	configs, err := api.NewAssetRegistry(ormInstance, 0).AssetConfigs()
+	if err != nil {
+		fmt.Println("Unknown DB Error: ", err)
+		os.Exit(1)
+	}
+	assetConfig, ok := configs[*asset]
+	if !ok {
+		fmt.Println("Unknown asset", *asset)
+		os.Exit(1)
+	}
+	requestedPublishDate, err := timeformat.Parse(*publishdate)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	oracleAsset := oracle.NewValuedAsset(*asset, assetConfig, ormInstance.GetDB(), nil)
+	if *outOfRange {
+		// extend the range up to the requested date, plus a publication as it is rounded up to the next one
+		if upTo := time.Until(*requestedPublishDate) + oracleAsset.Frequency; upTo > oracleAsset.Range {
+			oracleAsset.Range = upTo
 		}
 	}
+	return oracleAsset, requestedPublishDate
 }
 
 func newInitializedOrm(config *conf.Configuration, log *log.Log) *orm.ORM {
@@ -258,15 +265,3 @@ func newInitializedLog(config *conf.Configuration) *log.Log {
 	logger.Initialize()
 	return logger
 }
-
-// ParseTime will try to parse a string using ISO8691 format and convert it to a time.Time
-func ParseTime(timeParam string) (*time.Time, error) {
-	timestamp, err := time.Parse(TimeFormatISO8601, timeParam)
-	if err != nil {
-		fmt.Println(err, "Invalid time format ! You should use ISO8601 ex: %s", TimeFormatISO8601)
-		os.Exit(1)
-		return nil, err
-	}
-	utc := timestamp.UTC()
-	return &utc, nil
-}
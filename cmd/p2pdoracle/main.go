@@ -2,17 +2,25 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
+	"io/ioutil"
 	stdlog "log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/bitcoind"
 	"p2pderivatives-oracle/internal/cryptocompare"
 	"p2pderivatives-oracle/internal/database/entity"
 	"p2pderivatives-oracle/internal/datafeed"
 	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/grpcapi"
+	"p2pderivatives-oracle/internal/httpfeed"
 	"p2pderivatives-oracle/internal/oracle"
+	"p2pderivatives-oracle/internal/scheduler"
 	"syscall"
 	"time"
 
@@ -21,6 +29,8 @@ import (
 	"github.com/cryptogarageinc/server-common-go/pkg/log"
 	"github.com/cryptogarageinc/server-common-go/pkg/rest/router"
 	"github.com/rs/cors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var (
@@ -36,6 +46,8 @@ type Config struct {
 	TLS      bool   `configkey:"server.tls"`
 	CertFile string `configkey:"server.certfile" validate:"required_with=TLS"`
 	KeyFile  string `configkey:"server.keyfile" validate:"required_with=TLS"`
+	// ClientCAFile CA certificates of the client certificates (optional, enables mutual TLS)
+	ClientCAFile string `configkey:"server.clientCAFile"`
 }
 
 func init() {
@@ -66,16 +78,27 @@ func main() {
 	logInstance := newInitializedLog(config)
 	log := logInstance.Logger
 
+	services := newInitializedServices(logInstance, config)
+
 	// Initialize Router
-	routerInstance := newInitializedRouter(logInstance, config)
+	routerInstance := newInitializedRouter(logInstance, services)
+
+	// Start the background schedulers
+	announcer := newStartedAnnouncer(logInstance, config, services)
+	attester := newStartedAttester(logInstance, config, services)
+	webhookDispatcher := newStartedWebhookDispatcher(logInstance, config, services)
 
 	serverConfig := &Config{}
 	config.InitializeComponentConfig(serverConfig)
 
+	// the event stream connections are closed when the server shuts down
+	streamCtx, closeStreams := context.WithCancel(context.Background())
 	srv := &http.Server{
-		Addr:    serverConfig.Address,
-		Handler: cors.Default().Handler(routerInstance.GetEngine()),
+		Addr:        serverConfig.Address,
+		Handler:     cors.Default().Handler(routerInstance.GetEngine()),
+		BaseContext: func(net.Listener) context.Context { return streamCtx },
 	}
+	srv.RegisterOnShutdown(closeStreams)
 
 	listenAndServe := func() error {
 		return srv.ListenAndServe()
@@ -90,6 +113,18 @@ func main() {
 			log.Fatal("Need to provide the path to the key file")
 		}
 
+		if serverConfig.ClientCAFile != "" {
+			caCerts, err := ioutil.ReadFile(serverConfig.ClientCAFile)
+			if err != nil {
+				log.Fatalf("Could not read the client CA file %v", err)
+			}
+			clientCAs := x509.NewCertPool()
+			if !clientCAs.AppendCertsFromPEM(caCerts) {
+				log.Fatal("Could not parse the client CA file")
+			}
+			srv.TLSConfig = &tls.Config{ClientCAs: clientCAs, ClientAuth: tls.VerifyClientCertIfGiven}
+		}
+
 		listenAndServe = func() error {
 			return srv.ListenAndServeTLS(certFile, keyFile)
 		}
@@ -103,6 +138,8 @@ func main() {
 		}
 	}()
 
+	grpcServer := newStartedGRPCServer(logInstance, config, serverConfig, srv, services)
+
 	// Wait for interrupt signal to gracefully shutdown the server with
 	// a timeout of 5 seconds.
 	quit := make(chan os.Signal)
@@ -117,7 +154,29 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			// the attestation streams are only ended by the client
+			grpcServer.Stop()
+		}
+	}
 
+	if announcer != nil {
+		announcer.Stop()
+	}
+	if attester != nil {
+		attester.Stop()
+	}
+	if webhookDispatcher != nil {
+		webhookDispatcher.Stop()
+	}
 	routerInstance.Finalize()
 	log.Println("Server exiting")
 	logInstance.Finalize()
@@ -131,7 +190,8 @@ func newInitializedLog(config *conf.Configuration) *log.Log {
 	return logger
 }
 
-func newInitializedOrm(config *conf.Configuration, log *log.Log) *orm.ORM {
+// the configured assets missing in the database are stored on migration
+func newInitializedOrm(config *conf.Configuration, log *log.Log, assets map[string]api.AssetConfig) *orm.ORM {
 	ormConfig := &orm.Config{}
 	if err := config.InitializeComponentConfig(ormConfig); err != nil {
 		panic(err)
@@ -142,9 +202,10 @@ func newInitializedOrm(config *conf.Configuration, log *log.Log) *orm.ORM {
 	if err != nil {
 		panic("Could not initialize database.")
 	}
+	entity.RegisterMetricsCallbacks(ormInstance.GetDB())
 
 	if *migrate {
-		if err := doMigration(ormInstance); err != nil {
+		if err := doMigration(ormInstance, assets); err != nil {
 			log.Logger.Fatalf("Could not apply migrations")
 			panic(err)
 		}
@@ -153,9 +214,10 @@ func newInitializedOrm(config *conf.Configuration, log *log.Log) *orm.ORM {
 	return ormInstance
 }
 
-func newInitializedRouter(log *log.Log, config *conf.Configuration) *router.Router {
-	api := NewDefaultOracleAPI(log, config)
-	routerInstance := router.NewRouter(log, api)
+func newInitializedRouter(log *log.Log, services *oracleServices) *router.Router {
+	apiInstance := api.NewOracleAPI(
+		services.apiConfig, log, services.oracle, services.orm, services.assets, services.crypto, services.feed)
+	routerInstance := router.NewRouter(log, apiInstance)
 	err := routerInstance.Initialize()
 
 	if err != nil {
@@ -165,10 +227,72 @@ func newInitializedRouter(log *log.Log, config *conf.Configuration) *router.Rout
 	return routerInstance
 }
 
-// NewDefaultOracleAPI returns a router.API with default crypto, database and datafeed services
-func NewDefaultOracleAPI(l *log.Log, config *conf.Configuration) router.API {
-	// Setup crypto service
-	cryptoInstance := dlccrypto.NewCfdgoCryptoService()
+// newStartedGRPCServer returns the started gRPC server, nil if no address is configured
+// it uses the TLS configuration and certificate of the REST server, no TLS otherwise
+func newStartedGRPCServer(
+	l *log.Log,
+	config *conf.Configuration,
+	serverConfig *Config,
+	restServer *http.Server,
+	services *oracleServices) *grpc.Server {
+	grpcConfig := &grpcapi.Config{}
+	if err := config.InitializeComponentConfig(grpcConfig); err != nil {
+		l.Logger.Fatalf("Invalid gRPC server configuration")
+		panic(err)
+	}
+	if grpcConfig.Address == "" {
+		return nil
+	}
+	server, err := grpcapi.NewServer(
+		services.apiConfig, l, services.oracle, services.orm, services.assets, services.crypto, services.feed)
+	if err != nil {
+		l.Logger.Fatalf("Could not initialize the gRPC server: %v", err)
+		panic(err)
+	}
+
+	var opts []grpc.ServerOption
+	if serverConfig.TLS {
+		certificate, err := tls.LoadX509KeyPair(serverConfig.CertFile, serverConfig.KeyFile)
+		if err != nil {
+			l.Logger.Fatalf("Could not load the server certificate: %v", err)
+			panic(err)
+		}
+		tlsConfig := &tls.Config{}
+		if restServer.TLSConfig != nil {
+			tlsConfig = restServer.TLSConfig.Clone()
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	grpcServer := server.NewGRPCServer(opts...)
+
+	listener, err := net.Listen("tcp", grpcConfig.Address)
+	if err != nil {
+		l.Logger.Fatalf("gRPC server failing to listen: %s\n", err)
+		panic(err)
+	}
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			l.Logger.Fatalf("gRPC server failing to serve: %s\n", err)
+		}
+	}()
+	return grpcServer
+}
+
+// oracleServices contains the services shared by the api and the background schedulers
+type oracleServices struct {
+	apiConfig *api.Config
+	oracle    *oracle.Oracle
+	orm       *orm.ORM
+	assets    *api.AssetRegistry
+	crypto    dlccrypto.CryptoService
+	feed      datafeed.DataFeed
+}
+
+// newInitializedServices returns the default crypto, database and datafeed services
+func newInitializedServices(l *log.Log, config *conf.Configuration) *oracleServices {
+	// Setup crypto service (counting the key generations and signatures of the api and schedulers)
+	cryptoInstance := api.InstrumentCryptoService(dlccrypto.NewCfdgoCryptoService())
 
 	// Setup Oracle
 	oracleConfig := &oracle.Config{}
@@ -179,38 +303,164 @@ func NewDefaultOracleAPI(l *log.Log, config *conf.Configuration) router.API {
 		panic(err)
 	}
 
+	apiConfig := &api.Config{}
+	err = config.InitializeComponentConfig(apiConfig)
+	if err != nil {
+		panic(err)
+	}
+
 	// Setup orm service
-	ormInstance := newInitializedOrm(config, l)
+	ormInstance := newInitializedOrm(config, l, apiConfig.AssetConfigs)
 
 	// Setup DataFeed service
-	var feedInstance datafeed.DataFeed
-	datafeedConfig := config.Sub("datafeed")
-	dummyFeedConfig := &datafeed.DummyConfig{}
-	err = datafeedConfig.InitializeComponentConfig(dummyFeedConfig)
-	if err != nil {
-		ccFeedConfig := &cryptocompare.Config{}
-		datafeedConfig.InitializeComponentConfig(ccFeedConfig)
-		cryptoCompareClient := cryptocompare.NewClient(ccFeedConfig)
-		cryptoCompareClient.Initialize()
-		feedInstance = cryptoCompareClient
-	} else {
-		feedInstance = datafeed.NewDummyDataFeed(dummyFeedConfig)
+	feedInstance := newInitializedDataFeed(config, l)
+
+	return &oracleServices{
+		apiConfig: apiConfig,
+		oracle:    oracleInstance,
+		orm:       ormInstance,
+		assets:    api.NewAssetRegistry(ormInstance, apiConfig.AssetsCacheDuration),
+		crypto:    cryptoInstance,
+		feed:      feedInstance,
 	}
+}
 
-	apiConfig := &api.Config{}
-	err = config.InitializeComponentConfig(apiConfig)
-	if err != nil {
+// newStartedAnnouncer returns the started nonce pre-announcement scheduler, nil if disabled
+func newStartedAnnouncer(l *log.Log, config *conf.Configuration, services *oracleServices) *scheduler.Announcer {
+	announcerConfig := &scheduler.AnnouncerConfig{}
+	if err := config.InitializeComponentConfig(announcerConfig); err != nil {
+		l.Logger.Fatalf("Invalid announcement scheduler configuration")
 		panic(err)
 	}
-	return api.NewOracleAPI(apiConfig, l, oracleInstance, ormInstance, cryptoInstance, feedInstance)
+	if !announcerConfig.Enabled {
+		return nil
+	}
+	announcer := scheduler.NewAnnouncer(announcerConfig, services.assets, services.orm, services.crypto, l)
+	announcer.Start()
+	return announcer
 }
 
-func doMigration(o *orm.ORM) error {
+// newStartedAttester returns the started automatic attestation scheduler, nil if disabled
+func newStartedAttester(l *log.Log, config *conf.Configuration, services *oracleServices) *scheduler.Attester {
+	attesterConfig := &scheduler.AttesterConfig{}
+	if err := config.InitializeComponentConfig(attesterConfig); err != nil {
+		l.Logger.Fatalf("Invalid attestation scheduler configuration")
+		panic(err)
+	}
+	if !attesterConfig.Enabled {
+		return nil
+	}
+	attester := scheduler.NewAttester(
+		attesterConfig,
+		services.assets,
+		services.orm,
+		services.crypto,
+		services.oracle,
+		services.feed,
+		l)
+	attester.Start()
+	return attester
+}
+
+// newStartedWebhookDispatcher returns the started webhook notification dispatcher, nil if disabled
+func newStartedWebhookDispatcher(l *log.Log, config *conf.Configuration, services *oracleServices) *scheduler.WebhookDispatcher {
+	webhookConfig := &scheduler.WebhookConfig{}
+	if err := config.InitializeComponentConfig(webhookConfig); err != nil {
+		l.Logger.Fatalf("Invalid webhook scheduler configuration")
+		panic(err)
+	}
+	if !webhookConfig.Enabled {
+		return nil
+	}
+	dispatcher := scheduler.NewWebhookDispatcher(
+		webhookConfig, services.orm, api.NewWebhookPayloadEncoder(services.oracle.PublicKey), l)
+	dispatcher.Start()
+	return dispatcher
+}
+
+// newInitializedDataFeed returns the datafeed matching the configuration
+// in order of priority: file replay, dummy and cryptocompare datafeed
+// assets served by the configured generic http sources or bitcoind metrics are routed to them
+func newInitializedDataFeed(config *conf.Configuration, l *log.Log) datafeed.DataFeed {
+	datafeedConfig := config.Sub("datafeed")
+	defaultFeed := newInitializedDefaultDataFeed(datafeedConfig, l)
+	assetFeeds := map[string]datafeed.DataFeed{}
+
+	httpFeedConfig := &httpfeed.Config{}
+	if err := datafeedConfig.InitializeComponentConfig(httpFeedConfig); err == nil {
+		httpFeeds, err := httpfeed.NewDataFeeds(httpFeedConfig)
+		if err != nil {
+			l.Logger.Fatalf("Could not initialize the http datafeeds: %v", err)
+			panic(err)
+		}
+		for asset, feed := range httpFeeds {
+			assetFeeds[asset] = feed
+		}
+	}
+
+	bitcoindFeedConfig := &bitcoind.Config{}
+	if err := datafeedConfig.InitializeComponentConfig(bitcoindFeedConfig); err == nil {
+		for asset, feed := range bitcoind.NewDataFeeds(bitcoindFeedConfig) {
+			if _, ok := assetFeeds[asset]; ok {
+				l.Logger.Fatalf("Asset %s is served by more than one datafeed", asset)
+				panic(asset)
+			}
+			assetFeeds[asset] = feed
+		}
+	}
+
+	if len(assetFeeds) == 0 {
+		return defaultFeed
+	}
+	return datafeed.NewMultiDataFeed(defaultFeed, assetFeeds)
+}
+
+func newInitializedDefaultDataFeed(datafeedConfig *conf.Configuration, l *log.Log) datafeed.DataFeed {
+
+	fileFeedConfig := &datafeed.FileConfig{}
+	if err := datafeedConfig.InitializeComponentConfig(fileFeedConfig); err == nil {
+		fileFeed := datafeed.NewFileDataFeed(fileFeedConfig)
+		if err := fileFeed.Initialize(); err != nil {
+			l.Logger.Fatalf("Could not load the datafeed replay file %s", fileFeedConfig.Path)
+			panic(err)
+		}
+		return fileFeed
+	}
+
+	dummyFeedConfig := &datafeed.DummyConfig{}
+	if err := datafeedConfig.InitializeComponentConfig(dummyFeedConfig); err == nil {
+		return datafeed.NewDummyDataFeed(dummyFeedConfig)
+	}
+
+	ccFeedConfig := &cryptocompare.Config{}
+	datafeedConfig.InitializeComponentConfig(ccFeedConfig)
+	cryptoCompareClient := cryptocompare.NewClient(ccFeedConfig)
+	cryptoCompareClient.Initialize()
+	return cryptoCompareClient
+}
+
+func doMigration(o *orm.ORM, assets map[string]api.AssetConfig) error {
 	db := o.GetDB()
-	err := db.AutoMigrate(&entity.Asset{}, &entity.DLCData{}).Error
-	err = db.Create(&entity.Asset{AssetID: "btcusd", Description: "BTC USD"}).Error
-	err = db.Create(&entity.Asset{AssetID: "ethusd", Description: "ETH USD"}).Error
-	err = db.Create(&entity.Asset{AssetID: "sushiusd", Description: "SUSHI USD"}).Error
-	err = db.Create(&entity.Asset{AssetID: "election", Description: "Election"}).Error
-	return err
+	err := db.AutoMigrate(
+		&entity.Asset{},
+		&entity.DLCData{},
+		&entity.IndexComposition{},
+		&entity.Lease{},
+		&entity.EventNotification{},
+		&entity.Cursor{},
+		&entity.Webhook{},
+		&entity.WebhookDelivery{},
+		&entity.AuditRecord{},
+		&entity.APIKey{},
+		&entity.APIUsage{}).Error
+	if err != nil {
+		return err
+	}
+	if err := entity.AddDLCDataIndexes(db); err != nil {
+		return err
+	}
+	if err := entity.BackfillDLCDataStatus(db); err != nil {
+		return err
+	}
+	return api.SeedAssets(db, assets)
 }
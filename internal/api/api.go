@@ -1,18 +1,16 @@
 package api
 
 import (
-	"fmt"
-	"net/http"
-	"p2pderivatives-oracle/internal/database/entity"
 	"p2pderivatives-oracle/internal/datafeed"
 	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/netguard"
 	"p2pderivatives-oracle/internal/oracle"
+	"p2pderivatives-oracle/internal/timeformat"
 
 	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
 	"github.com/cryptogarageinc/server-common-go/pkg/log"
 	"github.com/cryptogarageinc/server-common-go/pkg/rest/middleware"
 	"github.com/cryptogarageinc/server-common-go/pkg/rest/router"
-	"github.com/jinzhu/gorm"
 
 	"github.com/pkg/errors"
 
@@ -26,7 +24,7 @@ type Controller interface {
 
 const (
 	// TimeFormatISO8601 time format of the api using ISO8601
-	TimeFormatISO8601 = "2006-01-02T15:04:05Z"
+	TimeFormatISO8601 = timeformat.ISO8601
 )
 
 const (
@@ -34,15 +32,34 @@ const (
 	AssetBaseRoute = "/asset"
 	// OracleBaseRoute base route of oracle api
 	OracleBaseRoute = "/oracle"
+	// WebhookBaseRoute base route of webhook api
+	WebhookBaseRoute = "/webhook"
+	// StreamBaseRoute base route of event stream api
+	StreamBaseRoute = "/stream"
+	// EventBaseRoute base route of event lookup api
+	EventBaseRoute = "/event"
 )
 
 // NewOracleAPI returns a new oracle api instance
-func NewOracleAPI(config *Config, log *log.Log, oracle *oracle.Oracle, orm *orm.ORM, cryptoService dlccrypto.CryptoService, feed datafeed.DataFeed) router.API {
+func NewOracleAPI(
+	config *Config,
+	log *log.Log,
+	oracle *oracle.Oracle,
+	orm *orm.ORM,
+	assets AssetProvider,
+	cryptoService dlccrypto.CryptoService,
+	feed datafeed.DataFeed) router.API {
+	authenticator, authErr := NewAuthenticator(config)
 	return &OracleAPI{
+		authenticator: authenticator,
+		authErr:       authErr,
+		limiter:       NewConfigRateLimiter(config),
+		eventCache:    NewEventCache(config),
 		logger:        log,
 		config:        config,
 		oracle:        oracle,
 		orm:           orm,
+		assets:        assets,
 		cryptoService: cryptoService,
 		feed:          feed,
 	}
@@ -51,43 +68,58 @@ func NewOracleAPI(config *Config, log *log.Log, oracle *oracle.Oracle, orm *orm.
 // OracleAPI represents an oracle api containing the related services (like crypto service)
 type OracleAPI struct {
 	Controller
+	authenticator *Authenticator
+	authErr       error
+	limiter       *RateLimiter
+	eventCache    *EventCache
 	logger        *log.Log
 	config        *Config
 	oracle        *oracle.Oracle
 	orm           *orm.ORM
+	assets        AssetProvider
 	cryptoService dlccrypto.CryptoService
 	feed          datafeed.DataFeed
 }
 
 // Routes defines (and attached to a gin.routerGroup) the routes of the api
+// the unversioned routes are kept for compatibility and serve the v1 api
+// the GET routes require the reader role, the other routes the attester role and the admin routes the admin role
+// the health probes do not require any role, the probes and the metrics are not rate limited
 func (a *OracleAPI) Routes(route *gin.RouterGroup) {
-	NewOracleController().Routes(route.Group(OracleBaseRoute))
-	assetRoutes := []string{}
-	for assetID, config := range a.config.AssetConfigs {
-		assetRoute := fmt.Sprintf("%s/%s", AssetBaseRoute, assetID)
-		assetRoutes = append(assetRoutes, assetID)
-		NewAssetController(assetID, config).Routes(route.Group(assetRoute))
-	}
-
-	route.Group(AssetBaseRoute).GET("", func(c *gin.Context) {
-		c.JSON(http.StatusOK, assetRoutes)
-	})
-
-	route.Group(AssetBaseRoute).GET("/rvalue/:rvalue", func(c *gin.Context) {
-		db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
-		oracleInstance := c.MustGet(ContextIDOracle).(*oracle.Oracle)
-		rvalue := c.Param("rvalue")
-
-		dlcData, err := entity.FindDLCDataWithRValue(db, rvalue)
-
-		if err != nil && !gorm.IsRecordNotFoundError(err) {
-			c.Error(NewUnknownDBError(err))
-			return
-		}
+	NewHealthController(
+		a.assets, a.config.HealthExpectedPublicKey, a.config.HealthMaxClockSkew, a.config.HealthDataFeedProbeInterval).
+		Routes(route)
+	NewMetricsController(a.assets, a.config.MetricsOverdueDelay).Routes(route.Group("", Authorize(RoleReader, RoleAttester)))
+
+	limited := route.Group("", RateLimit(a.limiter))
+	public := limited.Group("", Authorize(RoleReader, RoleAttester))
+	a.v1Routes(public)
+	a.v1Routes(public.Group(APIVersion1.BaseRoute()))
+	a.v2Routes(public.Group(APIVersion2.BaseRoute()))
+	NewOpenAPIController(a.assets, a.config.SwaggerUI).Routes(public)
+
+	admin := limited.Group(AdminBaseRoute, Authorize(RoleAdmin, RoleAdmin))
+	NewAssetAdminController(a.assets).Routes(admin)
+	NewAuditController().Routes(admin)
+}
 
-		c.JSON(http.StatusOK, NewDLCDataResponse(oracleInstance.PublicKey, dlcData))
+// v1Routes defines the routes of the v1 api
+func (a *OracleAPI) v1Routes(route *gin.RouterGroup) {
+	NewOracleController().Routes(route.Group(OracleBaseRoute))
+	// also serves the event lookup by rvalue of the asset route, kept for compatibility
+	NewAssetRouter(a.assets).Routes(route.Group(AssetBaseRoute))
+	NewWebhookController(a.assets, &netguard.Guard{AllowPrivate: a.config.WebhookAllowPrivateAddresses}).Routes(route.Group(WebhookBaseRoute))
+	NewStreamController(
+		a.assets, a.config.StreamPollInterval, a.config.StreamHeartbeat, a.config.StreamGapTimeout, a.config.StreamAllowedOrigins).
+		Routes(route.Group(StreamBaseRoute))
+	NewEventController(a.assets).Routes(route.Group(EventBaseRoute))
+}
 
-	})
+// v2Routes defines the routes of the v2 api, the webhooks and event stream are only served by v1
+func (a *OracleAPI) v2Routes(route *gin.RouterGroup) {
+	NewOracleController().Routes(route.Group(OracleBaseRoute))
+	NewAssetRouterV2(a.assets).Routes(route.Group(AssetBaseRoute))
+	NewEventControllerV2(a.assets).Routes(route.Group(EventBaseRoute))
 }
 
 // GlobalMiddlewares returns the global middlewares that the api should use
@@ -95,11 +127,14 @@ func (a *OracleAPI) GlobalMiddlewares() []gin.HandlerFunc {
 	return []gin.HandlerFunc{
 		middleware.GinLogrus(a.logger.Logger),
 		middleware.RequestID(ContextIDRequestID),
+		Metrics(),
 		ErrorHandler(),
 		middleware.AddToContext(ContextIDOracle, a.oracle),
 		middleware.AddToContext(ContextIDOrm, a.orm),
 		middleware.AddToContext(ContextIDCryptoService, a.cryptoService),
 		middleware.AddToContext(ContextIDDataFeed, a.feed),
+		middleware.AddToContext(ContextIDEventCache, a.eventCache),
+		Authenticate(a.authenticator),
 	}
 }
 
@@ -111,6 +146,10 @@ func (a *OracleAPI) InitializeServices() error {
 		}
 	}
 
+	if a.authErr != nil {
+		return errors.WithMessage(a.authErr, "Invalid authentication configuration")
+	}
+
 	if a.cryptoService == nil {
 		err := errors.New("Crypto Service is not set")
 		return err
@@ -121,6 +160,16 @@ func (a *OracleAPI) InitializeServices() error {
 		return err
 	}
 
+	assets, err := a.assets.AssetConfigs()
+	if err != nil {
+		return errors.WithMessage(err, "Could not load the assets")
+	}
+	for assetID, config := range assets {
+		if _, err := datafeed.ParseSettlementMethod(config.Settlement); err != nil {
+			return errors.WithMessagef(err, "Invalid settlement method for asset %s", assetID)
+		}
+	}
+
 	return nil
 }
 
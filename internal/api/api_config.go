@@ -1,20 +1,90 @@
 package api
 
-import "time"
+import (
+	"p2pderivatives-oracle/internal/oracle"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 // Config contains the API configuration
 type Config struct {
-	AssetConfigs map[string]AssetConfig `configkey:"api.assets" validate:"required"`
+	// AssetConfigs assets stored on migration if missing in the database, the assets served are the stored ones
+	AssetConfigs map[string]AssetConfig `configkey:"api.assets"`
+	// AssetsCacheDuration duration during which the stored assets are cached
+	AssetsCacheDuration time.Duration `configkey:"api.assetsCacheDuration,duration,iso8601" default:"PT10S"`
+	// CacheSize number of signed events cached in process, not cached if 0
+	CacheSize int `configkey:"api.cache.size" default:"10000"`
+	// CacheUnsignedMaxAge duration the unsigned event responses can be cached by the clients (signed ones are immutable)
+	CacheUnsignedMaxAge time.Duration `configkey:"api.cache.unsignedMaxAge,duration,iso8601" default:"PT5S"`
+	// AuthAnonymousRole role of the requests without credentials (none or reader)
+	AuthAnonymousRole string `configkey:"api.auth.anonymousRole" default:"reader"`
+	// AuthAPIKeys api keys indexed by name (principal identity)
+	AuthAPIKeys map[string]APIKeyConfig `configkey:"api.auth.apiKeys"`
+	// AuthJWTSecret HMAC secret of the accepted HS256 JSON web tokens, the tokens are rejected if not set
+	AuthJWTSecret string `configkey:"api.auth.jwt.secret"`
+	// AuthJWTIssuer issuer required in the JSON web tokens if set
+	AuthJWTIssuer string `configkey:"api.auth.jwt.issuer"`
+	// AuthClientCerts roles of the verified TLS client certificates indexed by common name
+	AuthClientCerts map[string]string `configkey:"api.auth.clientCerts"`
+	// HealthExpectedPublicKey hex encoded public key the loaded oracle key should match (readiness check), not checked if not set
+	HealthExpectedPublicKey string `configkey:"api.health.expectedPublicKey"`
+	// HealthMaxClockSkew duration the clock can be behind the last stored notification before the readiness check fails
+	HealthMaxClockSkew time.Duration `configkey:"api.health.maxClockSkew,duration,iso8601" default:"PT1M"`
+	// HealthDataFeedProbeInterval duration during which the datafeed reachability probe result is cached
+	HealthDataFeedProbeInterval time.Duration `configkey:"api.health.datafeedProbeInterval,duration,iso8601" default:"PT30S"`
+	// MetricsOverdueDelay delay after the publication date from which an unsigned event is reported as overdue
+	MetricsOverdueDelay time.Duration `configkey:"api.metrics.overdueDelay,duration,iso8601" default:"PT5M"`
+	// RateLimitAnonymousPerMinute requests per minute allowed per client ip without credentials, unlimited if 0
+	RateLimitAnonymousPerMinute int `configkey:"api.rateLimit.anonymous.perMinute" default:"30"`
+	// RateLimitAnonymousBurst requests allowed at once per client ip without credentials
+	RateLimitAnonymousBurst int `configkey:"api.rateLimit.anonymous.burst" default:"10"`
+	// RateLimitAnonymousDailyQuota announcements (events created) per UTC day allowed per client ip without credentials, unlimited if 0
+	RateLimitAnonymousDailyQuota int `configkey:"api.rateLimit.anonymous.dailyQuota" default:"100"`
+	// RateLimitPrincipalPerMinute requests per minute allowed per authenticated principal (if the key has no own limit), unlimited if 0
+	RateLimitPrincipalPerMinute int `configkey:"api.rateLimit.principal.perMinute" default:"600"`
+	// RateLimitPrincipalBurst requests allowed at once per authenticated principal
+	RateLimitPrincipalBurst int `configkey:"api.rateLimit.principal.burst" default:"100"`
+	// StreamPollInterval interval at which the event stream connections poll the new notifications
+	StreamPollInterval time.Duration `configkey:"api.stream.pollInterval,duration,iso8601" default:"PT1S"`
+	// StreamHeartbeat interval at which an idle event stream connection is kept alive
+	StreamHeartbeat time.Duration `configkey:"api.stream.heartbeat,duration,iso8601" default:"PT15S"`
+	// StreamGapTimeout delay after which a missing notification sequence is considered as rolled back,
+	// the following notifications are not streamed before it is committed or this delay expires
+	StreamGapTimeout time.Duration `configkey:"api.stream.gapTimeout,duration,iso8601" default:"PT10S"`
+	// StreamAllowedOrigins origins from which the browsers can open websocket streams ("*" for any origin)
+	StreamAllowedOrigins []string `configkey:"api.stream.allowedOrigins"`
+	// SwaggerUI serves a Swagger UI browsing the OpenAPI document if true
+	SwaggerUI bool `configkey:"api.openapi.swaggerUI"`
+	// WebhookAllowPrivateAddresses accepts webhook urls resolving to loopback, private or link-local addresses
+	// (shared with the webhook dispatcher, for development only)
+	WebhookAllowPrivateAddresses bool `configkey:"scheduler.webhook.allowPrivateAddresses"`
+}
+
+// APIKeyConfig represents an api key, only its hash is configured
+type APIKeyConfig struct {
+	// SHA256 hex encoded sha256 of the api key
+	SHA256 string `configkey:"sha256" validate:"required"`
+	Role   string `configkey:"role" validate:"required"`
 }
 
 // AssetConfig represents one asset configuration delivered by the oracle
-type AssetConfig struct {
-	Asset       string            `configkey:"asset" validate:"required"`
-	Currency    string            `configkey:"currency" validate:"required"`
-	HasDecimals bool              `configkey:"hasDecimals"`
-	StartDate   time.Time         `configkey:"startDate" validate:"required"`
-	Frequency   time.Duration     `configkey:"frequency,duration,iso8601" validate:"required"`
-	RangeD      time.Duration     `configkey:"range,duration,iso8601" validate:"required"`
-	Test        map[string]string `configkey:"test"`
-	EventTypes  map[string]bool   `configkey:"eventTypes"`
+type AssetConfig = oracle.AssetConfig
+
+// IndexVersionConfig represents a version of an index composition
+type IndexVersionConfig = oracle.IndexVersionConfig
+
+// IndexComponentConfig represents one component of an index composition
+type IndexComponentConfig = oracle.IndexComponentConfig
+
+var assetIDPattern = regexp.MustCompile("^[a-z0-9_-]+$")
+
+// ValidateAssetID returns an error if the asset id is not lower case alphanumeric (or - and _),
+// or is the segment of the v1 event lookup by rvalue which would shadow the asset routes
+func ValidateAssetID(assetID string) error {
+	if !assetIDPattern.MatchString(assetID) || assetID == rvalueAlias {
+		return errors.Errorf("asset id %s should be lower case alphanumeric (or - and _) and not %s", assetID, rvalueAlias)
+	}
+	return nil
 }
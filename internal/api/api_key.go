@@ -0,0 +1,61 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"p2pderivatives-oracle/internal/database/entity"
+
+	"github.com/pkg/errors"
+)
+
+// apiKeyPrefix prefix of the issued api keys, making them recognizable in logs or secret scanners
+const apiKeyPrefix = "p2pdo_"
+
+// HashAPIKey returns the hex encoded sha256 of an api key, as configured or stored
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// NewIssuedAPIKey returns a new random api key and its database model (storing only its hash),
+// the rate limit and daily announcement quota use the api defaults if 0
+func NewIssuedAPIKey(name string, role Role, limit Limit, dailyQuota int) (string, *entity.APIKey, error) {
+	if name == "" || name == anonymousPrincipal {
+		return "", nil, errors.Errorf("invalid api key name %s", name)
+	}
+	if _, ok := roleLevels[role]; !ok || role == RoleNone {
+		return "", nil, errors.Errorf("invalid api key role %s", role)
+	}
+	if limit.PerMinute < 0 || limit.Burst < 0 || dailyQuota < 0 {
+		return "", nil, errors.New("the rate limit and quota should not be negative")
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, errors.WithMessage(err, "could not generate the api key")
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+	return key, &entity.APIKey{
+		Name:          name,
+		SHA256:        HashAPIKey(key),
+		Role:          string(role),
+		RatePerMinute: limit.PerMinute,
+		Burst:         limit.Burst,
+		DailyQuota:    dailyQuota,
+	}, nil
+}
+
+// newIssuedKeyPrincipal returns the principal of an issued api key
+func newIssuedKeyPrincipal(key *entity.APIKey) (*Principal, error) {
+	role, err := ParseRole(key.Role)
+	if err != nil || role == RoleNone {
+		return nil, errors.Errorf("invalid role %s of api key %s", key.Role, key.Name)
+	}
+	return &Principal{
+		ID:         key.Name,
+		Method:     AuthMethodAPIKey,
+		Role:       role,
+		Limit:      Limit{PerMinute: key.RatePerMinute, Burst: key.Burst},
+		DailyQuota: key.DailyQuota,
+	}, nil
+}
@@ -45,6 +45,7 @@ func SetupTestOracleAPI(mockct *gomock.Controller) (router.API, error) {
 		test.NewLogger(),
 		oracleService,
 		test.NewOrm(),
+		api.StaticAssets(apiConfig.AssetConfigs),
 		crypto,
 		feed), nil
 }
@@ -87,3 +88,30 @@ func TestOracleAPI_FinalizeServices_NoError(t *testing.T) {
 	err = oracleApi.FinalizeServices()
 	assert.NoError(t, err)
 }
+
+func TestOracleAPI_ProbesAndMetrics_NotRateLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	apiConfig := &api.Config{}
+	assert.NoError(t, test.InitializeConfig(apiConfig))
+	apiConfig.RateLimitAnonymousPerMinute, apiConfig.RateLimitAnonymousBurst = 1, 1
+	oracleService, err := NewTestOracleService()
+	assert.NoError(t, err)
+	oracleAPI := api.NewOracleAPI(apiConfig, test.NewLogger(), oracleService, test.NewOrm(),
+		api.StaticAssets(apiConfig.AssetConfigs), mock_dlccrypto.NewMockCryptoService(ctrl), mock_datafeed.NewMockDataFeed(ctrl))
+	_, r := SetupEngine(httptest.NewRecorder(), oracleAPI, oracleAPI.GlobalMiddlewares()...)
+
+	serve := func(route string) int {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, route, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		r.ServeHTTP(resp, req)
+		return resp.Code
+	}
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve(api.RouteGETHealthz))
+		assert.NotEqual(t, http.StatusTooManyRequests, serve(api.RouteGETMetrics))
+	}
+	publicKeyRoute := api.OracleBaseRoute + api.RouteGETOraclePublicKey
+	assert.NotEqual(t, http.StatusTooManyRequests, serve(publicKeyRoute))
+	assert.Equal(t, http.StatusTooManyRequests, serve(publicKeyRoute))
+}
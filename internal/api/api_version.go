@@ -0,0 +1,81 @@
+package api
+
+import (
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/dlccrypto"
+)
+
+// APIVersion represents a version of the api response model
+type APIVersion string
+
+const (
+	// APIVersion1 api returning the DLC data responses (also served by the unversioned routes)
+	APIVersion1 APIVersion = "v1"
+	// APIVersion2 api returning the structured event responses
+	APIVersion2 APIVersion = "v2"
+)
+
+// BaseRoute returns the base route of the api version
+func (v APIVersion) BaseRoute() string {
+	return "/" + string(v)
+}
+
+// newEventResponse returns the response of an asset event for the api version
+func (v APIVersion) newEventResponse(oraclePubKey *dlccrypto.SchnorrPublicKey, dlcData *entity.DLCData, config AssetConfig) interface{} {
+	if v == APIVersion2 {
+		return NewV2EventResponse(oraclePubKey, dlcData, config)
+	}
+	return NewDLCDataResponse(oraclePubKey, dlcData)
+}
+
+// newEventDescriptorResponse returns the response of an event lookup for the api version
+func (v APIVersion) newEventDescriptorResponse(oraclePubKey *dlccrypto.SchnorrPublicKey, dlcData *entity.DLCData, config AssetConfig) interface{} {
+	if v == APIVersion2 {
+		return NewV2EventResponse(oraclePubKey, dlcData, config)
+	}
+	return NewEventDescriptorResponse(NewDLCDataResponse(oraclePubKey, dlcData), dlcData, config)
+}
+
+// newEventPageResponse returns the response of a page of asset events for the api version
+func (v APIVersion) newEventPageResponse(
+	oraclePubKey *dlccrypto.SchnorrPublicKey,
+	dlcDataList []entity.DLCData,
+	config AssetConfig,
+	nextCursor string) interface{} {
+	if v == APIVersion2 {
+		response := &V2EventPageResponse{Events: []*V2EventResponse{}, NextCursor: nextCursor}
+		for i := range dlcDataList {
+			response.Events = append(response.Events, NewV2EventResponse(oraclePubKey, &dlcDataList[i], config))
+		}
+		return response
+	}
+	response := &DLCDataPageResponse{Events: []*DLCDataResponse{}, NextCursor: nextCursor}
+	for i := range dlcDataList {
+		response.Events = append(response.Events, NewDLCDataResponse(oraclePubKey, &dlcDataList[i]))
+	}
+	return response
+}
+
+// newBatchResponse returns the response of a batch request in request order for the api version
+func (v APIVersion) newBatchResponse(oraclePubKey *dlccrypto.SchnorrPublicKey, items []*batchItem, config AssetConfig) interface{} {
+	if v == APIVersion2 {
+		response := make([]*V2BatchItemResponse, len(items))
+		for i, item := range items {
+			if item.err != nil {
+				response[i] = &V2BatchItemResponse{Error: item.err}
+			} else {
+				response[i] = &V2BatchItemResponse{Event: NewV2EventResponse(oraclePubKey, item.dlcData, config)}
+			}
+		}
+		return response
+	}
+	response := make([]*BatchItemResponse, len(items))
+	for i, item := range items {
+		if item.err != nil {
+			response[i] = &BatchItemResponse{Error: item.err}
+		} else {
+			response[i] = &BatchItemResponse{Event: NewDLCDataResponse(oraclePubKey, item.dlcData)}
+		}
+	}
+	return response
+}
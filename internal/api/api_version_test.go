@@ -0,0 +1,133 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/oracle"
+	"p2pderivatives-oracle/test"
+	mock_datafeed "p2pderivatives-oracle/test/mock/datafeed"
+	mock_dlccrypto "p2pderivatives-oracle/test/mock/dlccrypto"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testVersionSignedRvalue   = "signed rvalue"
+	testVersionUnsignedRvalue = "unsigned rvalue"
+)
+
+func SetupVersionedOracleAPI(t *testing.T) (*oracle.Oracle, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	oracleInstance, err := NewTestOracleService()
+	require.NoError(t, err)
+	ormInstance := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{})
+	db := ormInstance.GetDB()
+	db.Create(TestAsset)
+	publishDate := InDbDLCData.PublishedDate
+	_, err = entity.CreateDLCData(db, TestAsset.AssetID, publishDate, "digits", "signed kvalue", testVersionSignedRvalue)
+	require.NoError(t, err)
+	_, err = entity.UpdateDLCDataAttestation(db, TestAsset.AssetID, publishDate, "digits",
+		entity.DLCData{Signature: testEventSignature, Value: "9000", SettlementMethod: "close"})
+	require.NoError(t, err)
+	_, err = entity.CreateDLCData(db, TestAsset.AssetID, publishDate.Add(TestAssetConfig.Frequency), "digits", "unsigned kvalue", testVersionUnsignedRvalue)
+	require.NoError(t, err)
+
+	apiConfig := &api.Config{AssetConfigs: map[string]api.AssetConfig{TestAsset.AssetID: *TestAssetConfig}}
+	oracleAPI := api.NewOracleAPI(
+		apiConfig,
+		test.NewLogger(),
+		oracleInstance,
+		ormInstance,
+		api.StaticAssets(apiConfig.AssetConfigs),
+		mock_dlccrypto.NewMockCryptoService(ctrl),
+		mock_datafeed.NewMockDataFeed(ctrl))
+	engine := gin.New()
+	engine.Use(oracleAPI.GlobalMiddlewares()...)
+	oracleAPI.Routes(engine.Group(""))
+	return oracleInstance, engine
+}
+
+func GetVersionedRoute(t *testing.T, engine *gin.Engine, route string, response interface{}) int {
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, route, nil)
+	engine.ServeHTTP(resp, req)
+	if resp.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), response), route)
+	}
+	return resp.Code
+}
+
+func TestOracleAPI_V1AndUnversionedRoutes_ReturnDLCDataResponses(t *testing.T) {
+	_, engine := SetupVersionedOracleAPI(t)
+	eventID := api.NewEventID(TestAsset.AssetID, "digits", InDbDLCData.PublishedDate)
+
+	for _, version := range []string{"", api.APIVersion1.BaseRoute()} {
+		descriptor := &api.EventDescriptorResponse{}
+		route := version + api.EventBaseRoute + "/id/" + eventID
+		if assert.Equal(t, http.StatusOK, GetVersionedRoute(t, engine, route, descriptor), route) {
+			assert.Equal(t, testVersionSignedRvalue, descriptor.Rvalue)
+			assert.Equal(t, testEventSignature, descriptor.Signature)
+		}
+		page := &api.DLCDataPageResponse{}
+		route = version + api.AssetBaseRoute + "/" + TestAsset.AssetID + api.RouteGETAssetEvents
+		if assert.Equal(t, http.StatusOK, GetVersionedRoute(t, engine, route, page), route) {
+			assert.Len(t, page.Events, 2)
+		}
+	}
+}
+
+func TestOracleAPI_V2Routes_ReturnV2EventResponses(t *testing.T) {
+	oracleInstance, engine := SetupVersionedOracleAPI(t)
+	eventID := api.NewEventID(TestAsset.AssetID, "digits", InDbDLCData.PublishedDate)
+
+	event := &api.V2EventResponse{}
+	route := api.APIVersion2.BaseRoute() + api.EventBaseRoute + "/id/" + eventID
+	if assert.Equal(t, http.StatusOK, GetVersionedRoute(t, engine, route, event)) {
+		assert.Equal(t, eventID, event.EventID)
+		assert.Equal(t, oracleInstance.PublicKey.EncodeToString(), event.KeyID)
+		assert.Equal(t, entity.DLCDataStatusAttested, event.Status)
+		assert.Equal(t, TestAsset.AssetID, event.Descriptor.AssetID)
+		assert.Equal(t, TestAssetConfig.Asset, event.Descriptor.Asset)
+		assert.Equal(t, api.V2OutcomeTypeNumeric, event.Descriptor.OutcomeType)
+		assert.Equal(t, []string{testVersionSignedRvalue}, event.Nonces)
+		assert.Equal(t, []string{testEventSignature}, event.Signatures)
+		if assert.NotNil(t, event.Outcome) {
+			assert.Equal(t, "9000", event.Outcome.Value)
+			assert.Equal(t, "close", event.Outcome.Settlement)
+		}
+		assert.Equal(t, InDbDLCData.PublishedDate, event.Timestamps.PublishAt)
+		assert.NotNil(t, event.Timestamps.AttestedAt)
+	}
+
+	unsigned := &api.V2EventResponse{}
+	route = api.APIVersion2.BaseRoute() + api.EventBaseRoute + "/rvalue/" + testVersionUnsignedRvalue
+	if assert.Equal(t, http.StatusOK, GetVersionedRoute(t, engine, route, unsigned)) {
+		assert.Equal(t, entity.DLCDataStatusAnnounced, unsigned.Status)
+		assert.Equal(t, []string{testVersionUnsignedRvalue}, unsigned.Nonces)
+		assert.Empty(t, unsigned.Signatures)
+		assert.Nil(t, unsigned.Outcome)
+		assert.Nil(t, unsigned.Timestamps.AttestedAt)
+	}
+
+	page := &api.V2EventPageResponse{}
+	route = api.APIVersion2.BaseRoute() + api.AssetBaseRoute + "/" + TestAsset.AssetID + api.RouteGETAssetEvents + "?status=unsigned"
+	if assert.Equal(t, http.StatusOK, GetVersionedRoute(t, engine, route, page)) && assert.Len(t, page.Events, 1) {
+		assert.Equal(t, []string{testVersionUnsignedRvalue}, page.Events[0].Nonces)
+	}
+}
+
+func TestOracleAPI_V2Routes_WebhooksAndStreamNotServed(t *testing.T) {
+	_, engine := SetupVersionedOracleAPI(t)
+	for _, route := range []string{api.WebhookBaseRoute, api.StreamBaseRoute, api.AssetBaseRoute + "/rvalue/" + testVersionSignedRvalue} {
+		route = api.APIVersion2.BaseRoute() + route
+		assert.Equal(t, http.StatusNotFound, GetVersionedRoute(t, engine, route, nil), route)
+	}
+}
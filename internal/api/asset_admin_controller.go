@@ -0,0 +1,273 @@
+package api
+
+import (
+	"net/http"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/datafeed"
+	"strings"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/cryptogarageinc/server-common-go/pkg/utils/iso8601"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+const (
+	// AdminBaseRoute base route of the admin api
+	AdminBaseRoute = "/admin"
+	// RouteAdminAsset relative route of the asset collection
+	RouteAdminAsset = "/asset"
+	// RouteAdminAssetID relative route of an asset
+	RouteAdminAssetID = RouteAdminAsset + "/:" + URLParamTagID
+)
+
+// AssetRequest represents the asset creation or update request body,
+// the configuration uses the representation of the asset configuration route
+type AssetRequest struct {
+	// ID asset id, only used on creation
+	ID          string `json:"id,omitempty"`
+	Description string `json:"description,omitempty"`
+	AssetConfigResponse
+}
+
+// AssetResponse represents a stored asset and its configuration
+type AssetResponse struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	AssetConfigResponse
+}
+
+// invalidator is implemented by the asset providers caching the stored assets
+type invalidator interface {
+	Invalidate()
+}
+
+// AssetAdminController represents the asset administration api Controller
+type AssetAdminController struct {
+	assets AssetProvider
+}
+
+// NewAssetAdminController creates a new Controller structure with the given parameters.
+func NewAssetAdminController(assets AssetProvider) Controller {
+	return &AssetAdminController{
+		assets: assets,
+	}
+}
+
+// Routes list and binds all routes to the router group provided
+func (ct *AssetAdminController) Routes(route *gin.RouterGroup) {
+	route.GET(RouteAdminAsset, ct.GetAssets)
+	route.POST(RouteAdminAsset, ct.PostAsset)
+	route.GET(RouteAdminAssetID, ct.GetAsset)
+	route.PUT(RouteAdminAssetID, ct.PutAsset)
+	route.DELETE(RouteAdminAssetID, ct.DeleteAsset)
+}
+
+// GetAssets handler returns all the stored assets
+func (ct *AssetAdminController) GetAssets(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Admin Assets")
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	assets, err := entity.FindAssets(db)
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	response := make([]*AssetResponse, len(assets))
+	for i := range assets {
+		if response[i], err = NewAssetResponse(&assets[i]); err != nil {
+			c.Error(NewUnknownInternalError(err, "Asset configuration"))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAsset handler returns a stored asset
+func (ct *AssetAdminController) GetAsset(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Admin Asset")
+	assetID := c.Param(URLParamTagID)
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	asset, err := entity.FindAsset(db, assetID)
+	if err != nil {
+		c.Error(assetDBError(err, assetID))
+		return
+	}
+	ct.respondAsset(c, http.StatusOK, asset)
+}
+
+// PostAsset handler creates an asset, served from then on without restart
+func (ct *AssetAdminController) PostAsset(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Post Admin Asset")
+	request, asset, err := bindAssetRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := ValidateAssetID(request.ID); err != nil {
+		c.Error(NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, err, "id"))
+		return
+	}
+	asset.AssetID = request.ID
+
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	if err := entity.CreateAsset(db, asset); err != nil {
+		c.Error(assetDBError(err, request.ID))
+		return
+	}
+	ct.invalidate()
+	ct.respondAsset(c, http.StatusCreated, asset)
+}
+
+// PutAsset handler replaces the configuration of an asset, the announced events are kept
+func (ct *AssetAdminController) PutAsset(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Put Admin Asset")
+	_, asset, err := bindAssetRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	asset.AssetID = c.Param(URLParamTagID)
+
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	updated, err := entity.UpdateAsset(db, asset)
+	if err != nil {
+		c.Error(assetDBError(err, asset.AssetID))
+		return
+	}
+	ct.invalidate()
+	ct.respondAsset(c, http.StatusOK, updated)
+}
+
+// DeleteAsset handler deletes an asset, its events are kept but not served anymore
+func (ct *AssetAdminController) DeleteAsset(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Delete Admin Asset")
+	assetID := c.Param(URLParamTagID)
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	if err := entity.DeleteAsset(db, assetID); err != nil {
+		c.Error(assetDBError(err, assetID))
+		return
+	}
+	ct.invalidate()
+	c.Status(http.StatusNoContent)
+}
+
+func (ct *AssetAdminController) invalidate() {
+	if cache, ok := ct.assets.(invalidator); ok {
+		cache.Invalidate()
+	}
+}
+
+func (ct *AssetAdminController) respondAsset(c *gin.Context, status int, asset *entity.Asset) {
+	response, err := NewAssetResponse(asset)
+	if err != nil {
+		c.Error(NewUnknownInternalError(err, "Asset configuration"))
+		return
+	}
+	c.JSON(status, response)
+}
+
+// NewAssetResponse transforms an entity.Asset to an asset response
+func NewAssetResponse(asset *entity.Asset) (*AssetResponse, error) {
+	config, err := NewAssetConfig(asset)
+	if err != nil {
+		return nil, err
+	}
+	return &AssetResponse{
+		ID:                  asset.AssetID,
+		Description:         asset.Description,
+		AssetConfigResponse: *NewAssetConfigResponse(*config),
+	}, nil
+}
+
+func assetDBError(err error, assetID string) error {
+	switch {
+	case gorm.IsRecordNotFoundError(err):
+		return NewRecordNotFoundDBError(err, "asset "+assetID)
+	case errors.Is(err, entity.ErrAssetAlreadyExists):
+		return NewRecordAlreadyExistsDBError(err, "asset "+assetID)
+	default:
+		return NewUnknownDBError(err)
+	}
+}
+
+// bindAssetRequest returns the validated asset request and its database model (without asset id)
+func bindAssetRequest(c *gin.Context) (*AssetRequest, *entity.Asset, error) {
+	request := &AssetRequest{}
+	if err := c.ShouldBindJSON(request); err != nil {
+		return nil, nil, NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, err, "body")
+	}
+	config, err := newAssetConfigFromRequest(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	description := request.Description
+	if description == "" {
+		description = strings.ToUpper(config.Asset + " " + config.Currency)
+	}
+	asset, err := NewAssetEntity("", description, *config)
+	if err != nil {
+		return nil, nil, NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, err, "index")
+	}
+	return request, asset, nil
+}
+
+// newAssetConfigFromRequest returns the asset configuration of a request, validated as the configured assets
+func newAssetConfigFromRequest(request *AssetRequest) (*AssetConfig, error) {
+	invalid := func(field string, format string, args ...interface{}) error {
+		return NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, errors.Errorf(format, args...), field)
+	}
+	if request.Asset == "" || request.Currency == "" {
+		return nil, invalid("asset", "asset and currency are required")
+	}
+	if request.StartDate.IsZero() {
+		return nil, invalid("startDate", "start date is required")
+	}
+	frequency, err := iso8601.ParseDuration(request.Frequency)
+	if err != nil || frequency <= 0 {
+		return nil, invalid("frequency", "frequency should be a positive ISO8601 duration %s", request.Frequency)
+	}
+	rangeD, err := iso8601.ParseDuration(request.RangeD)
+	if err != nil || rangeD <= 0 {
+		return nil, invalid("range", "range should be a positive ISO8601 duration %s", request.RangeD)
+	}
+	if _, err := datafeed.ParseSettlementMethod(request.Settlement); err != nil {
+		return nil, NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, err, "settlement")
+	}
+
+	config := &AssetConfig{
+		Asset:       request.Asset,
+		Currency:    request.Currency,
+		HasDecimals: request.HasDecimals,
+		StartDate:   request.StartDate.UTC(),
+		Frequency:   frequency,
+		RangeD:      rangeD,
+		EventTypes:  request.EventTypes,
+		Settlement:  request.Settlement,
+	}
+	for _, composition := range request.Index {
+		if composition.Version == "" || len(composition.Components) == 0 {
+			return nil, invalid("index", "index versions should have a version name and components")
+		}
+		version := IndexVersionConfig{
+			EffectiveFrom: composition.EffectiveFrom.UTC(),
+			Components:    map[string]IndexComponentConfig{},
+		}
+		for _, component := range composition.Components {
+			if component.Name == "" || component.Asset == "" || component.Weight == 0 {
+				return nil, invalid("index", "index components should have a name, an asset and a weight")
+			}
+			version.Components[component.Name] = IndexComponentConfig{
+				Asset:    component.Asset,
+				Currency: component.Currency,
+				Weight:   component.Weight,
+			}
+		}
+		if config.Index == nil {
+			config.Index = map[string]IndexVersionConfig{}
+		}
+		config.Index[composition.Version] = version
+	}
+	return config, nil
+}
@@ -0,0 +1,165 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAdminToken  = "admin secret key"
+	testReaderToken = "reader secret key"
+)
+
+func NewTestAPIKeyConfig(key string, role api.Role) api.APIKeyConfig {
+	return api.APIKeyConfig{SHA256: api.HashAPIKey(key), Role: string(role)}
+}
+
+func SetupAdminOracleAPI(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	oracleInstance, err := NewTestOracleService()
+	require.NoError(t, err)
+	ormInstance := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.AuditRecord{}, &entity.APIKey{}, &entity.APIUsage{})
+	require.NoError(t, api.SeedAssets(ormInstance.GetDB(), map[string]api.AssetConfig{TestAsset.AssetID: *TestAssetConfig}))
+
+	apiConfig := &api.Config{
+		AuthAnonymousRole: string(api.RoleReader),
+		AuthAPIKeys: map[string]api.APIKeyConfig{
+			"operator": NewTestAPIKeyConfig(testAdminToken, api.RoleAdmin),
+			"client":   NewTestAPIKeyConfig(testReaderToken, api.RoleReader),
+		},
+	}
+	// cached longer than the test so that the assets are only reloaded on invalidation
+	registry := api.NewAssetRegistry(ormInstance, time.Hour)
+	oracleAPI := api.NewOracleAPI(apiConfig, test.NewLogger(), oracleInstance, ormInstance, registry, nil, nil)
+	engine := gin.New()
+	engine.Use(oracleAPI.GlobalMiddlewares()...)
+	oracleAPI.Routes(engine.Group(""))
+	return engine
+}
+
+func DoAdminRequest(engine *gin.Engine, method string, route string, token string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, route, bytes.NewReader(payload))
+	if token != "" {
+		req.Header.Set(api.HeaderAPIKey, token)
+	}
+	engine.ServeHTTP(resp, req)
+	return resp
+}
+
+func NewTestAssetRequest(id string, frequency string) *api.AssetRequest {
+	return &api.AssetRequest{
+		ID: id,
+		AssetConfigResponse: api.AssetConfigResponse{
+			Asset:      "eth",
+			Currency:   "usd",
+			StartDate:  TestAssetConfig.StartDate,
+			Frequency:  frequency,
+			RangeD:     "P2DT",
+			EventTypes: map[string]bool{"digits": true},
+			Settlement: "close",
+		},
+	}
+}
+
+func TestAssetAdminController_WithoutAdminRole_ReturnsUnauthorizedOrForbidden(t *testing.T) {
+	engine := SetupAdminOracleAPI(t)
+	route := api.AdminBaseRoute + api.RouteAdminAsset
+
+	resp := DoAdminRequest(engine, http.MethodGet, route, "", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	resp = DoAdminRequest(engine, http.MethodGet, route, "unknown key", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	resp = DoAdminRequest(engine, http.MethodPost, route, testReaderToken, NewTestAssetRequest("ethusd", "PT1H"))
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	records := []*api.AuditRecordResponse{}
+	resp = DoAdminRequest(engine, http.MethodGet, api.AdminBaseRoute+api.RouteGETAdminAudit, testAdminToken, nil)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &records))
+	// the audit request itself is recorded after its response
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "client", records[0].Principal)
+		assert.Equal(t, http.StatusForbidden, records[0].Status)
+		assert.Equal(t, api.AuthMethodAnonymous, records[1].AuthMethod)
+		assert.Equal(t, http.StatusUnauthorized, records[1].Status)
+	}
+}
+
+func TestAssetAdminController_AssetLifecycle_ServedWithoutRestart(t *testing.T) {
+	engine := SetupAdminOracleAPI(t)
+	adminRoute := api.AdminBaseRoute + api.RouteAdminAsset
+	configRoute := api.AssetBaseRoute + "/ethusd" + api.RouteGETAssetConfig
+	config := &api.AssetConfigResponse{}
+	assert.Equal(t, http.StatusNotFound, GetVersionedRoute(t, engine, configRoute, config))
+
+	resp := DoAdminRequest(engine, http.MethodPost, adminRoute, testAdminToken, NewTestAssetRequest("ethusd", "PT1H"))
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	created := &api.AssetResponse{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), created))
+	assert.Equal(t, "ethusd", created.ID)
+	assert.Equal(t, "ETH USD", created.Description)
+
+	assetIDs := []string{}
+	if assert.Equal(t, http.StatusOK, GetVersionedRoute(t, engine, api.AssetBaseRoute, &assetIDs)) {
+		assert.Equal(t, []string{"btcusd", "ethusd"}, assetIDs)
+	}
+	for _, route := range []string{configRoute, api.APIVersion2.BaseRoute() + configRoute} {
+		if assert.Equal(t, http.StatusOK, GetVersionedRoute(t, engine, route, config), route) {
+			assert.Equal(t, "PT1H", config.Frequency)
+		}
+	}
+
+	resp = DoAdminRequest(engine, http.MethodPut, adminRoute+"/ethusd", testAdminToken, NewTestAssetRequest("", "PT2H"))
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	if assert.Equal(t, http.StatusOK, GetVersionedRoute(t, engine, configRoute, config)) {
+		assert.Equal(t, "PT2H", config.Frequency)
+	}
+
+	resp = DoAdminRequest(engine, http.MethodDelete, adminRoute+"/ethusd", testAdminToken, nil)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, http.StatusNotFound, GetVersionedRoute(t, engine, configRoute, config))
+	resp = DoAdminRequest(engine, http.MethodGet, adminRoute+"/ethusd", testAdminToken, nil)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestAssetAdminController_PostAsset_Existing_ReturnsConflict(t *testing.T) {
+	engine := SetupAdminOracleAPI(t)
+
+	resp := DoAdminRequest(engine, http.MethodPost, api.AdminBaseRoute+api.RouteAdminAsset, testAdminToken,
+		NewTestAssetRequest(TestAsset.AssetID, "PT1H"))
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	apiError := &api.ErrorResponse{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), apiError))
+	assert.Equal(t, api.RecordAlreadyExistsDBErrorCode, apiError.ErrorCode)
+}
+
+func TestAssetAdminController_PostAsset_Invalid_ReturnsBadRequest(t *testing.T) {
+	engine := SetupAdminOracleAPI(t)
+	invalidSettlement := NewTestAssetRequest("ethusd", "PT1H")
+	invalidSettlement.Settlement = "unknown"
+
+	requests := []*api.AssetRequest{
+		NewTestAssetRequest("ethusd", "one hour"),
+		NewTestAssetRequest("ETH/USD", "PT1H"),
+		NewTestAssetRequest("rvalue", "PT1H"),
+		invalidSettlement,
+	}
+	for _, request := range requests {
+		resp := DoAdminRequest(engine, http.MethodPost, api.AdminBaseRoute+api.RouteAdminAsset, testAdminToken, request)
+		assert.Equal(t, http.StatusBadRequest, resp.Code, resp.Body.String())
+	}
+}
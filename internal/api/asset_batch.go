@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/oracle"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+	"github.com/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// RoutePOSTAssetRvalues relative POST route to retrieve the rvalues of several events
+	RoutePOSTAssetRvalues = "/rvalues"
+	// RoutePOSTAssetSignatures relative POST route to retrieve the signatures of several events
+	RoutePOSTAssetSignatures = "/signatures"
+	// MaxBatchSize maximum number of events of a batch request
+	MaxBatchSize = 100
+)
+
+// BatchRequest represents a batch request of asset events
+type BatchRequest struct {
+	Events []BatchEventRequest `json:"events"`
+}
+
+// BatchEventRequest represents an event of a batch request, the event type is digits if not set
+type BatchEventRequest struct {
+	Time      string `json:"time"`
+	EventType string `json:"eventType"`
+}
+
+// batchItem represents an event of a batch request being processed
+type batchItem struct {
+	publishDate time.Time
+	eventType   string
+	dlcData     *entity.DLCData
+	err         *Error
+}
+
+// PostAssetRvalues handler returns the rvalues of the requested events in request order
+// the missing events are created in a single transaction, invalid events are returned as item errors
+func (ct *AssetController) PostAssetRvalues(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Post Asset Rvalues")
+	items, err := ct.parseBatchRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	service := assetServiceOf(c, nil)
+	if err := service.findOrCreateBatch(c.Request.Context(), ct.assetID, ct.config, items); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, ct.version.newBatchResponse(service.Oracle.PublicKey, items, ct.config))
+}
+
+// PostAssetSignatures handler returns the signatures of the requested events in request order
+// the missing events are created in a single transaction and the unsigned ones are attested,
+// events not yet published or which cannot be attested are returned as item errors
+func (ct *AssetController) PostAssetSignatures(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Post Asset Signatures")
+	items, err := ct.parseBatchRequest(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	now := time.Now().UTC()
+	for _, item := range items {
+		if item.err == nil && item.publishDate.After(now) {
+			cause := errors.Errorf("Oracle cannot sign a value not yet known, retry after %s", item.publishDate.String())
+			item.err = NewTooEarlyError(InvalidTimeTooEarlyBadRequestErrorCode, cause, item.publishDate.Sub(now))
+		}
+	}
+
+	service := assetServiceOf(c, nil)
+	if err := service.findOrCreateBatch(c.Request.Context(), ct.assetID, ct.config, items); err != nil {
+		c.Error(err)
+		return
+	}
+	oracleService := service.oracleService()
+	asset := oracle.NewValuedAsset(ct.assetID, ct.config, service.DB, service.Feed)
+	ctx := service.meteredContext(c.Request.Context())
+	for _, item := range items {
+		if item.err != nil || item.dlcData.IsSigned() {
+			continue
+		}
+		dlcData, err := oracleService.AttestEvent(ctx, asset, item.dlcData)
+		if err != nil {
+			item.err = toAPIError(oracleError(err))
+			continue
+		}
+		// the same event can be requested several times
+		for _, other := range items {
+			if other.dlcData == item.dlcData {
+				other.dlcData = dlcData
+			}
+		}
+	}
+	c.JSON(http.StatusOK, ct.version.newBatchResponse(service.Oracle.PublicKey, items, ct.config))
+}
+
+// parseBatchRequest returns the items of a batch request, the events with an invalid time have an item error
+func (ct *AssetController) parseBatchRequest(c *gin.Context) ([]*batchItem, error) {
+	request := &BatchRequest{}
+	if err := c.ShouldBindJSON(request); err != nil {
+		return nil, NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, err, "body")
+	}
+	if len(request.Events) == 0 || len(request.Events) > MaxBatchSize {
+		cause := errors.Errorf("a batch should contain between 1 and %d events", MaxBatchSize)
+		return nil, NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, cause, "events")
+	}
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	if _, err := entity.FindAsset(db, ct.assetID); err != nil {
+		return nil, NewRecordNotFoundDBError(err, ct.assetID)
+	}
+
+	asset := oracle.NewValuedAsset(ct.assetID, ct.config, db, nil)
+	now := time.Now().UTC()
+	items := make([]*batchItem, len(request.Events))
+	for i, event := range request.Events {
+		item := &batchItem{eventType: event.EventType}
+		if item.eventType == "" {
+			item.eventType = oracle.DefaultEventType
+		}
+		items[i] = item
+		if _, _, err := oracle.ParseEventType(item.eventType); err != nil {
+			item.err = toAPIError(oracleError(err))
+			continue
+		}
+		requestedDate, err := ParseTime(event.Time)
+		if err != nil {
+			item.err = NewBadRequestError(InvalidTimeFormatBadRequestErrorCode, err, event.Time)
+			continue
+		}
+		publishDate, err := asset.PublishDate(*requestedDate, now)
+		if err != nil {
+			item.err = toAPIError(oracleError(err))
+			continue
+		}
+		item.publishDate = publishDate
+	}
+	return items, nil
+}
+
+// findOrCreateBatch sets the dlcData of the valid items, the missing ones are created in a single transaction
+// (if a concurrent request created some of them, they are announced one by one),
+// the items over the announcement quota of the usage have an item error
+func (s *AssetService) findOrCreateBatch(ctx context.Context, assetID string, config AssetConfig, items []*batchItem) error {
+	type eventKey struct {
+		publishDate time.Time
+		eventType   string
+	}
+	dlcDataByKey := map[eventKey]*entity.DLCData{}
+	missing := []*entity.DLCData{}
+	for _, item := range items {
+		key := eventKey{item.publishDate, item.eventType}
+		if _, ok := dlcDataByKey[key]; item.err != nil || ok {
+			continue
+		}
+		dlcData, err := entity.FindDLCDataPublishedAt(s.DB, assetID, item.publishDate, item.eventType)
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return NewUnknownDBError(err)
+		}
+		if err != nil {
+			if err := s.Usage.CanAnnounce(); err != nil {
+				item.err = toAPIError(err)
+				continue
+			}
+			// counted before the creation to stop at the quota, the batch being created in a single transaction
+			s.Usage.Announce()
+			signingK, rvalue, err := s.Crypto.GenerateSchnorrKeyPair()
+			if err != nil {
+				return NewUnknownCryptoServiceError(err)
+			}
+			dlcData = &entity.DLCData{
+				AssetID:       assetID,
+				PublishedDate: item.publishDate,
+				EventType:     item.eventType,
+				Kvalue:        signingK.EncodeToString(),
+				Rvalue:        rvalue.EncodeToString(),
+			}
+			missing = append(missing, dlcData)
+		}
+		dlcDataByKey[key] = dlcData
+	}
+
+	if len(missing) > 0 {
+		s.Logger.Debugf("Creating %d new DLC data", len(missing))
+		if err := entity.CreateDLCDataBatch(s.DB, missing); err != nil {
+			s.Logger.Debugf("Could not create the DLC data batch, creating them one by one: %v", err)
+			// not metered, already counted in the usage
+			service := s.oracleService()
+			asset := oracle.NewValuedAsset(assetID, config, s.DB, s.Feed)
+			for _, dlcData := range missing {
+				created, err := service.Announce(ctx, asset, dlcData.EventType, dlcData.PublishedDate)
+				if err != nil {
+					return oracleError(err)
+				}
+				*dlcData = *created
+			}
+		}
+	}
+
+	for _, item := range items {
+		if item.err == nil {
+			item.dlcData = dlcDataByKey[eventKey{item.publishDate, item.eventType}]
+		}
+	}
+	return nil
+}
+
+// toAPIError returns the api error, or an unknown internal error if the error is not an api error
+func toAPIError(err error) *Error {
+	if apiError, ok := err.(*Error); ok {
+		return apiError
+	}
+	return NewUnknownInternalError(err, "Batch")
+}
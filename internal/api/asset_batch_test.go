@@ -0,0 +1,155 @@
+package api_test
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/test"
+	mock_datafeed "p2pderivatives-oracle/test/mock/datafeed"
+	mock_dlccrypto "p2pderivatives-oracle/test/mock/dlccrypto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type batchItemResult struct {
+	Event *api.DLCDataResponse `json:"event"`
+	Error *api.ErrorResponse   `json:"error"`
+}
+
+func randomHex() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func ExpectRandomKeyPairs(crypto *mock_dlccrypto.MockCryptoService, times int) {
+	crypto.EXPECT().GenerateSchnorrKeyPair().Times(times).DoAndReturn(
+		func() (*dlccrypto.PrivateKey, *dlccrypto.SchnorrPublicKey, error) {
+			k, _ := dlccrypto.NewPrivateKey(randomHex())
+			r, _ := dlccrypto.NewSchnorrPublicKey(randomHex())
+			return k, r, nil
+		})
+}
+
+func SetupBatchEngine(t *testing.T, recorder *httptest.ResponseRecorder, crypto dlccrypto.CryptoService, feed *mock_datafeed.MockDataFeed) (*gin.Context, *gin.Engine, *orm.ORM) {
+	oracleInstance, err := NewTestOracleService()
+	assert.NoError(t, err)
+	ormInstance := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{})
+	ormInstance.GetDB().Create(TestAsset)
+	// signed digits event
+	_, err = entity.CreateDLCData(ormInstance.GetDB(), TestAsset.AssetID, InDbDLCData.PublishedDate, "digits", InDbDLCData.Kvalue, InDbDLCData.Rvalue)
+	assert.NoError(t, err)
+	_, err = entity.UpdateDLCDataAttestation(ormInstance.GetDB(), TestAsset.AssetID, InDbDLCData.PublishedDate, "digits", *InDbDLCData)
+	assert.NoError(t, err)
+	setup := func(c *gin.Context) {
+		c.Set(api.ContextIDOracle, oracleInstance)
+		c.Set(api.ContextIDCryptoService, crypto)
+		c.Set(api.ContextIDDataFeed, feed)
+		c.Set(api.ContextIDOrm, ormInstance)
+	}
+	c, r := SetupEngine(recorder, api.NewAssetController(TestAsset.AssetID, *TestAssetConfig), api.ErrorHandler(), setup)
+	return c, r, ormInstance
+}
+
+func PostBatch(t *testing.T, c *gin.Context, r *gin.Engine, route string, events []api.BatchEventRequest) (int, []batchItemResult) {
+	resp := httptest.NewRecorder()
+	body, _ := json.Marshal(&api.BatchRequest{Events: events})
+	c.Request, _ = http.NewRequest(http.MethodPost, route, strings.NewReader(string(body)))
+	r.ServeHTTP(resp, c.Request)
+	results := []batchItemResult{}
+	if resp.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &results))
+	}
+	return resp.Code, results
+}
+
+func TestAssetController_PostAssetRvalues_ReturnsResultsInRequestOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	// two new events, the duplicated one and the one in db are not generated
+	ExpectRandomKeyPairs(crypto, 2)
+	resp := httptest.NewRecorder()
+	c, r, ormInstance := SetupBatchEngine(t, resp, crypto, nil)
+	first := time.Now().UTC().Add(3 * time.Hour).Truncate(time.Hour)
+	second := first.Add(time.Hour)
+	events := []api.BatchEventRequest{
+		{Time: first.Format(api.TimeFormatISO8601)},
+		{Time: "invalid"},
+		{Time: second.Format(api.TimeFormatISO8601), EventType: "digits"},
+		{Time: first.Format(api.TimeFormatISO8601)},
+		{Time: first.Add(TestAssetConfig.RangeD).Format(api.TimeFormatISO8601)},
+		{Time: InDbDLCData.PublishedDate.Format(api.TimeFormatISO8601)},
+	}
+
+	status, results := PostBatch(t, c, r, api.RoutePOSTAssetRvalues, events)
+
+	if assert.Equal(t, http.StatusOK, status) && assert.Len(t, results, len(events)) {
+		assert.Equal(t, first, results[0].Event.PublishedDate)
+		assert.Equal(t, api.InvalidTimeFormatBadRequestErrorCode, results[1].Error.ErrorCode)
+		assert.Equal(t, second, results[2].Event.PublishedDate)
+		assert.Equal(t, results[0].Event.Rvalue, results[3].Event.Rvalue)
+		assert.Equal(t, api.InvalidTimeTooLateBadRequestErrorCode, results[4].Error.ErrorCode)
+		assert.Equal(t, InDbDLCData.Rvalue, results[5].Event.Rvalue)
+	}
+	// after the existing event announcement and attestation
+	notifications, err := entity.FindEventNotificationsAfter(ormInstance.GetDB(), 2, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, notifications, 2)
+}
+
+func TestAssetController_PostAssetSignatures_AttestsPublishedEvents(t *testing.T) {
+	oracleInstance, err := NewTestOracleService()
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctrl := gomock.NewController(t)
+	_, _, sig, sigValue, err := SetupMockValues()
+	if !assert.NoError(t, err) {
+		return
+	}
+	published := InDbDLCData.PublishedDate.Add(TestAssetConfig.Frequency)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	ExpectRandomKeyPairs(crypto, 1)
+	crypto.EXPECT().ComputeSchnorrSignature(gomock.Any(), gomock.Any(), TestResponseValues.Value).Return(sig, nil)
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	feed.EXPECT().FindPastAssetPrice("btc", "usd", published).Return(sigValue, nil)
+	resp := httptest.NewRecorder()
+	c, r, _ := SetupBatchEngine(t, resp, crypto, feed)
+	c.Set(api.ContextIDOracle, oracleInstance)
+	events := []api.BatchEventRequest{
+		{Time: published.Format(api.TimeFormatISO8601)},
+		{Time: time.Now().UTC().Add(time.Hour).Format(api.TimeFormatISO8601)},
+		{Time: InDbDLCData.PublishedDate.Format(api.TimeFormatISO8601)},
+	}
+
+	status, results := PostBatch(t, c, r, api.RoutePOSTAssetSignatures, events)
+
+	if assert.Equal(t, http.StatusOK, status) && assert.Len(t, results, len(events)) {
+		assert.Equal(t, TestResponseValues.Signature, results[0].Event.Signature)
+		assert.Equal(t, TestResponseValues.Value, results[0].Event.Value)
+		assert.Equal(t, api.InvalidTimeTooEarlyBadRequestErrorCode, results[1].Error.ErrorCode)
+		assert.True(t, results[1].Error.RetryAfter > 0)
+		assert.Equal(t, InDbDLCData.Signature, results[2].Event.Signature)
+	}
+}
+
+func TestAssetController_PostAssetRvalues_InvalidBatch_ReturnsBadRequest(t *testing.T) {
+	tooMany := make([]api.BatchEventRequest, api.MaxBatchSize+1)
+	for _, events := range [][]api.BatchEventRequest{{}, tooMany} {
+		resp := httptest.NewRecorder()
+		c, r, _ := SetupBatchEngine(t, resp, nil, nil)
+		status, _ := PostBatch(t, c, r, api.RoutePOSTAssetRvalues, events)
+		assert.Equal(t, http.StatusBadRequest, status)
+	}
+}
@@ -84,7 +84,7 @@ func SetupMockValues() (*dlccrypto.PrivateKey, *dlccrypto.SchnorrPublicKey, *dlc
 
 func SetupAssetEngine(recorder *httptest.ResponseRecorder, o *oracle.Oracle, crypto dlccrypto.CryptoService, feed datafeed.DataFeed) (*gin.Context, *gin.Engine) {
 	assetController := api.NewAssetController(TestAsset.AssetID, *TestAssetConfig)
-	orm := test.NewOrm(&entity.Asset{}, &entity.DLCData{})
+	orm := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{})
 	orm.GetDB().Create(TestAsset)
 	orm.GetDB().Create(InDbDLCData)
 	setup := func(c *gin.Context) {
@@ -104,11 +104,12 @@ func TestAssetController_GetConfiguration(t *testing.T) {
 	r.ServeHTTP(resp, c.Request)
 	if assert.Equal(t, http.StatusOK, resp.Code) {
 		expected := &api.AssetConfigResponse{
-			Asset:     TestAssetConfig.Asset,
-			Currency:  TestAssetConfig.Currency,
-			StartDate: TestAssetConfig.StartDate,
-			Frequency: "PT1H",
-			RangeD:    "P2DT",
+			Asset:      TestAssetConfig.Asset,
+			Currency:   TestAssetConfig.Currency,
+			StartDate:  TestAssetConfig.StartDate,
+			Frequency:  "PT1H",
+			RangeD:     "P2DT",
+			Settlement: "close",
 		}
 		actual := &api.AssetConfigResponse{}
 		err := json.Unmarshal([]byte(resp.Body.String()), actual)
@@ -256,6 +257,7 @@ func TestAssetController_GetAssetSignature_NotInDB_ReturnsCorrectValue(t *testin
 		Rvalue:          TestResponseValues.Rvalue,
 		Signature:       TestResponseValues.Signature,
 		Value:           TestResponseValues.Value,
+		Settlement:      "close",
 	}
 
 	oracleInstance, err := NewTestOracleService()
@@ -325,7 +327,7 @@ func TestAssetController_GetAssetSignature_WithNearValidDateInDB_ReturnsCorrectV
 	}
 }
 
-func TestAssetController_GetAssetSignature_WithFutureDate_ReturnsBadRequestValue(t *testing.T) {
+func TestAssetController_GetAssetSignature_WithFutureDate_ReturnsTooEarlyWithRetryAfter(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
 	resp := httptest.NewRecorder()
@@ -339,12 +341,170 @@ func TestAssetController_GetAssetSignature_WithFutureDate_ReturnsBadRequestValue
 	r.ServeHTTP(resp, c.Request)
 
 	// assert
-	if assert.Equal(t, http.StatusBadRequest, resp.Code) {
+	if assert.Equal(t, http.StatusTooEarly, resp.Code) {
 		expectedErrorCode := api.InvalidTimeTooEarlyBadRequestErrorCode
 		actual := &api.ErrorResponse{}
 		err := json.Unmarshal([]byte(resp.Body.String()), actual)
 		if assert.NoError(t, err) {
 			assert.Equal(t, expectedErrorCode, actual.ErrorCode, actual)
+			// publish date is the next hour after the requested date
+			assert.True(t, actual.RetryAfter > 30*60 && actual.RetryAfter <= 90*60, actual.RetryAfter)
+			assert.Equal(t, fmt.Sprint(actual.RetryAfter), resp.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestAssetController_GetAssetSignature_WithFutureDateAfterWait_ReturnsTooEarlyWithoutWaiting(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	resp := httptest.NewRecorder()
+	c, r := SetupAssetEngine(resp, nil, crypto, nil)
+
+	date := time.Now().UTC().Add(30 * time.Minute)
+	route := GetRouteWithTimeParam(api.RouteGETAssetSignature, date) + "?wait=PT30S"
+	c.Request, _ = http.NewRequest(http.MethodGet, route, nil)
+
+	// act
+	start := time.Now()
+	r.ServeHTTP(resp, c.Request)
+
+	// assert
+	assert.True(t, time.Since(start) < 5*time.Second)
+	assert.Equal(t, http.StatusTooEarly, resp.Code)
+	assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+}
+
+func TestAssetController_GetAssetSignature_WithInvalidWait_ReturnsBadRequest(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r := SetupAssetEngine(resp, nil, nil, nil)
+	route := GetRouteWithTimeParam(api.RouteGETAssetSignature, InDbDLCData.PublishedDate) + "?wait=later"
+	c.Request, _ = http.NewRequest(http.MethodGet, route, nil)
+
+	r.ServeHTTP(resp, c.Request)
+
+	if assert.Equal(t, http.StatusBadRequest, resp.Code) {
+		actual := &api.ErrorResponse{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), actual))
+		assert.Equal(t, api.InvalidQueryBadRequestErrorCode, actual.ErrorCode)
+	}
+}
+
+func TestAssetController_GetAssetRvalue_WithInvalidEventType_ReturnsBadRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Times(0)
+	resp := httptest.NewRecorder()
+	c, r := SetupAssetEngine(resp, nil, crypto, nil)
+	route := GetRouteWithTimeParam(api.RouteGETAssetRvalue, InDbDLCData.PublishedDate) + "?eventType=above"
+	c.Request, _ = http.NewRequest(http.MethodGet, route, nil)
+
+	r.ServeHTTP(resp, c.Request)
+
+	if assert.Equal(t, http.StatusBadRequest, resp.Code) {
+		actual := &api.ErrorResponse{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), actual))
+		assert.Equal(t, api.InvalidEventTypeErrorCode, actual.ErrorCode)
+	}
+}
+
+func TestAssetController_GetAssetSignature_WithWait_ReturnsSignatureOncePublished(t *testing.T) {
+	oracleInstance, err := NewTestOracleService()
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctrl := gomock.NewController(t)
+	kvalue, rvalue, sig, sigValue, err := SetupMockValues()
+	if !assert.NoError(t, err) {
+		return
+	}
+	// publish every second
+	config := *TestAssetConfig
+	config.Frequency = time.Second
+	publishDate := time.Now().UTC().Truncate(time.Second).Add(time.Second)
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	feed.EXPECT().FindPastAssetPrice("btc", "usd", publishDate).Return(sigValue, nil)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Return(kvalue, rvalue, nil)
+	crypto.EXPECT().ComputeSchnorrSignature(gomock.Any(), gomock.Any(), gomock.Any()).Return(sig, nil)
+	ormInstance := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{})
+	ormInstance.GetDB().Create(TestAsset)
+	setup := func(c *gin.Context) {
+		c.Set(api.ContextIDOracle, oracleInstance)
+		c.Set(api.ContextIDCryptoService, crypto)
+		c.Set(api.ContextIDDataFeed, feed)
+		c.Set(api.ContextIDOrm, ormInstance)
+	}
+	resp := httptest.NewRecorder()
+	c, r := SetupEngine(resp, api.NewAssetController(TestAsset.AssetID, config), api.ErrorHandler(), setup)
+	route := GetRouteWithTimeParam(api.RouteGETAssetSignature, publishDate) + "?wait=5s"
+	c.Request, _ = http.NewRequest(http.MethodGet, route, nil)
+
+	// act
+	r.ServeHTTP(resp, c.Request)
+
+	// assert
+	assert.False(t, time.Now().Before(publishDate))
+	if assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String()) {
+		actual := &api.DLCDataResponse{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), actual))
+		assert.Equal(t, publishDate, actual.PublishedDate)
+		assert.NotEmpty(t, actual.Signature)
+	}
+}
+
+func TestAssetController_GetAssetSignature_WithTWAPSettlement_StoresSettlementMethod(t *testing.T) {
+	// params
+	publishDate := InDbDLCData.PublishedDate.Add(TestAssetConfig.Frequency)
+	config := *TestAssetConfig
+	config.Settlement = "twap(PT2M)"
+	candles := []datafeed.Candle{
+		{Time: publishDate.Add(-time.Minute), Close: 99.5},
+		{Time: publishDate, Close: 100.5},
+	}
+
+	oracleInstance, err := NewTestOracleService()
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctrl := gomock.NewController(t)
+	kvalue, rvalue, sig, _, err := SetupMockValues()
+	if !assert.NoError(t, err) {
+		return
+	}
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	feed.EXPECT().FindPastAssetCandles("btc", "usd", publishDate.Add(-2*time.Minute), publishDate).Return(candles, nil)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Return(kvalue, rvalue, nil)
+	crypto.EXPECT().ComputeSchnorrSignature(oracleInstance.PrivateKey, kvalue, "100").Return(sig, nil)
+
+	orm := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{})
+	orm.GetDB().Create(TestAsset)
+	setup := func(c *gin.Context) {
+		c.Set(api.ContextIDOracle, oracleInstance)
+		c.Set(api.ContextIDCryptoService, crypto)
+		c.Set(api.ContextIDDataFeed, feed)
+		c.Set(api.ContextIDOrm, orm)
+	}
+	resp := httptest.NewRecorder()
+	c, r := SetupEngine(resp, api.NewAssetController(TestAsset.AssetID, config), api.ErrorHandler(), setup)
+	route := GetRouteWithTimeParam(api.RouteGETAssetSignature, publishDate)
+	c.Request, _ = http.NewRequest(http.MethodGet, route, nil)
+
+	// act
+	r.ServeHTTP(resp, c.Request)
+
+	// assert
+	if assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String()) {
+		actual := &api.DLCDataResponse{}
+		err := json.Unmarshal(resp.Body.Bytes(), actual)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "100", actual.Value)
+			assert.Equal(t, "twap(PT2M)", actual.Settlement)
+		}
+		inDB, err := entity.FindDLCDataPublishedAt(orm.GetDB(), TestAsset.AssetID, publishDate, "digits")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "twap(PT2M)", inDB.SettlementMethod)
 		}
 	}
 }
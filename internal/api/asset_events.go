@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"p2pderivatives-oracle/internal/database/entity"
+	"strconv"
+	"strings"
+	"time"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+	"github.com/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// URLQueryTagFrom Tag to be used to select events published at or after a date
+	URLQueryTagFrom = "from"
+	// URLQueryTagTo Tag to be used to select events published at or before a date
+	URLQueryTagTo = "to"
+	// URLQueryTagLimit Tag to be used to set the maximum number of returned items
+	URLQueryTagLimit = "limit"
+	// EventStatusSigned status of the events which have been signed
+	EventStatusSigned = "signed"
+	// EventStatusUnsigned status of the events which have not been signed yet
+	EventStatusUnsigned = "unsigned"
+	// RouteGETAssetEvents relative GET route to list the asset events
+	RouteGETAssetEvents = "/events"
+)
+
+const (
+	defaultEventsLimit = 100
+	maxEventsLimit     = 1000
+)
+
+// GetAssetEvents handler returns a page of the announced events of the asset sorted by publish date
+// filtered by publish date range, event type and status, the next page is requested with the returned cursor
+func (ct *AssetController) GetAssetEvents(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Asset Events")
+	query, err := parseEventsQuery(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	service := assetServiceOf(c, nil)
+	dlcDataList, nextCursor, err := service.Events(ct.assetID, query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, ct.version.newEventPageResponse(service.Oracle.PublicKey, dlcDataList, ct.config, nextCursor))
+}
+
+func parseEventsQuery(c *gin.Context) (*EventsQuery, error) {
+	query := &EventsQuery{
+		EventType: c.Query(URLQueryTagEventType),
+		Status:    c.Query(URLQueryTagStatus),
+		Cursor:    c.Query(URLQueryTagCursor),
+	}
+	for tag, bound := range map[string]*time.Time{URLQueryTagFrom: &query.From, URLQueryTagTo: &query.To} {
+		if timeParam := c.Query(tag); timeParam != "" {
+			parsed, err := ParseTime(timeParam)
+			if err != nil {
+				return nil, NewBadRequestError(InvalidTimeFormatBadRequestErrorCode, err, tag)
+			}
+			*bound = *parsed
+		}
+	}
+	if limitParam := c.Query(URLQueryTagLimit); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 {
+			cause := errors.Errorf("limit should be an integer between 1 and %d", maxEventsLimit)
+			return nil, NewBadRequestError(InvalidQueryBadRequestErrorCode, cause, URLQueryTagLimit)
+		}
+		query.Limit = parsed
+	}
+	return query, nil
+}
+
+// encodeEventsCursor returns the opaque cursor of the page following an event
+func encodeEventsCursor(dlcData *entity.DLCData) string {
+	position := dlcData.PublishedDate.UTC().Format(time.RFC3339Nano) + "|" + dlcData.EventType
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+func decodeEventsCursor(cursor string) (time.Time, string, error) {
+	position, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", errors.WithMessage(err, "invalid cursor")
+	}
+	parts := strings.SplitN(string(position), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, "", errors.New("invalid cursor")
+	}
+	publishedDate, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, "", errors.WithMessage(err, "invalid cursor")
+	}
+	return publishedDate, parts[1], nil
+}
@@ -0,0 +1,103 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func SetupAssetEventsEngine(t *testing.T, recorder *httptest.ResponseRecorder, count int) (*gin.Context, *gin.Engine) {
+	oracleInstance, err := NewTestOracleService()
+	assert.NoError(t, err)
+	ormInstance := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{})
+	db := ormInstance.GetDB()
+	db.Create(TestAsset)
+	for i := 0; i < count; i++ {
+		publishDate := TestAssetConfig.StartDate.Add(time.Duration(i) * TestAssetConfig.Frequency)
+		_, err := entity.CreateDLCData(db, TestAsset.AssetID, publishDate, "digits", fmt.Sprint("k", i), fmt.Sprint("r", i))
+		assert.NoError(t, err)
+		if i%2 == 0 {
+			_, err = entity.UpdateDLCDataAttestation(db, TestAsset.AssetID, publishDate, "digits", entity.DLCData{Signature: "s", Value: "1"})
+			assert.NoError(t, err)
+		}
+	}
+	setup := func(c *gin.Context) {
+		c.Set(api.ContextIDOracle, oracleInstance)
+		c.Set(api.ContextIDOrm, ormInstance)
+	}
+	return SetupEngine(recorder, api.NewAssetController(TestAsset.AssetID, *TestAssetConfig), api.ErrorHandler(), setup)
+}
+
+func GetAssetEventsPage(t *testing.T, query string) (int, *api.DLCDataPageResponse) {
+	resp := httptest.NewRecorder()
+	c, r := SetupAssetEventsEngine(t, resp, 5)
+	c.Request, _ = http.NewRequest(http.MethodGet, api.RouteGETAssetEvents+query, nil)
+	r.ServeHTTP(resp, c.Request)
+	page := &api.DLCDataPageResponse{}
+	if resp.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), page))
+	}
+	return resp.Code, page
+}
+
+func TestAssetController_GetAssetEvents_Paginated_ReturnsAllPages(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r := SetupAssetEventsEngine(t, resp, 5)
+	rvalues := []string{}
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		resp = httptest.NewRecorder()
+		c.Request, _ = http.NewRequest(http.MethodGet, api.RouteGETAssetEvents+"?limit=2&cursor="+cursor, nil)
+		r.ServeHTTP(resp, c.Request)
+		if !assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String()) {
+			return
+		}
+		page := &api.DLCDataPageResponse{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), page))
+		for _, event := range page.Events {
+			rvalues = append(rvalues, event.Rvalue)
+		}
+		cursor = page.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"r0", "r1", "r2", "r3", "r4"}, rvalues)
+}
+
+func TestAssetController_GetAssetEvents_WithFilters_ReturnsMatching(t *testing.T) {
+	from := TestAssetConfig.StartDate.Add(TestAssetConfig.Frequency).Format(api.TimeFormatISO8601)
+	to := TestAssetConfig.StartDate.Add(3 * TestAssetConfig.Frequency).Format(api.TimeFormatISO8601)
+
+	status, page := GetAssetEventsPage(t, "?status=signed&eventType=digits&from="+from+"&to="+to)
+
+	if assert.Equal(t, http.StatusOK, status) && assert.Len(t, page.Events, 1) {
+		assert.Equal(t, "r2", page.Events[0].Rvalue)
+		assert.Equal(t, "s", page.Events[0].Signature)
+		assert.Empty(t, page.NextCursor)
+	}
+	status, page = GetAssetEventsPage(t, "?status=unsigned")
+	if assert.Equal(t, http.StatusOK, status) {
+		assert.Len(t, page.Events, 2)
+	}
+	status, page = GetAssetEventsPage(t, "?eventType=above(10)")
+	if assert.Equal(t, http.StatusOK, status) {
+		assert.Empty(t, page.Events)
+	}
+}
+
+func TestAssetController_GetAssetEvents_InvalidQuery_ReturnsBadRequest(t *testing.T) {
+	for _, query := range []string{"?status=pending", "?cursor=bm90LWEtY3Vyc29y", "?limit=0", "?limit=1001", "?from=yesterday"} {
+		status, _ := GetAssetEventsPage(t, query)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+	}
+}
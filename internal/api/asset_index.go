@@ -0,0 +1,27 @@
+package api
+
+import (
+	"p2pderivatives-oracle/internal/oracle"
+)
+
+// NewIndexCompositionsResponse returns the index composition versions of an asset sorted by effective date
+func NewIndexCompositionsResponse(config AssetConfig) []*IndexCompositionResponse {
+	compositions := []*IndexCompositionResponse{}
+	for _, composition := range oracle.IndexCompositions(config) {
+		components := make([]*IndexComponentResponse, 0, len(composition.Components))
+		for _, component := range composition.Components {
+			components = append(components, &IndexComponentResponse{
+				Name:     component.Name,
+				Asset:    component.Asset,
+				Currency: component.Currency,
+				Weight:   component.Weight,
+			})
+		}
+		compositions = append(compositions, &IndexCompositionResponse{
+			Version:       composition.Version,
+			EffectiveFrom: composition.EffectiveFrom,
+			Components:    components,
+		})
+	}
+	return compositions
+}
@@ -0,0 +1,165 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/datafeed"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/oracle"
+	"p2pderivatives-oracle/test"
+	mock_datafeed "p2pderivatives-oracle/test/mock/datafeed"
+	mock_dlccrypto "p2pderivatives-oracle/test/mock/dlccrypto"
+	"testing"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const TestIndexAssetID = "defiusd"
+
+var TestIndexAssetConfig = &api.AssetConfig{
+	Asset:       "defi",
+	Currency:    "usd",
+	HasDecimals: true,
+	StartDate:   time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+	Frequency:   time.Hour,
+	RangeD:      time.Hour * 48,
+	Index: map[string]api.IndexVersionConfig{
+		"v2": {
+			EffectiveFrom: time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC),
+			Components: map[string]api.IndexComponentConfig{
+				"sushi": {Asset: "sushi", Weight: 2},
+				"link":  {Asset: "link", Weight: 3},
+			},
+		},
+		"v1": {
+			EffectiveFrom: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Components: map[string]api.IndexComponentConfig{
+				"sushi": {Asset: "sushi", Weight: 10},
+				"link":  {Asset: "link", Currency: "jpy", Weight: 1},
+			},
+		},
+	},
+}
+
+func SetupIndexAssetEngine(recorder *httptest.ResponseRecorder, o *oracle.Oracle, crypto dlccrypto.CryptoService, feed datafeed.DataFeed) (*gin.Context, *gin.Engine, *orm.ORM) {
+	assetController := api.NewAssetController(TestIndexAssetID, *TestIndexAssetConfig)
+	orm := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.IndexComposition{}, &entity.EventNotification{})
+	orm.GetDB().Create(&entity.Asset{AssetID: TestIndexAssetID})
+	setup := func(c *gin.Context) {
+		c.Set(api.ContextIDOracle, o)
+		c.Set(api.ContextIDCryptoService, crypto)
+		c.Set(api.ContextIDDataFeed, feed)
+		c.Set(api.ContextIDOrm, orm)
+	}
+	c, r := SetupEngine(recorder, assetController, api.ErrorHandler(), setup)
+	return c, r, orm
+}
+
+func TestAssetController_GetConfiguration_WithIndexAsset_ReturnsSortedCompositions(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r, _ := SetupIndexAssetEngine(resp, nil, nil, nil)
+	c.Request, _ = http.NewRequest(http.MethodGet, api.RouteGETAssetConfig, nil)
+	r.ServeHTTP(resp, c.Request)
+	if assert.Equal(t, http.StatusOK, resp.Code) {
+		actual := &api.AssetConfigResponse{}
+		err := json.Unmarshal(resp.Body.Bytes(), actual)
+		if assert.NoError(t, err) && assert.Len(t, actual.Index, 2) {
+			assert.Equal(t, "v1", actual.Index[0].Version)
+			assert.Equal(t, "v2", actual.Index[1].Version)
+			expectedComponents := []*api.IndexComponentResponse{
+				{Name: "link", Asset: "link", Currency: "jpy", Weight: 1},
+				{Name: "sushi", Asset: "sushi", Currency: "usd", Weight: 10},
+			}
+			assert.Equal(t, expectedComponents, actual.Index[0].Components)
+		}
+	}
+}
+
+func TestAssetController_GetAssetSignature_WithIndexAsset_ReturnsWeightedValue(t *testing.T) {
+	// params
+	publishDate := time.Date(2020, time.February, 10, 10, 0, 0, 0, time.UTC)
+	sushiPrice, linkPrice := 1.5, 10.25
+	expectedValue := "33.75"
+
+	oracleInstance, err := NewTestOracleService()
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctrl := gomock.NewController(t)
+	kvalue, rvalue, sig, _, err := SetupMockValues()
+	if !assert.NoError(t, err) {
+		return
+	}
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	feed.EXPECT().FindPastAssetPrice("sushi", "usd", publishDate).Return(&sushiPrice, nil)
+	feed.EXPECT().FindPastAssetPrice("link", "usd", publishDate).Return(&linkPrice, nil)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Return(kvalue, rvalue, nil)
+	crypto.EXPECT().ComputeSchnorrSignature(oracleInstance.PrivateKey, kvalue, expectedValue).Return(sig, nil)
+
+	resp := httptest.NewRecorder()
+	c, r, orm := SetupIndexAssetEngine(resp, oracleInstance, crypto, feed)
+	route := GetRouteWithTimeParam(api.RouteGETAssetSignature, publishDate)
+	c.Request, _ = http.NewRequest(http.MethodGet, route, nil)
+
+	// act
+	r.ServeHTTP(resp, c.Request)
+
+	// assert
+	if assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String()) {
+		actual := &api.DLCDataResponse{}
+		err := json.Unmarshal(resp.Body.Bytes(), actual)
+		if assert.NoError(t, err) {
+			assert.Equal(t, expectedValue, actual.Value)
+		}
+		inDB, err := entity.FindDLCDataPublishedAt(orm.GetDB(), TestIndexAssetID, publishDate, "digits")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "v2", inDB.IndexVersion)
+		}
+		composition, err := entity.FindIndexComposition(orm.GetDB(), TestIndexAssetID, "v2")
+		if assert.NoError(t, err) {
+			assert.JSONEq(t,
+				`[{"name":"link","asset":"link","currency":"usd","weight":3},{"name":"sushi","asset":"sushi","currency":"usd","weight":2}]`,
+				composition.Components)
+		}
+	}
+}
+
+func TestAssetController_GetAssetSignature_WithIndexAssetBeforeFirstComposition_ReturnsBadRequest(t *testing.T) {
+	oracleInstance, err := NewTestOracleService()
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctrl := gomock.NewController(t)
+	kvalue, rvalue, _, _, err := SetupMockValues()
+	if !assert.NoError(t, err) {
+		return
+	}
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Return(kvalue, rvalue, nil)
+
+	resp := httptest.NewRecorder()
+	c, r, _ := SetupIndexAssetEngine(resp, oracleInstance, crypto, feed)
+	route := GetRouteWithTimeParam(api.RouteGETAssetSignature, time.Date(2019, time.December, 31, 10, 0, 0, 0, time.UTC))
+	c.Request, _ = http.NewRequest(http.MethodGet, route, nil)
+
+	// act
+	r.ServeHTTP(resp, c.Request)
+
+	// assert
+	if assert.Equal(t, http.StatusBadRequest, resp.Code) {
+		actual := &api.ErrorResponse{}
+		err := json.Unmarshal(resp.Body.Bytes(), actual)
+		if assert.NoError(t, err) {
+			assert.Equal(t, api.InvalidTimeTooEarlyBadRequestErrorCode, actual.ErrorCode)
+		}
+	}
+}
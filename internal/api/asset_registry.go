@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/oracle"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// AssetProvider provides the configurations of the assets served by the oracle
+type AssetProvider = oracle.AssetProvider
+
+// StaticAssets is an AssetProvider of a fixed set of assets
+type StaticAssets = oracle.StaticAssets
+
+// findAssetConfig returns the configuration of an asset, false if the asset is unknown
+func findAssetConfig(assets AssetProvider, assetID string) (AssetConfig, bool, error) {
+	configs, err := assets.AssetConfigs()
+	if err != nil {
+		return AssetConfig{}, false, err
+	}
+	config, ok := configs[assetID]
+	return config, ok, nil
+}
+
+// sortedAssetIDs returns the ids of the provided assets sorted
+func sortedAssetIDs(assets AssetProvider) ([]string, error) {
+	configs, err := assets.AssetConfigs()
+	if err != nil {
+		return nil, err
+	}
+	assetIDs := make([]string, 0, len(configs))
+	for assetID := range configs {
+		assetIDs = append(assetIDs, assetID)
+	}
+	sort.Strings(assetIDs)
+	return assetIDs, nil
+}
+
+// NewAssetRegistry returns an AssetProvider of the assets stored in the database,
+// loaded at most once per cache duration
+func NewAssetRegistry(orm *orm.ORM, cacheDuration time.Duration) *AssetRegistry {
+	return &AssetRegistry{
+		orm:           orm,
+		cacheDuration: cacheDuration,
+	}
+}
+
+// AssetRegistry provides the configured assets stored in the database, the assets without
+// schedule (frequency) are ignored
+type AssetRegistry struct {
+	orm           *orm.ORM
+	cacheDuration time.Duration
+	mutex         sync.RWMutex
+	configs       map[string]AssetConfig
+	loadedAt      time.Time
+}
+
+// AssetConfigs returns the configurations of the assets indexed by asset id
+func (r *AssetRegistry) AssetConfigs() (map[string]AssetConfig, error) {
+	r.mutex.RLock()
+	configs, loadedAt := r.configs, r.loadedAt
+	r.mutex.RUnlock()
+	if configs != nil && time.Since(loadedAt) < r.cacheDuration {
+		return configs, nil
+	}
+
+	assets, err := entity.FindAssets(r.orm.GetDB())
+	if err != nil {
+		return nil, err
+	}
+	configs = make(map[string]AssetConfig, len(assets))
+	for i := range assets {
+		if assets[i].Frequency <= 0 {
+			continue
+		}
+		config, err := NewAssetConfig(&assets[i])
+		if err != nil {
+			return nil, err
+		}
+		configs[assets[i].AssetID] = *config
+	}
+
+	r.mutex.Lock()
+	r.configs, r.loadedAt = configs, time.Now()
+	r.mutex.Unlock()
+	return configs, nil
+}
+
+// Invalidate forces the assets to be loaded from the database on next access
+func (r *AssetRegistry) Invalidate() {
+	r.mutex.Lock()
+	r.configs = nil
+	r.mutex.Unlock()
+}
+
+// NewAssetConfig returns the configuration of an asset stored in the database
+func NewAssetConfig(asset *entity.Asset) (*AssetConfig, error) {
+	config := &AssetConfig{
+		Asset:       asset.Symbol,
+		Currency:    asset.Currency,
+		HasDecimals: asset.HasDecimals,
+		StartDate:   asset.StartDate.UTC(),
+		Frequency:   asset.Frequency,
+		RangeD:      asset.Range,
+		EventTypes:  map[string]bool{},
+		Settlement:  asset.Settlement,
+	}
+	for _, eventType := range strings.Split(asset.EventTypes, ",") {
+		if eventType != "" {
+			config.EventTypes[eventType] = true
+		}
+	}
+	if asset.Index != "" {
+		if err := json.Unmarshal([]byte(asset.Index), &config.Index); err != nil {
+			return nil, errors.WithMessagef(err, "invalid index composition of asset %s", asset.AssetID)
+		}
+	}
+	return config, nil
+}
+
+// NewAssetEntity returns the database model of an asset configuration
+func NewAssetEntity(assetID string, description string, config AssetConfig) (*entity.Asset, error) {
+	eventTypes := []string{}
+	for eventType, enabled := range config.EventTypes {
+		if enabled {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	sort.Strings(eventTypes)
+	asset := &entity.Asset{
+		AssetID:     assetID,
+		Description: description,
+		Symbol:      config.Asset,
+		Currency:    config.Currency,
+		HasDecimals: config.HasDecimals,
+		StartDate:   config.StartDate.UTC(),
+		Frequency:   config.Frequency,
+		Range:       config.RangeD,
+		EventTypes:  strings.Join(eventTypes, ","),
+		Settlement:  config.Settlement,
+	}
+	if config.IsIndex() {
+		index, err := json.Marshal(config.Index)
+		if err != nil {
+			return nil, err
+		}
+		asset.Index = string(index)
+	}
+	return asset, nil
+}
+
+// SeedAssets stores the configured assets missing in the database, or stored without schedule
+// (created before the assets configuration was stored), the stored assets are left unchanged
+func SeedAssets(db *gorm.DB, configs map[string]AssetConfig) error {
+	for assetID, config := range configs {
+		if err := ValidateAssetID(assetID); err != nil {
+			return err
+		}
+		asset, err := NewAssetEntity(assetID, strings.ToUpper(config.Asset+" "+config.Currency), config)
+		if err != nil {
+			return err
+		}
+		existing, err := entity.FindAsset(db, assetID)
+		switch {
+		case gorm.IsRecordNotFoundError(err):
+			err = entity.CreateAsset(db, asset)
+		case err == nil && existing.Frequency <= 0:
+			asset.Description = existing.Description
+			_, err = entity.UpdateAsset(db, asset)
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "could not seed asset %s", assetID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package api_test
+
+import (
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func NewTestIndexAssetConfig() api.AssetConfig {
+	config := *TestAssetConfig
+	config.EventTypes = map[string]bool{"digits": true, "above": true}
+	config.Settlement = "twap(PT1H)"
+	config.Index = map[string]api.IndexVersionConfig{
+		"v1": {
+			EffectiveFrom: TestAssetConfig.StartDate,
+			Components: map[string]api.IndexComponentConfig{
+				"btc": {Asset: "btc", Weight: 0.6},
+				"eth": {Asset: "eth", Currency: "usd", Weight: 0.4},
+			},
+		},
+	}
+	return config
+}
+
+func TestAssetEntity_RoundTrip_ReturnsSameConfig(t *testing.T) {
+	config := NewTestIndexAssetConfig()
+
+	asset, err := api.NewAssetEntity("defiusd", "DeFi", config)
+	require.NoError(t, err)
+	assert.Equal(t, "above,digits", asset.EventTypes)
+	actual, err := api.NewAssetConfig(asset)
+	require.NoError(t, err)
+
+	assert.Equal(t, config, *actual)
+}
+
+func TestSeedAssets_StoresMissingAndUnscheduledAssetsOnly(t *testing.T) {
+	ormInstance := test.NewOrm(&entity.Asset{})
+	db := ormInstance.GetDB()
+	require.NoError(t, db.Create(&entity.Asset{AssetID: "btcusd", Description: "BTC USD"}).Error)
+	updated := *TestAssetConfig
+	updated.Frequency = 2 * time.Hour
+	stored, err := api.NewAssetEntity("ethusd", "ETH USD", updated)
+	require.NoError(t, err)
+	require.NoError(t, entity.CreateAsset(db, stored))
+
+	err = api.SeedAssets(db, map[string]api.AssetConfig{
+		"btcusd": *TestAssetConfig,
+		"ethusd": *TestAssetConfig,
+		"xrpusd": *TestAssetConfig,
+	})
+	require.NoError(t, err)
+
+	configs, err := api.NewAssetRegistry(ormInstance, time.Minute).AssetConfigs()
+	require.NoError(t, err)
+	assert.Len(t, configs, 3)
+	assert.Equal(t, TestAssetConfig.Frequency, configs["btcusd"].Frequency)
+	assert.Equal(t, TestAssetConfig.Frequency, configs["xrpusd"].Frequency)
+	// stored assets are left unchanged
+	assert.Equal(t, 2*time.Hour, configs["ethusd"].Frequency)
+	btcusd, err := entity.FindAsset(db, "btcusd")
+	require.NoError(t, err)
+	assert.Equal(t, "BTC USD", btcusd.Description)
+}
+
+func TestSeedAssets_RvalueAliasID_ReturnsError(t *testing.T) {
+	ormInstance := test.NewOrm(&entity.Asset{})
+
+	err := api.SeedAssets(ormInstance.GetDB(), map[string]api.AssetConfig{"rvalue": *TestAssetConfig})
+
+	assert.Error(t, err)
+	_, err = entity.FindAsset(ormInstance.GetDB(), "rvalue")
+	assert.Error(t, err)
+}
+
+func TestAssetRegistry_CachesUntilInvalidated(t *testing.T) {
+	ormInstance := test.NewOrm(&entity.Asset{})
+	db := ormInstance.GetDB()
+	require.NoError(t, api.SeedAssets(db, map[string]api.AssetConfig{"btcusd": *TestAssetConfig}))
+	// assets without schedule are not served
+	require.NoError(t, db.Create(&entity.Asset{AssetID: "sushiusd"}).Error)
+	registry := api.NewAssetRegistry(ormInstance, time.Hour)
+
+	configs, err := registry.AssetConfigs()
+	require.NoError(t, err)
+	assert.Len(t, configs, 1)
+
+	require.NoError(t, api.SeedAssets(db, map[string]api.AssetConfig{"ethusd": *TestAssetConfig}))
+	configs, err = registry.AssetConfigs()
+	require.NoError(t, err)
+	assert.Len(t, configs, 1)
+
+	registry.Invalidate()
+	configs, err = registry.AssetConfigs()
+	require.NoError(t, err)
+	assert.Contains(t, configs, "btcusd")
+	assert.Contains(t, configs, "ethusd")
+}
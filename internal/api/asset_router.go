@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const (
+	// URLParamTagAction Tag used for the route relative to the requested asset
+	URLParamTagAction = "action"
+	// RouteAssetAction route of the asset routes, dispatched to the controller of the requested asset
+	RouteAssetAction = "/:" + URLParamTagAsset + "/*" + URLParamTagAction
+
+	// rvalueAlias asset route segment of the v1 event lookup by rvalue
+	rvalueAlias = "rvalue"
+)
+
+// assetAction represents a route served for each asset, relative to the asset route
+type assetAction struct {
+	method  string
+	path    string
+	handler func(ct *AssetController, c *gin.Context)
+}
+
+var assetActions = []*assetAction{
+	{http.MethodGet, RouteGETAssetRvalue, (*AssetController).GetAssetRvalue},
+	{http.MethodGet, RouteGETAssetSignature, (*AssetController).GetAssetSignature},
+	{http.MethodGet, RouteGETAssetConfig, (*AssetController).GetConfiguration},
+	{http.MethodGet, RouteGETAssetEvents, (*AssetController).GetAssetEvents},
+	{http.MethodPost, RoutePOSTAssetRvalues, (*AssetController).PostAssetRvalues},
+	{http.MethodPost, RoutePOSTAssetSignatures, (*AssetController).PostAssetSignatures},
+}
+
+// AssetRoutes returns the method and path of the routes served for each asset, relative to the asset route
+func AssetRoutes() gin.RoutesInfo {
+	routes := gin.RoutesInfo{}
+	for _, action := range assetActions {
+		routes = append(routes, gin.RouteInfo{Method: action.method, Path: action.path})
+	}
+	return routes
+}
+
+// match returns the route parameters if the action serves the request method and path
+func (a *assetAction) match(method string, path string) (gin.Params, bool) {
+	if method != a.method {
+		return nil, false
+	}
+	segments := strings.Split(a.path, "/")
+	pathSegments := strings.Split(path, "/")
+	if len(segments) != len(pathSegments) {
+		return nil, false
+	}
+	params := gin.Params{}
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":") && pathSegments[i] != "":
+			params = append(params, gin.Param{Key: segment[1:], Value: pathSegments[i]})
+		case segment != pathSegments[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// AssetRouter represents the asset api Controller, the requests are dispatched to the controller
+// of the requested asset so that the assets added while running are served without restart
+type AssetRouter struct {
+	version APIVersion
+	assets  AssetProvider
+}
+
+// NewAssetRouter creates a new Controller structure serving the v1 asset routes,
+// including the event lookup by rvalue kept for compatibility.
+func NewAssetRouter(assets AssetProvider) Controller {
+	return &AssetRouter{
+		version: APIVersion1,
+		assets:  assets,
+	}
+}
+
+// NewAssetRouterV2 creates a new Controller structure serving the v2 asset routes.
+func NewAssetRouterV2(assets AssetProvider) Controller {
+	return &AssetRouter{
+		version: APIVersion2,
+		assets:  assets,
+	}
+}
+
+// Routes list and binds all routes to the router group provided
+func (r *AssetRouter) Routes(route *gin.RouterGroup) {
+	route.GET("", r.GetAssets)
+	route.GET(RouteAssetAction, r.HandleAsset)
+	route.POST(RouteAssetAction, r.HandleAsset)
+}
+
+// GetAssets handler returns the ids of the available assets
+func (r *AssetRouter) GetAssets(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Assets")
+	assetIDs, err := sortedAssetIDs(r.assets)
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	c.JSON(http.StatusOK, assetIDs)
+}
+
+// HandleAsset handler dispatches an asset route to the controller of the requested asset
+func (r *AssetRouter) HandleAsset(c *gin.Context) {
+	assetID := c.Param(URLParamTagAsset)
+	path := c.Param(URLParamTagAction)
+	if r.version == APIVersion1 && assetID == rvalueAlias && c.Request.Method == http.MethodGet &&
+		strings.Count(path, "/") == 1 && len(path) > 1 {
+		c.Params = append(c.Params, gin.Param{Key: URLParamTagRvalue, Value: path[1:]})
+		NewEventController(r.assets).GetEventByRvalue(c)
+		return
+	}
+
+	config, ok, err := findAssetConfig(r.assets, assetID)
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	if !ok {
+		c.Error(NewRecordNotFoundDBError(errors.Errorf("unknown asset %s", assetID), assetID))
+		return
+	}
+	ct := &AssetController{version: r.version, assetID: assetID, config: config}
+	for _, action := range assetActions {
+		if params, ok := action.match(c.Request.Method, path); ok {
+			c.Params = append(c.Params, params...)
+			action.handler(ct, c)
+			return
+		}
+	}
+	c.String(http.StatusNotFound, "404 page not found")
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/datafeed"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/oracle"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+)
+
+// AssetService serves the asset operations independently of the transport,
+// it is used by the REST handlers and the gRPC server
+type AssetService struct {
+	Assets AssetProvider
+	Oracle *oracle.Oracle
+	DB     *gorm.DB
+	// Cache in-process cache of the signed events, not cached if nil
+	Cache  *entity.DLCDataCache
+	Crypto dlccrypto.CryptoService
+	Feed   datafeed.DataFeed
+	Logger *logrus.Entry
+	// Usage meters the announcements and attestations of the caller, not metered if nil
+	Usage *Usage
+}
+
+// assetServiceOf returns the asset service of a request from the services added to its context
+func assetServiceOf(c *gin.Context, assets AssetProvider) *AssetService {
+	service := &AssetService{
+		Assets: assets,
+		Logger: ginlogrus.GetCtxLogger(c),
+		Cache:  eventCacheOf(c).DLCData,
+		Usage:  usageOf(c),
+	}
+	service.Oracle, _ = c.Value(ContextIDOracle).(*oracle.Oracle)
+	if ormInstance, ok := c.Value(ContextIDOrm).(*orm.ORM); ok {
+		service.DB = ormInstance.GetDB()
+	}
+	service.Crypto, _ = c.Value(ContextIDCryptoService).(dlccrypto.CryptoService)
+	service.Feed, _ = c.Value(ContextIDDataFeed).(datafeed.DataFeed)
+	return service
+}
+
+// AssetIDs returns the ids of the available assets sorted
+func (s *AssetService) AssetIDs() ([]string, error) {
+	assetIDs, err := sortedAssetIDs(s.Assets)
+	if err != nil {
+		return nil, NewUnknownDBError(err)
+	}
+	return assetIDs, nil
+}
+
+// AssetConfig returns the configuration of an available asset
+func (s *AssetService) AssetConfig(assetID string) (AssetConfig, error) {
+	config, ok, err := findAssetConfig(s.Assets, assetID)
+	if err != nil {
+		return AssetConfig{}, NewUnknownDBError(err)
+	}
+	if !ok {
+		return AssetConfig{}, NewRecordNotFoundDBError(errors.Errorf("unknown asset %s", assetID), assetID)
+	}
+	return config, nil
+}
+
+// Rvalue returns the event published at the first publish date at or after the requested date,
+// announcing it if not stored yet (digits event type if not set)
+func (s *AssetService) Rvalue(
+	ctx context.Context,
+	assetID string,
+	config AssetConfig,
+	eventType string,
+	requestedDate time.Time) (*entity.DLCData, error) {
+	if _, err := entity.FindAsset(s.DB, assetID); err != nil {
+		return nil, NewRecordNotFoundDBError(err, assetID)
+	}
+	s.Logger.Debug("Finding or generating DLC data Rvalue")
+	dlcData, err := s.oracleService().Announce(
+		s.meteredContext(ctx), oracle.NewValuedAsset(assetID, config, s.DB, s.Feed), eventType, requestedDate)
+	if err != nil {
+		return nil, oracleError(err)
+	}
+	return dlcData, nil
+}
+
+// Signature returns the attested event published at the first publish date at or after the requested date,
+// announcing and attesting it if needed, the request is held up to wait until the publish date
+func (s *AssetService) Signature(
+	ctx context.Context,
+	assetID string,
+	config AssetConfig,
+	eventType string,
+	requestedDate time.Time,
+	wait time.Duration) (*entity.DLCData, error) {
+	if _, err := entity.FindAsset(s.DB, assetID); err != nil {
+		return nil, NewRecordNotFoundDBError(err, assetID)
+	}
+	service := s.oracleService()
+	asset := oracle.NewValuedAsset(assetID, config, s.DB, s.Feed)
+	publishDate, err := service.PublishDate(asset, requestedDate)
+	if err != nil {
+		return nil, oracleError(err)
+	}
+	// check the signature has been published, waiting for it if requested
+	if err := waitPublishDate(ctx, publishDate, wait); err != nil {
+		return nil, err
+	}
+	s.Logger.Debug("Finding or computing Signature")
+	dlcData, err := service.Attest(s.meteredContext(ctx), asset, eventType, requestedDate)
+	if err != nil {
+		return nil, oracleError(err)
+	}
+	return dlcData, nil
+}
+
+// oracleService returns the oracle service of the caller
+func (s *AssetService) oracleService() *oracle.Service {
+	return oracle.NewService(s.Oracle, s.DB, s.Cache, s.Crypto)
+}
+
+// meteredContext returns a context counting the announcements and attestations in the usage of the caller (if metered)
+func (s *AssetService) meteredContext(ctx context.Context) context.Context {
+	if s.Usage == nil {
+		return ctx
+	}
+	return oracle.WithMeter(ctx, s.Usage)
+}
+
+// oracleError returns the api error of a failed oracle service operation,
+// the quota errors are already api errors
+func oracleError(err error) error {
+	serviceError, ok := err.(*oracle.Error)
+	if !ok {
+		return err
+	}
+	switch serviceError.Kind {
+	case oracle.ErrorKindDatabase:
+		return NewUnknownDBError(serviceError.Err)
+	case oracle.ErrorKindOutOfRange:
+		return NewBadRequestError(
+			InvalidTimeTooLateBadRequestErrorCode, serviceError.Err, serviceError.PublishDate.String())
+	case oracle.ErrorKindNotPublished:
+		return NewTooEarlyError(
+			InvalidTimeTooEarlyBadRequestErrorCode, serviceError.Err, time.Until(serviceError.PublishDate))
+	case oracle.ErrorKindNoIndexComposition:
+		return NewBadRequestError(
+			InvalidTimeTooEarlyBadRequestErrorCode, serviceError.Err, serviceError.PublishDate.String())
+	case oracle.ErrorKindDataFeed:
+		return NewUnknownDataFeedError(serviceError.Err)
+	case oracle.ErrorKindInternal:
+		return NewUnknownInternalError(serviceError.Err, "Asset valuation")
+	case oracle.ErrorKindInvalidParameter:
+		return NewBadRequestError(InvalidEventTypeErrorCode, serviceError.Err, serviceError.Err.Error())
+	default:
+		return NewUnknownCryptoServiceError(serviceError.Err)
+	}
+}
+
+// EventsQuery represents the filters and page of an asset event listing
+type EventsQuery struct {
+	// From and To publish date range, not bounded if zero
+	From time.Time
+	To   time.Time
+	// EventType all event types if not set
+	EventType string
+	// Status signed or unsigned, all events if not set
+	Status string
+	// Cursor position returned with the previous page
+	Cursor string
+	// Limit page size, the default page size is used if 0
+	Limit int
+}
+
+// Events returns a page of the announced events of an asset sorted by publish date, and the cursor of the next page if any
+func (s *AssetService) Events(assetID string, eventsQuery *EventsQuery) ([]entity.DLCData, string, error) {
+	query := &entity.DLCDataQuery{
+		AssetID:   assetID,
+		EventType: eventsQuery.EventType,
+		From:      eventsQuery.From,
+		To:        eventsQuery.To,
+	}
+	switch status := eventsQuery.Status; status {
+	case "":
+	case EventStatusSigned, EventStatusUnsigned:
+		signed := status == EventStatusSigned
+		query.Signed = &signed
+	default:
+		cause := errors.Errorf("unknown status %s, should be %s or %s", status, EventStatusSigned, EventStatusUnsigned)
+		return nil, "", NewBadRequestError(InvalidQueryBadRequestErrorCode, cause, URLQueryTagStatus)
+	}
+	if eventsQuery.Cursor != "" {
+		publishedDate, eventType, err := decodeEventsCursor(eventsQuery.Cursor)
+		if err != nil {
+			return nil, "", NewBadRequestError(InvalidQueryBadRequestErrorCode, err, URLQueryTagCursor)
+		}
+		query.AfterPublishedDate, query.AfterEventType = publishedDate, eventType
+	}
+	limit := eventsQuery.Limit
+	if limit == 0 {
+		limit = defaultEventsLimit
+	}
+	if limit < 1 || limit > maxEventsLimit {
+		cause := errors.Errorf("limit should be an integer between 1 and %d", maxEventsLimit)
+		return nil, "", NewBadRequestError(InvalidQueryBadRequestErrorCode, cause, URLQueryTagLimit)
+	}
+
+	// one more to know if there is a next page
+	dlcDataList, err := entity.FindDLCDataList(s.DB, query, limit+1)
+	if err != nil {
+		return nil, "", NewUnknownDBError(err)
+	}
+	nextCursor := ""
+	if len(dlcDataList) > limit {
+		dlcDataList = dlcDataList[:limit]
+		nextCursor = encodeEventsCursor(&dlcDataList[limit-1])
+	}
+	return dlcDataList, nextCursor, nil
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"p2pderivatives-oracle/internal/database/entity"
+	"strconv"
+	"time"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RouteGETAdminAudit relative GET route to retrieve the audit records
+	RouteGETAdminAudit = "/audit"
+	// URLQueryTagPrincipal Tag to be used to filter by principal
+	URLQueryTagPrincipal = "principal"
+
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+)
+
+// AuditRecordResponse represents a recorded privileged request
+type AuditRecordResponse struct {
+	ID         uint      `json:"id"`
+	Principal  string    `json:"principal"`
+	AuthMethod string    `json:"authMethod"`
+	Role       string    `json:"role"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	RequestID  string    `json:"requestId,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// NewAuditRecordResponse transforms a entity.AuditRecord to an audit record response
+func NewAuditRecordResponse(record *entity.AuditRecord) *AuditRecordResponse {
+	return &AuditRecordResponse{
+		ID:         record.ID,
+		Principal:  record.Principal,
+		AuthMethod: record.AuthMethod,
+		Role:       record.Role,
+		Method:     record.Method,
+		Path:       record.Path,
+		Status:     record.Status,
+		RequestID:  record.RequestID,
+		CreatedAt:  record.CreatedAt,
+	}
+}
+
+// AuditController represents the audit api Controller
+type AuditController struct{}
+
+// NewAuditController creates a new Controller structure.
+func NewAuditController() Controller {
+	return &AuditController{}
+}
+
+// Routes list and binds all routes to the router group provided
+func (ct *AuditController) Routes(route *gin.RouterGroup) {
+	route.GET(RouteGETAdminAudit, ct.GetAuditRecords)
+}
+
+// GetAuditRecords handler returns the most recent audit records (optionally filtered by principal)
+func (ct *AuditController) GetAuditRecords(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Audit Records")
+	limit := auditDefaultLimit
+	if limitParam := c.Query(URLQueryTagLimit); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 || parsed > auditMaxLimit {
+			cause := errors.Errorf("limit should be between 1 and %d", auditMaxLimit)
+			c.Error(NewBadRequestError(InvalidQueryBadRequestErrorCode, cause, URLQueryTagLimit))
+			return
+		}
+		limit = parsed
+	}
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	records, err := entity.FindAuditRecords(db, c.Query(URLQueryTagPrincipal), limit)
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	response := make([]*AuditRecordResponse, len(records))
+	for i := range records {
+		response[i] = NewAuditRecordResponse(&records[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"p2pderivatives-oracle/internal/database/entity"
+	"strings"
+	"time"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// Role represents the permissions of a principal, each role includes the permissions of the previous ones
+type Role string
+
+const (
+	// RoleNone no permission, used as anonymous role to require credentials on every route
+	RoleNone Role = "none"
+	// RoleReader can read the oracle, asset and event routes
+	RoleReader Role = "reader"
+	// RoleAttester can also use the routes creating events or subscriptions (batch routes, webhooks)
+	RoleAttester Role = "attester"
+	// RoleAdmin can also use the admin routes
+	RoleAdmin Role = "admin"
+)
+
+var roleLevels = map[Role]int{RoleNone: 0, RoleReader: 1, RoleAttester: 2, RoleAdmin: 3}
+
+// ParseRole returns the role of its name, an error if unknown
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	if _, ok := roleLevels[role]; !ok {
+		return RoleNone, errors.Errorf("unknown role %s", name)
+	}
+	return role, nil
+}
+
+// Includes returns true if the role has the permissions of the other role
+func (r Role) Includes(other Role) bool {
+	return roleLevels[r] >= roleLevels[other]
+}
+
+const (
+	// AuthMethodAnonymous request without credentials
+	AuthMethodAnonymous = "anonymous"
+	// AuthMethodAPIKey request authenticated with an api key (X-API-Key header)
+	AuthMethodAPIKey = "apikey"
+	// AuthMethodJWT request authenticated with a HS256 JSON web token (Authorization: Bearer header)
+	AuthMethodJWT = "jwt"
+	// AuthMethodClientCert request authenticated with a verified TLS client certificate
+	AuthMethodClientCert = "mtls"
+
+	// HeaderAPIKey header containing the api key
+	HeaderAPIKey = "X-API-Key"
+
+	anonymousPrincipal = "anonymous"
+)
+
+// Principal represents the authenticated caller of a request
+type Principal struct {
+	// ID identity of the caller (api key name, token subject or certificate common name)
+	ID     string
+	Method string
+	Role   Role
+	// Limit rate limit of the principal, the api default is used if not set
+	Limit Limit
+	// DailyQuota maximum number of announcements per UTC day, unlimited if 0
+	DailyQuota int
+}
+
+// Owner returns the identity of the principal as owner of the resources it registers (unique across the authentication methods)
+func (p *Principal) Owner() string {
+	return p.Method + ":" + p.ID
+}
+
+// owns returns true if the principal can access a resource registered by the owner, the admins can access all resources
+func (p *Principal) owns(owner string) bool {
+	return p.Role.Includes(RoleAdmin) || p.Owner() == owner
+}
+
+// ErrUnknownAPIKey is returned when the api key of a request is not configured
+var ErrUnknownAPIKey = errors.New("unknown api key")
+
+// principalOf returns the principal of the request, anonymous without role if not authenticated
+func principalOf(c *gin.Context) *Principal {
+	if principal, ok := c.Value(ContextIDPrincipal).(*Principal); ok {
+		return principal
+	}
+	return &Principal{ID: anonymousPrincipal, Method: AuthMethodAnonymous, Role: RoleNone}
+}
+
+// Authenticator authenticates the requests from their credentials, the requests without credentials
+// get the anonymous role and the requests with invalid credentials are rejected
+type Authenticator struct {
+	anonymousRole   Role
+	apiKeys         map[string]*Principal
+	jwtSecret       []byte
+	jwtIssuer       string
+	clientCertRoles map[string]Role
+}
+
+// NewAuthenticator returns the authenticator of the api configuration
+func NewAuthenticator(config *Config) (*Authenticator, error) {
+	anonymousRole := RoleReader
+	if config.AuthAnonymousRole != "" {
+		var err error
+		anonymousRole, err = ParseRole(config.AuthAnonymousRole)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the routes requiring more than the reader role (nonce generation, webhooks) always require credentials
+	if !RoleReader.Includes(anonymousRole) {
+		return nil, errors.Errorf("invalid anonymous role %s, should be %s or %s", config.AuthAnonymousRole, RoleNone, RoleReader)
+	}
+	authenticator := &Authenticator{
+		anonymousRole:   anonymousRole,
+		apiKeys:         map[string]*Principal{},
+		jwtSecret:       []byte(config.AuthJWTSecret),
+		jwtIssuer:       config.AuthJWTIssuer,
+		clientCertRoles: map[string]Role{},
+	}
+	for name, key := range config.AuthAPIKeys {
+		role, err := ParseRole(key.Role)
+		if err != nil || role == RoleNone {
+			return nil, errors.Errorf("invalid role %s of api key %s", key.Role, name)
+		}
+		hash, err := hex.DecodeString(key.SHA256)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, errors.Errorf("invalid sha256 of api key %s", name)
+		}
+		authenticator.apiKeys[hex.EncodeToString(hash)] = &Principal{ID: name, Method: AuthMethodAPIKey, Role: role}
+	}
+	for commonName, name := range config.AuthClientCerts {
+		role, err := ParseRole(name)
+		if err != nil || role == RoleNone {
+			return nil, errors.Errorf("invalid role %s of client certificate %s", name, commonName)
+		}
+		authenticator.clientCertRoles[commonName] = role
+	}
+	return authenticator, nil
+}
+
+// Authenticate returns the principal of a request, in order of priority from a verified client certificate
+// (with a configured common name), an api key or a JSON web token
+func (a *Authenticator) Authenticate(req *http.Request, now time.Time) (*Principal, error) {
+	if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 {
+		commonName := req.TLS.VerifiedChains[0][0].Subject.CommonName
+		if role, ok := a.clientCertRoles[commonName]; ok {
+			return &Principal{ID: commonName, Method: AuthMethodClientCert, Role: role}, nil
+		}
+	}
+	if key := req.Header.Get(HeaderAPIKey); key != "" {
+		principal, ok := a.apiKeys[HashAPIKey(key)]
+		if !ok {
+			return nil, ErrUnknownAPIKey
+		}
+		return principal, nil
+	}
+	if header := req.Header.Get("Authorization"); header != "" {
+		if !strings.HasPrefix(header, "Bearer ") {
+			return nil, errors.New("unsupported authorization scheme")
+		}
+		return a.authenticateJWT(strings.TrimPrefix(header, "Bearer "), now)
+	}
+	return &Principal{ID: anonymousPrincipal, Method: AuthMethodAnonymous, Role: a.anonymousRole}, nil
+}
+
+// jwtClaims represents the supported claims of the JSON web tokens
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	Role      string `json:"role"`
+	Issuer    string `json:"iss"`
+	ExpiresAt int64  `json:"exp"`
+	NotBefore int64  `json:"nbf"`
+}
+
+// authenticateJWT verifies a HS256 JSON web token, the subject, role and expiration claims are required
+func (a *Authenticator) authenticateJWT(token string, now time.Time) (*Principal, error) {
+	if len(a.jwtSecret) == 0 {
+		return nil, errors.New("json web tokens are not accepted")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed json web token")
+	}
+	header := &struct {
+		Algorithm string `json:"alg"`
+	}{}
+	if err := decodeJWTPart(parts[0], header); err != nil || header.Algorithm != "HS256" {
+		return nil, errors.New("json web token should be signed with HS256")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.WithMessage(err, "malformed json web token signature")
+	}
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errors.New("invalid json web token signature")
+	}
+
+	claims := &jwtClaims{}
+	if err := decodeJWTPart(parts[1], claims); err != nil {
+		return nil, errors.WithMessage(err, "malformed json web token claims")
+	}
+	switch {
+	case claims.Subject == "":
+		return nil, errors.New("json web token without subject")
+	case claims.ExpiresAt == 0 || now.Unix() >= claims.ExpiresAt:
+		return nil, errors.New("json web token expired or without expiration")
+	case claims.NotBefore != 0 && now.Unix() < claims.NotBefore:
+		return nil, errors.New("json web token not yet valid")
+	case a.jwtIssuer != "" && claims.Issuer != a.jwtIssuer:
+		return nil, errors.Errorf("unexpected json web token issuer %s", claims.Issuer)
+	}
+	role, err := ParseRole(claims.Role)
+	if err != nil || role == RoleNone {
+		return nil, errors.Errorf("invalid json web token role %s", claims.Role)
+	}
+	return &Principal{ID: claims.Subject, Method: AuthMethodJWT, Role: role}, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Authenticate returns a middleware adding the principal of the request to the context,
+// the api keys which are not configured are looked up in the issued keys and the requests with invalid credentials are rejected
+func Authenticate(authenticator *Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var db *gorm.DB
+		if ormInstance, ok := c.Value(ContextIDOrm).(*orm.ORM); ok {
+			db = ormInstance.GetDB()
+		}
+		principal, err := AuthenticateRequest(authenticator, db, c.Request, time.Now())
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Set(ContextIDPrincipal, principal)
+		c.Next()
+	}
+}
+
+// AuthenticateRequest returns the principal of a request, the api keys which are not configured
+// are looked up in the issued keys of the database (if set), the errors are api errors
+func AuthenticateRequest(authenticator *Authenticator, db *gorm.DB, req *http.Request, now time.Time) (*Principal, error) {
+	if authenticator == nil {
+		return nil, NewUnauthorizedError(errors.New("authentication is not configured"))
+	}
+	principal, err := authenticator.Authenticate(req, now)
+	if errors.Is(err, ErrUnknownAPIKey) && db != nil {
+		principal, err = findIssuedKeyPrincipal(db, req.Header.Get(HeaderAPIKey))
+	}
+	if apiErr, ok := err.(*Error); ok {
+		return nil, apiErr
+	}
+	if err != nil {
+		return nil, NewUnauthorizedError(err)
+	}
+	return principal, nil
+}
+
+// findIssuedKeyPrincipal returns the principal of an issued api key, ErrUnknownAPIKey if not issued or revoked
+func findIssuedKeyPrincipal(db *gorm.DB, apiKey string) (*Principal, error) {
+	key, err := entity.FindActiveAPIKey(db, HashAPIKey(apiKey))
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrUnknownAPIKey
+	}
+	if err != nil {
+		return nil, NewUnknownDBError(err)
+	}
+	return newIssuedKeyPrincipal(key)
+}
+
+// Authorize returns a middleware requiring the read role for the GET requests and the write role for the others,
+// the requests requiring more than the reader role are recorded in the audit table
+func Authorize(read Role, write Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		required := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			required = read
+		}
+		principal := principalOf(c)
+
+		if !principal.Role.Includes(required) {
+			var apiErr *Error
+			cause := errors.Errorf("%s role required", required)
+			if principal.Method == AuthMethodAnonymous {
+				apiErr = NewUnauthorizedError(cause)
+			} else {
+				apiErr = NewForbiddenError(cause)
+			}
+			c.Error(apiErr)
+			c.Abort()
+			if RoleReader.Includes(required) {
+				return
+			}
+			recordAudit(c, principal, apiErr.HTTPStatusCode)
+			return
+		}
+
+		c.Next()
+		if !RoleReader.Includes(required) {
+			status := c.Writer.Status()
+			if err := c.Errors.Last(); err != nil {
+				if apiErr, ok := err.Err.(*Error); ok {
+					status = apiErr.HTTPStatusCode
+				} else {
+					status = http.StatusInternalServerError
+				}
+			}
+			recordAudit(c, principal, status)
+		}
+	}
+}
+
+// recordAudit stores the audit record of a privileged request, failures are only logged
+func recordAudit(c *gin.Context, principal *Principal, status int) {
+	ormInstance, ok := c.Value(ContextIDOrm).(*orm.ORM)
+	if !ok {
+		return
+	}
+	record := &entity.AuditRecord{
+		Principal:  principal.ID,
+		AuthMethod: principal.Method,
+		Role:       string(principal.Role),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Status:     status,
+		RequestID:  c.GetString(ContextIDRequestID),
+	}
+	if err := entity.CreateAuditRecord(ormInstance.GetDB(), record); err != nil {
+		ginlogrus.GetCtxLogger(c).Errorf("Could not record the audit of %s %s: %v", record.Method, record.Path, err)
+	}
+}
@@ -0,0 +1,179 @@
+package api_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJWTSecret = "jwt secret"
+
+var testAuthNow = time.Date(2020, time.May, 12, 8, 0, 0, 0, time.UTC)
+
+func NewTestJWT(secret string, alg string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func NewTestAuthenticator(t *testing.T) *api.Authenticator {
+	authenticator, err := api.NewAuthenticator(&api.Config{
+		AuthAnonymousRole: string(api.RoleNone),
+		AuthAPIKeys:       map[string]api.APIKeyConfig{"client": NewTestAPIKeyConfig(testReaderToken, api.RoleReader)},
+		AuthJWTSecret:     testJWTSecret,
+		AuthJWTIssuer:     "issuer",
+		AuthClientCerts:   map[string]string{"operator": string(api.RoleAdmin)},
+	})
+	require.NoError(t, err)
+	return authenticator
+}
+
+func TestNewAuthenticator_InvalidConfig_ReturnsError(t *testing.T) {
+	configs := []*api.Config{
+		{AuthAnonymousRole: string(api.RoleAdmin)},
+		{AuthAnonymousRole: string(api.RoleAttester)},
+		{AuthAnonymousRole: "unknown"},
+		{AuthAPIKeys: map[string]api.APIKeyConfig{"client": {SHA256: "not hex", Role: string(api.RoleReader)}}},
+		{AuthAPIKeys: map[string]api.APIKeyConfig{"client": NewTestAPIKeyConfig("key", api.RoleNone)}},
+		{AuthClientCerts: map[string]string{"operator": "root"}},
+	}
+	for _, config := range configs {
+		_, err := api.NewAuthenticator(config)
+		assert.Error(t, err)
+	}
+}
+
+func TestNewAuthenticator_NotConfigured_AnonymousReader(t *testing.T) {
+	authenticator, err := api.NewAuthenticator(&api.Config{})
+	require.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, "/asset/btcusd/rvalues", nil)
+
+	principal, err := authenticator.Authenticate(req, testAuthNow)
+
+	assert.NoError(t, err)
+	assert.Equal(t, api.AuthMethodAnonymous, principal.Method)
+	assert.Equal(t, api.RoleReader, principal.Role)
+}
+
+func TestAuthenticator_Authenticate_ReturnsPrincipal(t *testing.T) {
+	authenticator := NewTestAuthenticator(t)
+	validClaims := map[string]interface{}{"sub": "bot", "role": "attester", "iss": "issuer", "exp": testAuthNow.Add(time.Hour).Unix()}
+
+	cases := []struct {
+		name     string
+		setup    func(req *http.Request)
+		expected *api.Principal
+	}{
+		{"anonymous", func(req *http.Request) {}, &api.Principal{ID: "anonymous", Method: api.AuthMethodAnonymous, Role: api.RoleNone}},
+		{"api key", func(req *http.Request) { req.Header.Set(api.HeaderAPIKey, testReaderToken) },
+			&api.Principal{ID: "client", Method: api.AuthMethodAPIKey, Role: api.RoleReader}},
+		{"jwt", func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+NewTestJWT(testJWTSecret, "HS256", validClaims))
+		}, &api.Principal{ID: "bot", Method: api.AuthMethodJWT, Role: api.RoleAttester}},
+		{"client certificate", func(req *http.Request) {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "operator"}}}}}
+			req.Header.Set(api.HeaderAPIKey, testReaderToken)
+		}, &api.Principal{ID: "operator", Method: api.AuthMethodClientCert, Role: api.RoleAdmin}},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		tc.setup(req)
+		principal, err := authenticator.Authenticate(req, testAuthNow)
+		if assert.NoError(t, err, tc.name) {
+			assert.Equal(t, tc.expected, principal, tc.name)
+		}
+	}
+}
+
+func TestAuthenticator_Authenticate_InvalidCredentials_ReturnsError(t *testing.T) {
+	authenticator := NewTestAuthenticator(t)
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{"sub": "bot", "role": "attester", "iss": "issuer", "exp": testAuthNow.Add(time.Hour).Unix()}
+		for key, value := range overrides {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	headers := map[string]string{
+		"unknown api key":    api.HeaderAPIKey + ":unknown",
+		"basic scheme":       "Authorization:Basic dXNlcjpwYXNz",
+		"malformed token":    "Authorization:Bearer abc",
+		"wrong secret":       "Authorization:Bearer " + NewTestJWT("other secret", "HS256", claims(nil)),
+		"wrong algorithm":    "Authorization:Bearer " + NewTestJWT(testJWTSecret, "none", claims(nil)),
+		"expired":            "Authorization:Bearer " + NewTestJWT(testJWTSecret, "HS256", claims(map[string]interface{}{"exp": testAuthNow.Unix()})),
+		"not yet valid":      "Authorization:Bearer " + NewTestJWT(testJWTSecret, "HS256", claims(map[string]interface{}{"nbf": testAuthNow.Add(time.Minute).Unix()})),
+		"wrong issuer":       "Authorization:Bearer " + NewTestJWT(testJWTSecret, "HS256", claims(map[string]interface{}{"iss": "other"})),
+		"unknown role":       "Authorization:Bearer " + NewTestJWT(testJWTSecret, "HS256", claims(map[string]interface{}{"role": "root"})),
+		"without subject":    "Authorization:Bearer " + NewTestJWT(testJWTSecret, "HS256", claims(map[string]interface{}{"sub": ""})),
+		"without expiration": "Authorization:Bearer " + NewTestJWT(testJWTSecret, "HS256", claims(map[string]interface{}{"exp": 0})),
+	}
+	for name, header := range headers {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		parts := strings.SplitN(header, ":", 2)
+		req.Header.Set(parts[0], parts[1])
+		_, err := authenticator.Authenticate(req, testAuthNow)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestAuthorize_RequiresRoleByMethod(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(api.ErrorHandler())
+	withRole := func(role api.Role, method string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set(api.ContextIDPrincipal, &api.Principal{ID: "caller", Method: method, Role: role})
+		}
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	for _, role := range []api.Role{api.RoleNone, api.RoleReader, api.RoleAttester} {
+		group := engine.Group("/"+string(role), withRole(role, api.AuthMethodAPIKey), api.Authorize(api.RoleReader, api.RoleAttester))
+		group.GET("", ok)
+		group.POST("", ok)
+	}
+	anonymous := engine.Group("/anonymous", withRole(api.RoleNone, api.AuthMethodAnonymous), api.Authorize(api.RoleReader, api.RoleAttester))
+	anonymous.GET("", ok)
+
+	expected := map[string]int{
+		"GET /none":      http.StatusForbidden,
+		"POST /none":     http.StatusForbidden,
+		"GET /reader":    http.StatusOK,
+		"POST /reader":   http.StatusForbidden,
+		"GET /attester":  http.StatusOK,
+		"POST /attester": http.StatusOK,
+		"GET /anonymous": http.StatusUnauthorized,
+	}
+	for route, status := range expected {
+		parts := strings.Fields(route)
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(parts[0], parts[1], nil)
+		engine.ServeHTTP(resp, req)
+		assert.Equal(t, status, resp.Code, route)
+	}
+}
+
+func TestRole_Includes_FollowsHierarchy(t *testing.T) {
+	assert.True(t, api.RoleAdmin.Includes(api.RoleAttester))
+	assert.True(t, api.RoleAttester.Includes(api.RoleReader))
+	assert.True(t, api.RoleReader.Includes(api.RoleReader))
+	assert.False(t, api.RoleReader.Includes(api.RoleAttester))
+	assert.False(t, api.RoleNone.Includes(api.RoleReader))
+}
@@ -11,4 +11,10 @@ const (
 	ContextIDDataFeed = "datafeed"
 	// ContextIDRequestID ID to use to retrieve requestID in gin.handler context
 	ContextIDRequestID = "Request-Id"
+	// ContextIDPrincipal ID to use to retrieve the authenticated Principal in gin.handler context
+	ContextIDPrincipal = "principal"
+	// ContextIDUsage ID to use to retrieve the metered Usage of the request in gin.handler context
+	ContextIDUsage = "usage"
+	// ContextIDEventCache ID to use to retrieve the EventCache in gin.handler context
+	ContextIDEventCache = "event-cache"
 )
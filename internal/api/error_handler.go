@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,6 +22,9 @@ func ErrorHandler() gin.HandlerFunc {
 		if !ok {
 			c.AbortWithStatus(http.StatusInternalServerError)
 		} else {
+			if retryAfter := errorResponse.RetryAfterSeconds(); retryAfter > 0 {
+				c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			}
 			c.AbortWithStatusJSON(errorResponse.HTTPStatusCode, errorResponse)
 		}
 	}
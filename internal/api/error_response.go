@@ -2,7 +2,9 @@ package api
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,6 +38,24 @@ const (
 
 	// UnknownCryptoErrorCode represents an error caused by the crypto computation resulting in an unexpected state.
 	UnknownCryptoErrorCode
+
+	// InvalidRequestBodyBadRequestErrorCode represents a request body being invalid.
+	InvalidRequestBodyBadRequestErrorCode
+	// InvalidIDBadRequestErrorCode represents an id parameter being in invalid format.
+	InvalidIDBadRequestErrorCode
+	// InvalidQueryBadRequestErrorCode represents a query parameter being invalid.
+	InvalidQueryBadRequestErrorCode
+
+	// UnauthorizedErrorCode represents a request without valid credentials.
+	UnauthorizedErrorCode
+	// RecordAlreadyExistsDBErrorCode represents a record already existing error
+	RecordAlreadyExistsDBErrorCode
+	// ForbiddenErrorCode represents a request of a principal without the required role.
+	ForbiddenErrorCode
+	// TooManyRequestsErrorCode represents a request over the rate limit of its client.
+	TooManyRequestsErrorCode
+	// QuotaExceededErrorCode represents a request over the daily quota of its principal.
+	QuotaExceededErrorCode
 )
 
 // ErrorResponse represents an error response from the api
@@ -43,6 +63,8 @@ type ErrorResponse struct {
 	ErrorCode int    `json:"errorCode"`
 	Message   string `json:"message"`
 	Cause     string `json:"cause,omitempty"`
+	// RetryAfter number of seconds after which the request can be retried (also sent as Retry-After header)
+	RetryAfter int64 `json:"retryAfter,omitempty"`
 }
 
 // Error represents an api error, the json marshall is meant to be sent to client
@@ -52,6 +74,8 @@ type Error struct {
 	ErrorCode      int
 	ClientMessage  string
 	Cause          error
+	// RetryAfter duration after which the request can be retried, not set if retrying is useless
+	RetryAfter time.Duration
 }
 
 func (e *Error) Error() string {
@@ -66,12 +90,21 @@ func (e *Error) MarshalJSON() ([]byte, error) {
 	}
 
 	return json.Marshal(&ErrorResponse{
-		ErrorCode: e.ErrorCode,
-		Message:   e.ClientMessage,
-		Cause:     cause,
+		ErrorCode:  e.ErrorCode,
+		Message:    e.ClientMessage,
+		Cause:      cause,
+		RetryAfter: e.RetryAfterSeconds(),
 	})
 }
 
+// RetryAfterSeconds returns the retry after duration rounded up to the second, 0 if not set
+func (e *Error) RetryAfterSeconds() int64 {
+	if e.RetryAfter <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(e.RetryAfter.Seconds()))
+}
+
 // NewDBError returns a DB error
 func NewDBError(httpStatusCode int, code int, cause error, clientMessage string) *Error {
 	return &Error{
@@ -87,6 +120,55 @@ func NewRecordNotFoundDBError(cause error, recordInfo string) *Error {
 	return NewDBError(http.StatusNotFound, RecordNotFoundDBErrorCode, cause, "Could not find the specified record "+recordInfo)
 }
 
+// NewRecordAlreadyExistsDBError returns a DB error when a record already exists (with record information)
+func NewRecordAlreadyExistsDBError(cause error, recordInfo string) *Error {
+	return NewDBError(http.StatusConflict, RecordAlreadyExistsDBErrorCode, cause, "The specified record already exists "+recordInfo)
+}
+
+// NewUnauthorizedError returns an Unauthorized error for a request without valid credentials
+func NewUnauthorizedError(cause error) *Error {
+	return &Error{
+		HTTPStatusCode: http.StatusUnauthorized,
+		ErrorCode:      UnauthorizedErrorCode,
+		ClientMessage:  "Unauthorized: missing or invalid credentials",
+		Cause:          cause,
+	}
+}
+
+// NewForbiddenError returns a Forbidden error for a request of a principal without the required role
+func NewForbiddenError(cause error) *Error {
+	return &Error{
+		HTTPStatusCode: http.StatusForbidden,
+		ErrorCode:      ForbiddenErrorCode,
+		ClientMessage:  "Forbidden: insufficient role",
+		Cause:          cause,
+	}
+}
+
+// NewTooManyRequestsError returns a Too Many Requests error for a request over the rate limit of its client
+// with the duration after which it can be retried
+func NewTooManyRequestsError(cause error, retryAfter time.Duration) *Error {
+	return &Error{
+		HTTPStatusCode: http.StatusTooManyRequests,
+		ErrorCode:      TooManyRequestsErrorCode,
+		ClientMessage:  "Too many requests: rate limit exceeded",
+		Cause:          cause,
+		RetryAfter:     retryAfter,
+	}
+}
+
+// NewQuotaExceededError returns a Too Many Requests error for a request over the daily quota of its principal
+// with the duration until the quota is reset
+func NewQuotaExceededError(cause error, retryAfter time.Duration) *Error {
+	return &Error{
+		HTTPStatusCode: http.StatusTooManyRequests,
+		ErrorCode:      QuotaExceededErrorCode,
+		ClientMessage:  "Too many requests: daily quota exceeded",
+		Cause:          cause,
+		RetryAfter:     retryAfter,
+	}
+}
+
 // NewBadRequestError returns a Bad Request error with bad parameter info
 func NewBadRequestError(code int, cause error, badParameterInfo string) *Error {
 	return &Error{
@@ -97,6 +179,18 @@ func NewBadRequestError(code int, cause error, badParameterInfo string) *Error {
 	}
 }
 
+// NewTooEarlyError returns a Too Early error for a request made before the requested data is available
+// with the duration after which it can be retried
+func NewTooEarlyError(code int, cause error, retryAfter time.Duration) *Error {
+	return &Error{
+		HTTPStatusCode: http.StatusTooEarly,
+		ErrorCode:      code,
+		ClientMessage:  "Too early: the requested data is not yet available",
+		Cause:          cause,
+		RetryAfter:     retryAfter,
+	}
+}
+
 // NewUnknownDBError returns an unknown DB error with default message
 func NewUnknownDBError(cause error) *Error {
 	return NewUnknownInternalError(cause, "Database")
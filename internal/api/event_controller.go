@@ -0,0 +1,132 @@
+package api
+
+import (
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/oracle"
+	"strings"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+	"github.com/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// URLParamTagRvalue Tag to use as rvalue parameter in route
+	URLParamTagRvalue = "rvalue"
+	// URLParamTagSignature Tag to use as signature parameter in route
+	URLParamTagSignature = "signature"
+	// RouteGETEventByID relative GET route to retrieve an event by id
+	RouteGETEventByID = "/id/:" + URLParamTagID
+	// RouteGETEventByRvalue relative GET route to retrieve an event by rvalue
+	RouteGETEventByRvalue = "/rvalue/:" + URLParamTagRvalue
+	// RouteGETEventBySignature relative GET route to retrieve an event by signature
+	RouteGETEventBySignature = "/signature/:" + URLParamTagSignature
+)
+
+const eventIDSeparator = ":"
+
+// NewEventID returns the id of an event, <asset id>:<event type>:<publish date ISO8601>
+func NewEventID(assetID string, eventType string, publishDate time.Time) string {
+	return strings.Join([]string{assetID, eventType, publishDate.UTC().Format(TimeFormatISO8601)}, eventIDSeparator)
+}
+
+// ParseEventID returns the asset id, event type and publish date of an event id
+func ParseEventID(eventID string) (string, string, *time.Time, error) {
+	parts := strings.SplitN(eventID, eventIDSeparator, 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", nil, errors.Errorf("invalid event id %s, should be <asset id>:<event type>:<publish date>", eventID)
+	}
+	publishDate, err := ParseTime(parts[2])
+	if err != nil {
+		return "", "", nil, err
+	}
+	return parts[0], parts[1], publishDate, nil
+}
+
+// EventController represents the event lookup api Controller
+type EventController struct {
+	version APIVersion
+	assets  AssetProvider
+}
+
+// NewEventController creates a new Controller structure with the given parameters.
+func NewEventController(assets AssetProvider) *EventController {
+	return &EventController{
+		version: APIVersion1,
+		assets:  assets,
+	}
+}
+
+// NewEventControllerV2 creates a new Controller structure returning the v2 event responses.
+func NewEventControllerV2(assets AssetProvider) *EventController {
+	return &EventController{
+		version: APIVersion2,
+		assets:  assets,
+	}
+}
+
+// Routes list and binds all routes to the router group provided
+func (ct *EventController) Routes(route *gin.RouterGroup) {
+	route.GET(RouteGETEventByID, ct.GetEventByID)
+	route.GET(RouteGETEventByRvalue, ct.GetEventByRvalue)
+	route.GET(RouteGETEventBySignature, ct.GetEventBySignature)
+}
+
+// GetEventByID handler returns the descriptor of an announced event by event id
+func (ct *EventController) GetEventByID(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Event By ID")
+	eventID := c.Param(URLParamTagID)
+	assetID, eventType, publishDate, err := ParseEventID(eventID)
+	if err != nil {
+		c.Error(NewBadRequestError(InvalidIDBadRequestErrorCode, err, eventID))
+		return
+	}
+	cache := eventCacheOf(c).DLCData
+	ct.respondEvent(c, eventID, func(db *gorm.DB) (*entity.DLCData, error) {
+		return cache.FindDLCDataPublishedAt(db, assetID, *publishDate, eventType)
+	})
+}
+
+// GetEventByRvalue handler returns the descriptor of an announced event by rvalue (nonce)
+func (ct *EventController) GetEventByRvalue(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Event By Rvalue")
+	rvalue := c.Param(URLParamTagRvalue)
+	ct.respondEvent(c, "rvalue "+rvalue, func(db *gorm.DB) (*entity.DLCData, error) {
+		return entity.FindDLCDataWithRValue(db, rvalue)
+	})
+}
+
+// GetEventBySignature handler returns the descriptor of an attested event by signature
+func (ct *EventController) GetEventBySignature(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Event By Signature")
+	signature := c.Param(URLParamTagSignature)
+	ct.respondEvent(c, "signature "+signature, func(db *gorm.DB) (*entity.DLCData, error) {
+		return entity.FindDLCDataWithSignature(db, signature)
+	})
+}
+
+func (ct *EventController) respondEvent(c *gin.Context, recordInfo string, find func(db *gorm.DB) (*entity.DLCData, error)) {
+	oracleInstance := c.MustGet(ContextIDOracle).(*oracle.Oracle)
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	dlcData, err := find(db)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			c.Error(NewRecordNotFoundDBError(err, recordInfo))
+			return
+		}
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	config, _, err := findAssetConfig(ct.assets, dlcData.AssetID)
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	response := ct.version.newEventDescriptorResponse(oracleInstance.PublicKey, dlcData, config)
+	eventCacheOf(c).respondEvent(c, dlcData, response)
+}
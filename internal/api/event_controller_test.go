@@ -0,0 +1,142 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	mock_datafeed "p2pderivatives-oracle/test/mock/datafeed"
+	mock_dlccrypto "p2pderivatives-oracle/test/mock/dlccrypto"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testEventRvalue    = "event rvalue"
+	testEventSignature = "event signature"
+)
+
+func SetupEventEngine(t *testing.T, recorder *httptest.ResponseRecorder) (*gin.Context, *gin.Engine) {
+	oracleInstance, err := NewTestOracleService()
+	assert.NoError(t, err)
+	ormInstance := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{})
+	db := ormInstance.GetDB()
+	db.Create(TestAsset)
+	_, err = entity.CreateDLCData(db, TestAsset.AssetID, InDbDLCData.PublishedDate, "digits", "kvalue", testEventRvalue)
+	assert.NoError(t, err)
+	_, err = entity.UpdateDLCDataAttestation(db, TestAsset.AssetID, InDbDLCData.PublishedDate, "digits", entity.DLCData{Signature: testEventSignature, Value: "9000"})
+	assert.NoError(t, err)
+	setup := func(c *gin.Context) {
+		c.Set(api.ContextIDOracle, oracleInstance)
+		c.Set(api.ContextIDOrm, ormInstance)
+	}
+	controller := api.NewEventController(api.StaticAssets{TestAsset.AssetID: *TestAssetConfig})
+	return SetupEngine(recorder, controller, api.ErrorHandler(), setup)
+}
+
+func GetEvent(t *testing.T, route string) (int, *api.EventDescriptorResponse) {
+	resp := httptest.NewRecorder()
+	c, r := SetupEventEngine(t, resp)
+	c.Request, _ = http.NewRequest(http.MethodGet, route, nil)
+	r.ServeHTTP(resp, c.Request)
+	descriptor := &api.EventDescriptorResponse{}
+	if resp.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), descriptor))
+	}
+	return resp.Code, descriptor
+}
+
+func TestEventController_GetEvent_Known_ReturnsDescriptor(t *testing.T) {
+	eventID := api.NewEventID(TestAsset.AssetID, "digits", InDbDLCData.PublishedDate)
+	routes := []string{
+		"/id/" + eventID,
+		"/rvalue/" + testEventRvalue,
+		"/signature/" + testEventSignature,
+	}
+	for _, route := range routes {
+		status, descriptor := GetEvent(t, route)
+		if assert.Equal(t, http.StatusOK, status, route) {
+			assert.Equal(t, eventID, descriptor.EventID)
+			assert.Equal(t, TestAsset.AssetID, descriptor.AssetID)
+			assert.Equal(t, InDbDLCData.PublishedDate, descriptor.PublishedDate)
+			assert.Equal(t, testEventRvalue, descriptor.Rvalue)
+			assert.Equal(t, testEventSignature, descriptor.Signature)
+			assert.Equal(t, "9000", descriptor.Value)
+			assert.Equal(t, api.EventStatusSigned, descriptor.Status)
+			assert.Equal(t, TestAssetConfig.Currency, descriptor.Currency)
+		}
+	}
+}
+
+func TestEventController_GetEvent_Unknown_ReturnsNotFound(t *testing.T) {
+	eventID := api.NewEventID(TestAsset.AssetID, "digits", InDbDLCData.PublishedDate.Add(TestAssetConfig.Frequency))
+	for _, route := range []string{"/id/" + eventID, "/rvalue/unknown", "/signature/unknown"} {
+		status, _ := GetEvent(t, route)
+		assert.Equal(t, http.StatusNotFound, status, route)
+	}
+}
+
+func TestEventController_GetEventByID_Invalid_ReturnsBadRequest(t *testing.T) {
+	for _, route := range []string{"/id/btcusd", "/id/btcusd:digits:yesterday", "/id/:digits:2020-01-01T00:00:00Z"} {
+		status, _ := GetEvent(t, route)
+		assert.Equal(t, http.StatusBadRequest, status, route)
+	}
+}
+
+func TestParseEventID_ReturnsEventKey(t *testing.T) {
+	eventID := api.NewEventID("btcusd", "above(10.5)", InDbDLCData.PublishedDate)
+	assetID, eventType, publishDate, err := api.ParseEventID(eventID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "btcusd", assetID)
+		assert.Equal(t, "above(10.5)", eventType)
+		assert.Equal(t, InDbDLCData.PublishedDate, *publishDate)
+	}
+}
+
+func TestOracleAPI_GetAssetRvalue_Unknown_ReturnsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	apiConfig := &api.Config{}
+	assert.NoError(t, test.InitializeConfig(apiConfig))
+	oracleInstance, err := NewTestOracleService()
+	assert.NoError(t, err)
+	oracleAPI := api.NewOracleAPI(
+		apiConfig,
+		test.NewLogger(),
+		oracleInstance,
+		test.NewOrm(&entity.Asset{}, &entity.DLCData{}),
+		api.StaticAssets(apiConfig.AssetConfigs),
+		mock_dlccrypto.NewMockCryptoService(ctrl),
+		mock_datafeed.NewMockDataFeed(ctrl))
+	resp := httptest.NewRecorder()
+	c, r := SetupEngine(resp, oracleAPI, oracleAPI.GlobalMiddlewares()...)
+	c.Request, _ = http.NewRequest(http.MethodGet, api.AssetBaseRoute+"/rvalue/unknown", nil)
+
+	r.ServeHTTP(resp, c.Request)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code, resp.Body.String())
+}
+
+func TestEventController_GetEventByID_AlternativeTimeFormats_ReturnsDescriptor(t *testing.T) {
+	date := InDbDLCData.PublishedDate
+	eventID := api.NewEventID(TestAsset.AssetID, "digits", date)
+	times := []string{
+		strconv.FormatInt(date.Unix(), 10),
+		strconv.FormatInt(date.Unix()*1000, 10),
+		date.In(time.FixedZone("", 2*60*60)).Format(time.RFC3339),
+		date.Format("2006-01-02T15:04:05.000Z07:00"),
+	}
+	for _, timeParam := range times {
+		route := "/id/" + TestAsset.AssetID + ":digits:" + timeParam
+		status, descriptor := GetEvent(t, route)
+		if assert.Equal(t, http.StatusOK, status, route) {
+			assert.Equal(t, eventID, descriptor.EventID)
+		}
+	}
+}
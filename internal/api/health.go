@@ -0,0 +1,217 @@
+package api
+
+import (
+	"net/http"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/datafeed"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/oracle"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RouteGETHealthz GET route of the liveness probe
+	RouteGETHealthz = "/healthz"
+	// RouteGETReadyz GET route of the readiness probe, checking the oracle dependencies
+	RouteGETReadyz = "/readyz"
+
+	// HealthStatusOK status of a passed check
+	HealthStatusOK = "ok"
+	// HealthStatusFailed status of a failed check
+	HealthStatusFailed = "failed"
+
+	// HealthCheckDatabase name of the database connectivity check
+	HealthCheckDatabase = "database"
+	// HealthCheckDataFeed name of the datafeed reachability check
+	HealthCheckDataFeed = "datafeed"
+	// HealthCheckOracleKey name of the oracle key check
+	HealthCheckOracleKey = "oracleKey"
+	// HealthCheckClock name of the clock check
+	HealthCheckClock = "clock"
+)
+
+// minClockDate date before which the clock is considered wrong (no asset can start before it)
+var minClockDate = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// HealthResponse represents the result of a probe and of each of its checks
+type HealthResponse struct {
+	Status string                          `json:"status"`
+	Checks map[string]*HealthCheckResponse `json:"checks,omitempty"`
+}
+
+// HealthCheckResponse represents the result of a dependency check
+type HealthCheckResponse struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+func newHealthCheckResponse(err error, checkedAt time.Time) *HealthCheckResponse {
+	if err != nil {
+		return &HealthCheckResponse{Status: HealthStatusFailed, Error: err.Error(), CheckedAt: checkedAt}
+	}
+	return &HealthCheckResponse{Status: HealthStatusOK, CheckedAt: checkedAt}
+}
+
+// HealthController represents the health api Controller
+type HealthController struct {
+	assets            AssetProvider
+	expectedPublicKey string
+	maxClockSkew      time.Duration
+	probeInterval     time.Duration
+
+	// the datafeed probe result is cached to avoid calling the provider on each probe
+	probeMutex sync.Mutex
+	probe      *HealthCheckResponse
+}
+
+// NewHealthController creates a new Controller structure,
+// the oracle public key is compared to expectedPublicKey if set
+// and the datafeed probe is cached for probeInterval
+func NewHealthController(
+	assets AssetProvider,
+	expectedPublicKey string,
+	maxClockSkew time.Duration,
+	probeInterval time.Duration) Controller {
+	return &HealthController{
+		assets:            assets,
+		expectedPublicKey: expectedPublicKey,
+		maxClockSkew:      maxClockSkew,
+		probeInterval:     probeInterval,
+	}
+}
+
+// Routes list and binds all routes to the router group provided
+func (ct *HealthController) Routes(route *gin.RouterGroup) {
+	route.GET(RouteGETHealthz, ct.GetHealthz)
+	route.GET(RouteGETReadyz, ct.GetReadyz)
+}
+
+// GetHealthz handler returns ok while the server is serving requests
+func (ct *HealthController) GetHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, &HealthResponse{Status: HealthStatusOK})
+}
+
+// GetReadyz handler checks the oracle dependencies,
+// returning 503 Service Unavailable with the failed checks if one of them fails
+func (ct *HealthController) GetReadyz(c *gin.Context) {
+	now := time.Now().UTC()
+	checks := map[string]*HealthCheckResponse{
+		HealthCheckDatabase:  newHealthCheckResponse(ct.checkDatabase(c), now),
+		HealthCheckDataFeed:  ct.checkDataFeed(c, now),
+		HealthCheckOracleKey: newHealthCheckResponse(ct.checkOracleKey(c), now),
+		HealthCheckClock:     newHealthCheckResponse(ct.checkClock(c, now), now),
+	}
+	response := &HealthResponse{Status: HealthStatusOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status != HealthStatusOK {
+			response.Status = HealthStatusFailed
+		}
+	}
+	status := http.StatusOK
+	if response.Status != HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, response)
+}
+
+func (ct *HealthController) checkDatabase(c *gin.Context) error {
+	ormInstance, ok := c.Value(ContextIDOrm).(*orm.ORM)
+	if !ok || ormInstance == nil || !ormInstance.IsInitialized() {
+		return errors.New("database is not initialized")
+	}
+	return errors.WithMessage(ormInstance.GetDB().DB().Ping(), "could not reach the database")
+}
+
+// checkDataFeed requests the current price of the first attestable asset, at most once per probe interval
+func (ct *HealthController) checkDataFeed(c *gin.Context, now time.Time) *HealthCheckResponse {
+	ct.probeMutex.Lock()
+	defer ct.probeMutex.Unlock()
+	if ct.probe != nil && now.Sub(ct.probe.CheckedAt) < ct.probeInterval {
+		return ct.probe
+	}
+	ct.probe = newHealthCheckResponse(ct.probeDataFeed(c), now)
+	return ct.probe
+}
+
+func (ct *HealthController) probeDataFeed(c *gin.Context) error {
+	feed, ok := c.Value(ContextIDDataFeed).(datafeed.DataFeed)
+	if !ok || feed == nil {
+		return errors.New("datafeed is not set")
+	}
+	assets, err := ct.assets.AssetConfigs()
+	if err != nil {
+		return errors.WithMessage(err, "could not load the assets")
+	}
+	assetIDs := make([]string, 0, len(assets))
+	for assetID, config := range assets {
+		if config.IsAttestable() && !config.IsIndex() {
+			assetIDs = append(assetIDs, assetID)
+		}
+	}
+	if len(assetIDs) == 0 {
+		return nil
+	}
+	sort.Strings(assetIDs)
+	config := assets[assetIDs[0]]
+	if _, err := feed.FindCurrentAssetPrice(config.Asset, config.Currency); err != nil {
+		return errors.WithMessagef(err, "could not get the current price of %s", assetIDs[0])
+	}
+	return nil
+}
+
+// checkOracleKey verifies that the public key is derived from the loaded private key and is the expected one
+func (ct *HealthController) checkOracleKey(c *gin.Context) error {
+	oracleInstance, ok := c.Value(ContextIDOracle).(*oracle.Oracle)
+	if !ok || oracleInstance == nil || oracleInstance.PrivateKey == nil || oracleInstance.PublicKey == nil {
+		return errors.New("oracle key is not loaded")
+	}
+	crypto, ok := c.Value(ContextIDCryptoService).(dlccrypto.CryptoService)
+	if !ok || crypto == nil {
+		return errors.New("crypto service is not set")
+	}
+	derived, err := crypto.SchnorrPublicKeyFromPrivateKey(oracleInstance.PrivateKey)
+	if err != nil {
+		return errors.WithMessage(err, "could not derive the oracle public key")
+	}
+	publicKey := oracleInstance.PublicKey.EncodeToString()
+	if derived.EncodeToString() != publicKey {
+		return errors.New("oracle public key does not match the private key")
+	}
+	if ct.expectedPublicKey != "" && ct.expectedPublicKey != publicKey {
+		return errors.Errorf("oracle public key %s is not the expected one", publicKey)
+	}
+	return nil
+}
+
+// checkClock verifies that the clock is after the minimum date
+// and not behind the last stored notification (clock going backwards)
+func (ct *HealthController) checkClock(c *gin.Context, now time.Time) error {
+	if now.Before(minClockDate) {
+		return errors.Errorf("clock %s is before %s", now.Format(TimeFormatISO8601), minClockDate.Format(TimeFormatISO8601))
+	}
+	ormInstance, ok := c.Value(ContextIDOrm).(*orm.ORM)
+	if !ok || ormInstance == nil || !ormInstance.IsInitialized() {
+		return nil
+	}
+	db := ormInstance.GetDB()
+	sequence, err := entity.FindLastEventNotificationSequence(db)
+	if err != nil || sequence == 0 {
+		// the database failure is reported by its own check
+		return nil
+	}
+	last, err := entity.FindEventNotification(db, sequence)
+	if err != nil {
+		return nil
+	}
+	if skew := last.CreatedAt.Sub(now); skew > ct.maxClockSkew {
+		return errors.Errorf("clock is %s behind the last notification", skew)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	mock_datafeed "p2pderivatives-oracle/test/mock/datafeed"
+	mock_dlccrypto "p2pderivatives-oracle/test/mock/dlccrypto"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func SetupHealthEngine(t *testing.T, ctrl *gomock.Controller, expectedPublicKey string) (*gin.Engine, *mock_datafeed.MockDataFeed) {
+	oracleInstance, err := NewTestOracleService()
+	require.NoError(t, err)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	crypto.EXPECT().SchnorrPublicKeyFromPrivateKey(oracleInstance.PrivateKey).Return(oracleInstance.PublicKey, nil).AnyTimes()
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	orm := test.NewOrm(&entity.EventNotification{})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(api.ErrorHandler(), func(c *gin.Context) {
+		c.Set(api.ContextIDOracle, oracleInstance)
+		c.Set(api.ContextIDCryptoService, crypto)
+		c.Set(api.ContextIDDataFeed, feed)
+		c.Set(api.ContextIDOrm, orm)
+	})
+	assets := api.StaticAssets{TestAsset.AssetID: *TestAssetConfig}
+	api.NewHealthController(assets, expectedPublicKey, time.Minute, time.Hour).Routes(engine.Group(""))
+	return engine, feed
+}
+
+func GetHealth(t *testing.T, engine *gin.Engine, route string) (int, *api.HealthResponse) {
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, route, nil)
+	engine.ServeHTTP(resp, req)
+	health := &api.HealthResponse{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), health), resp.Body.String())
+	return resp.Code, health
+}
+
+func TestHealthController_Healthz_ReturnsOk(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	engine, _ := SetupHealthEngine(t, ctrl, "")
+
+	code, health := GetHealth(t, engine, api.RouteGETHealthz)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, api.HealthStatusOK, health.Status)
+	assert.Empty(t, health.Checks)
+}
+
+func TestHealthController_Readyz_AllChecksPass_ReturnsOkAndCachesDataFeedProbe(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	oracleInstance, err := NewTestOracleService()
+	require.NoError(t, err)
+	engine, feed := SetupHealthEngine(t, ctrl, oracleInstance.PublicKey.EncodeToString())
+	price := 8000.0
+	feed.EXPECT().FindCurrentAssetPrice(TestAssetConfig.Asset, TestAssetConfig.Currency).Return(&price, nil).Times(1)
+
+	for i := 0; i < 2; i++ {
+		code, health := GetHealth(t, engine, api.RouteGETReadyz)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, api.HealthStatusOK, health.Status)
+		for _, name := range []string{api.HealthCheckDatabase, api.HealthCheckDataFeed, api.HealthCheckOracleKey, api.HealthCheckClock} {
+			if assert.Contains(t, health.Checks, name) {
+				assert.Equal(t, api.HealthStatusOK, health.Checks[name].Status, name)
+			}
+		}
+	}
+}
+
+func TestHealthController_Readyz_FailedChecks_ReturnsServiceUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	engine, feed := SetupHealthEngine(t, ctrl, "unexpected key")
+	feed.EXPECT().FindCurrentAssetPrice(gomock.Any(), gomock.Any()).Return(nil, errors.New("unreachable"))
+
+	code, health := GetHealth(t, engine, api.RouteGETReadyz)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, api.HealthStatusFailed, health.Status)
+	assert.Equal(t, api.HealthStatusOK, health.Checks[api.HealthCheckDatabase].Status)
+	assert.Equal(t, api.HealthStatusFailed, health.Checks[api.HealthCheckDataFeed].Status)
+	assert.Contains(t, health.Checks[api.HealthCheckDataFeed].Error, "unreachable")
+	assert.Equal(t, api.HealthStatusFailed, health.Checks[api.HealthCheckOracleKey].Status)
+	assert.Equal(t, api.HealthStatusOK, health.Checks[api.HealthCheckClock].Status)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"p2pderivatives-oracle/internal/database/entity"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HeaderETag response header containing the entity tag of the event response
+	HeaderETag = "ETag"
+	// HeaderLastModified response header containing the last modification date of the event
+	HeaderLastModified = "Last-Modified"
+	// HeaderCacheControl response header containing the caching policy of the event response
+	HeaderCacheControl = "Cache-Control"
+	// HeaderIfNoneMatch conditional request header containing the entity tags known by the client
+	HeaderIfNoneMatch = "If-None-Match"
+	// HeaderIfModifiedSince conditional request header containing the last modification date known by the client
+	HeaderIfModifiedSince = "If-Modified-Since"
+
+	// CacheControlSigned caching policy of the signed events, which never change
+	CacheControlSigned = "public, max-age=31536000, immutable"
+)
+
+// EventCache represents how the event responses are cached,
+// by the HTTP clients and in process for the signed events
+type EventCache struct {
+	// UnsignedMaxAge duration the unsigned event responses can be cached by the clients
+	UnsignedMaxAge time.Duration
+	// DLCData in-process cache of the signed events, no cache if nil
+	DLCData *entity.DLCDataCache
+}
+
+// NewEventCache returns the event cache of the api configuration
+func NewEventCache(config *Config) *EventCache {
+	return &EventCache{
+		UnsignedMaxAge: config.CacheUnsignedMaxAge,
+		DLCData:        entity.NewDLCDataCache(config.CacheSize),
+	}
+}
+
+// eventCacheOf returns the event cache of the request, a cache without in-process cache if not set
+func eventCacheOf(c *gin.Context) *EventCache {
+	if cache, ok := c.Value(ContextIDEventCache).(*EventCache); ok {
+		return cache
+	}
+	return &EventCache{}
+}
+
+// cacheControl returns the caching policy of an event response
+func (e *EventCache) cacheControl(dlcData *entity.DLCData) string {
+	if dlcData.IsSigned() {
+		return CacheControlSigned
+	}
+	return fmt.Sprintf("public, max-age=%d", int(e.UnsignedMaxAge.Seconds()))
+}
+
+// respondEvent writes an event response with its caching headers,
+// or 304 Not Modified if the conditional request headers match the response
+func (e *EventCache) respondEvent(c *gin.Context, dlcData *entity.DLCData, response interface{}) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		c.Error(NewUnknownInternalError(err, "Event response"))
+		return
+	}
+	hash := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+	lastModified := dlcData.UpdatedAt.UTC().Truncate(time.Second)
+
+	header := c.Writer.Header()
+	header.Set(HeaderETag, etag)
+	header.Set(HeaderCacheControl, e.cacheControl(dlcData))
+	if !lastModified.IsZero() {
+		header.Set(HeaderLastModified, lastModified.Format(http.TimeFormat))
+	}
+	if isNotModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, gin.MIMEJSON+"; charset=utf-8", body)
+}
+
+// isNotModified returns true if the conditional headers of the request match the response,
+// If-Modified-Since is ignored if If-None-Match is set
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := r.Header.Get(HeaderIfNoneMatch); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	ifModifiedSince, err := http.ParseTime(r.Header.Get(HeaderIfModifiedSince))
+	if err != nil || lastModified.IsZero() {
+		return false
+	}
+	return !lastModified.After(ifModifiedSince)
+}
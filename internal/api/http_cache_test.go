@@ -0,0 +1,95 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func GetCachedEvent(t *testing.T, cache *api.EventCache, route string, header http.Header) *httptest.ResponseRecorder {
+	oracleInstance, err := NewTestOracleService()
+	require.NoError(t, err)
+	ormInstance := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{})
+	db := ormInstance.GetDB()
+	db.Create(TestAsset)
+	_, err = entity.CreateDLCData(db, TestAsset.AssetID, InDbDLCData.PublishedDate, "digits", "kvalue", testEventRvalue)
+	require.NoError(t, err)
+	_, err = entity.UpdateDLCDataAttestation(db, TestAsset.AssetID, InDbDLCData.PublishedDate, "digits", entity.DLCData{Signature: testEventSignature, Value: "9000"})
+	require.NoError(t, err)
+	unsignedDate := InDbDLCData.PublishedDate.Add(TestAssetConfig.Frequency)
+	_, err = entity.CreateDLCData(db, TestAsset.AssetID, unsignedDate, "digits", "unsigned kvalue", "unsigned rvalue")
+	require.NoError(t, err)
+	setup := func(c *gin.Context) {
+		c.Set(api.ContextIDOracle, oracleInstance)
+		c.Set(api.ContextIDOrm, ormInstance)
+		c.Set(api.ContextIDEventCache, cache)
+	}
+	controller := api.NewEventController(api.StaticAssets{TestAsset.AssetID: *TestAssetConfig})
+	resp := httptest.NewRecorder()
+	c, r := SetupEngine(resp, controller, api.ErrorHandler(), setup)
+	c.Request, _ = http.NewRequest(http.MethodGet, route, nil)
+	for key, values := range header {
+		c.Request.Header[key] = values
+	}
+	r.ServeHTTP(resp, c.Request)
+	return resp
+}
+
+func TestEventCache_Signed_ReturnsImmutableResponse(t *testing.T) {
+	cache := &api.EventCache{UnsignedMaxAge: 5 * time.Second}
+	route := "/id/" + api.NewEventID(TestAsset.AssetID, "digits", InDbDLCData.PublishedDate)
+
+	resp := GetCachedEvent(t, cache, route, nil)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, api.CacheControlSigned, resp.Header().Get(api.HeaderCacheControl))
+	assert.NotEmpty(t, resp.Header().Get(api.HeaderETag))
+	assert.NotEmpty(t, resp.Header().Get(api.HeaderLastModified))
+	assert.Contains(t, resp.Body.String(), testEventSignature)
+}
+
+func TestEventCache_Unsigned_ReturnsShortMaxAge(t *testing.T) {
+	cache := &api.EventCache{UnsignedMaxAge: 5 * time.Second}
+	route := "/rvalue/unsigned%20rvalue"
+
+	resp := GetCachedEvent(t, cache, route, nil)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "public, max-age=5", resp.Header().Get(api.HeaderCacheControl))
+}
+
+func TestEventCache_ConditionalRequest_ReturnsNotModified(t *testing.T) {
+	cache := &api.EventCache{DLCData: entity.NewDLCDataCache(10)}
+	route := "/id/" + api.NewEventID(TestAsset.AssetID, "digits", InDbDLCData.PublishedDate)
+	resp := GetCachedEvent(t, cache, route, nil)
+	require.Equal(t, http.StatusOK, resp.Code)
+	etag := resp.Header().Get(api.HeaderETag)
+	lastModified := resp.Header().Get(api.HeaderLastModified)
+
+	tests := []struct {
+		header   http.Header
+		expected int
+	}{
+		{http.Header{api.HeaderIfNoneMatch: {etag}}, http.StatusNotModified},
+		{http.Header{api.HeaderIfNoneMatch: {`"other", W/` + etag}}, http.StatusNotModified},
+		{http.Header{api.HeaderIfNoneMatch: {`"other"`}}, http.StatusOK},
+		{http.Header{api.HeaderIfModifiedSince: {lastModified}}, http.StatusNotModified},
+		{http.Header{api.HeaderIfModifiedSince: {time.Unix(0, 0).UTC().Format(http.TimeFormat)}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp = GetCachedEvent(t, cache, route, tt.header)
+		if assert.Equal(t, tt.expected, resp.Code, tt.header) && tt.expected == http.StatusNotModified {
+			assert.Empty(t, resp.Body.String())
+			assert.Equal(t, etag, resp.Header().Get(api.HeaderETag))
+		}
+	}
+	assert.Equal(t, 1, cache.DLCData.Len())
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// RouteGETMetrics GET route exposing the metrics in the Prometheus text exposition format
+	RouteGETMetrics = "/metrics"
+
+	metricsUnmatchedRoute = "unmatched"
+	metricsResultSuccess  = "success"
+	metricsResultError    = "error"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oracle_http_requests_total",
+		Help: "Number of http requests.",
+	}, []string{"method", "route", "status"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "oracle_http_request_duration_seconds",
+		Help: "Duration of the http requests.",
+	}, []string{"method", "route"})
+	keygens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oracle_keygens_total",
+		Help: "Number of generated one-time signing key pairs.",
+	}, []string{"result"})
+	signatures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oracle_signatures_total",
+		Help: "Number of computed signatures.",
+	}, []string{"result"})
+)
+
+// Metrics middleware counting the requests and observing their duration per route
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+		// the route pattern is used (not the path) to bound the number of series
+		route := c.FullPath()
+		if route == "" {
+			route = metricsUnmatchedRoute
+		}
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(started).Seconds())
+	}
+}
+
+// InstrumentCryptoService returns a crypto service counting the key generations and signatures of the given one
+func InstrumentCryptoService(crypto dlccrypto.CryptoService) dlccrypto.CryptoService {
+	return &instrumentedCryptoService{CryptoService: crypto}
+}
+
+type instrumentedCryptoService struct {
+	dlccrypto.CryptoService
+}
+
+func (s *instrumentedCryptoService) GenerateSchnorrKeyPair() (*dlccrypto.PrivateKey, *dlccrypto.SchnorrPublicKey, error) {
+	kvalue, rvalue, err := s.CryptoService.GenerateSchnorrKeyPair()
+	keygens.WithLabelValues(metricsResult(err)).Inc()
+	return kvalue, rvalue, err
+}
+
+func (s *instrumentedCryptoService) ComputeSchnorrSignature(
+	privateKey *dlccrypto.PrivateKey,
+	oneTimeSigningK *dlccrypto.PrivateKey,
+	message string) (*dlccrypto.Signature, error) {
+	signature, err := s.CryptoService.ComputeSchnorrSignature(privateKey, oneTimeSigningK, message)
+	signatures.WithLabelValues(metricsResult(err)).Inc()
+	return signature, err
+}
+
+func metricsResult(err error) string {
+	if err != nil {
+		return metricsResultError
+	}
+	return metricsResultSuccess
+}
+
+// MetricsController represents the metrics api Controller
+type MetricsController struct {
+	assets       AssetProvider
+	overdueDelay time.Duration
+}
+
+// NewMetricsController creates a new Controller structure,
+// the events not attested overdueDelay after their publication are reported as overdue
+func NewMetricsController(assets AssetProvider, overdueDelay time.Duration) Controller {
+	return &MetricsController{assets: assets, overdueDelay: overdueDelay}
+}
+
+// Routes list and binds all routes to the router group provided
+func (ct *MetricsController) Routes(route *gin.RouterGroup) {
+	route.GET(RouteGETMetrics, ct.GetMetrics)
+}
+
+// GetMetrics handler writes the metrics of the prometheus default registry,
+// the attestation gauges are computed from the database on each request
+func (ct *MetricsController) GetMetrics(c *gin.Context) {
+	assets, err := ct.assets.AssetConfigs()
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	assetIDs := make([]string, 0, len(assets))
+	for assetID := range assets {
+		assetIDs = append(assetIDs, assetID)
+	}
+	sort.Strings(assetIDs)
+
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	now := time.Now().UTC()
+	overdue, err := entity.CountOverdueDLCData(db, now.Add(-ct.overdueDelay))
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	overdueEvents := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oracle_overdue_events",
+		Help: "Number of announced events not attested after the overdue delay.",
+	}, []string{"asset"})
+	lastAttestationAge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oracle_last_attestation_age_seconds",
+		Help: "Age of the last attestation.",
+	}, []string{"asset"})
+	lastAttestations, err := entity.FindLastAttestationDates(db)
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	for _, assetID := range assetIDs {
+		overdueEvents.WithLabelValues(assetID).Set(float64(overdue[assetID]))
+		if last, ok := lastAttestations[assetID]; ok {
+			lastAttestationAge.WithLabelValues(assetID).Set(now.Sub(last).Seconds())
+		}
+	}
+
+	gauges := prometheus.NewRegistry()
+	gauges.MustRegister(overdueEvents, lastAttestationAge)
+	promhttp.HandlerFor(prometheus.Gatherers{prometheus.DefaultGatherer, gauges}, promhttp.HandlerOpts{}).
+		ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,77 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	mock_dlccrypto "p2pderivatives-oracle/test/mock/dlccrypto"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsController_GetMetrics_ExposesRequestsAndAttestationGauges(t *testing.T) {
+	orm := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{})
+	db := orm.GetDB()
+	db.Create(TestAsset)
+	published := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	_, err := entity.CreateDLCData(db, TestAsset.AssetID, published, "digits", "k1", "r1")
+	require.NoError(t, err)
+	_, err = entity.CreateDLCData(db, TestAsset.AssetID, published.Add(-time.Hour), "digits", "k2", "r2")
+	require.NoError(t, err)
+	_, err = entity.UpdateDLCDataAttestation(db, TestAsset.AssetID, published.Add(-time.Hour), "digits",
+		entity.DLCData{Signature: "s", Value: "1"})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(api.Metrics(), api.ErrorHandler(), func(c *gin.Context) { c.Set(api.ContextIDOrm, orm) })
+	assets := api.StaticAssets{TestAsset.AssetID: *TestAssetConfig, "ethusd": *TestAssetConfig}
+	api.NewMetricsController(assets, 5*time.Minute).Routes(engine.Group(""))
+
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/unknown", nil)
+	engine.ServeHTTP(resp, req)
+	resp = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, api.RouteGETMetrics, nil)
+	engine.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, string(expfmt.FmtText), resp.Header().Get("Content-Type"))
+	body := resp.Body.String()
+	assert.Contains(t, body, `oracle_http_requests_total{method="GET",route="unmatched",status="404"}`)
+	assert.Contains(t, body, "oracle_overdue_events{asset=\"btcusd\"} 1\noracle_overdue_events{asset=\"ethusd\"} 0\n")
+	assert.Contains(t, body, `oracle_last_attestation_age_seconds{asset="btcusd"}`)
+	assert.NotContains(t, body, `oracle_last_attestation_age_seconds{asset="ethusd"}`)
+}
+
+func TestInstrumentCryptoService_CountsKeygensAndSignatures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	kvalue, rvalue, _, _, err := SetupMockValues()
+	require.NoError(t, err)
+	mock := mock_dlccrypto.NewMockCryptoService(ctrl)
+	mock.EXPECT().GenerateSchnorrKeyPair().Return(kvalue, rvalue, nil)
+	mock.EXPECT().ComputeSchnorrSignature(kvalue, kvalue, "1").Return(nil, errors.New("signature error"))
+	crypto := api.InstrumentCryptoService(mock)
+
+	_, _, err = crypto.GenerateSchnorrKeyPair()
+	assert.NoError(t, err)
+	_, err = crypto.ComputeSchnorrSignature(kvalue, kvalue, "1")
+	assert.Error(t, err)
+
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, api.RouteGETMetrics, nil)
+	promhttp.Handler().ServeHTTP(resp, req)
+	assert.Contains(t, resp.Body.String(), `oracle_keygens_total{result="success"}`)
+	assert.Contains(t, resp.Body.String(), `oracle_signatures_total{result="error"} 1`)
+}
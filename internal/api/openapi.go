@@ -0,0 +1,622 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	// OpenAPIRoute route serving the OpenAPI document of the api
+	OpenAPIRoute = "/openapi.json"
+	// SwaggerUIRoute route serving the Swagger UI of the api (if enabled)
+	SwaggerUIRoute = "/docs"
+	// URLParamTagAsset Tag used in the OpenAPI document for the asset id of the asset routes
+	URLParamTagAsset = "asset"
+	// OpenAPIVersion version of the OpenAPI specification used by the document
+	OpenAPIVersion = "3.0.3"
+	// OpenAPIInfoVersion version of the api described by the document
+	OpenAPIInfoVersion = "1.0.0"
+)
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>P2PDerivatives Oracle API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@3/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@3/swagger-ui-bundle.js"></script>
+  <script>SwaggerUIBundle({url: "` + OpenAPIRoute + `", dom_id: "#swagger-ui"})</script>
+</body>
+</html>
+`
+
+// OpenAPIDocument represents an OpenAPI 3 document
+type OpenAPIDocument struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       OpenAPIInfo                `json:"info"`
+	Paths      map[string]OpenAPIPathItem `json:"paths"`
+	Components OpenAPIComponents          `json:"components"`
+}
+
+// OpenAPIInfo represents the metadata of an OpenAPI document
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPIPathItem represents the operations of a path indexed by lower case http method
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+// OpenAPIOperation represents an api operation
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Tags        []string                    `json:"tags"`
+	Summary     string                      `json:"summary"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter represents a path, query or header parameter of an operation
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPIRequestBody represents the request body of an operation
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse represents a response of an operation
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Headers     map[string]*OpenAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIHeader represents a response header
+type OpenAPIHeader struct {
+	Description string         `json:"description,omitempty"`
+	Schema      *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPIMediaType represents the content of a request or response body
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPIComponents represents the reusable schemas of an OpenAPI document
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `json:"schemas"`
+}
+
+// OpenAPISchema represents a json schema, named types are referenced from the components
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
+
+// openAPIRoute describes an api route, the route uses the gin syntax and the asset routes use the asset parameter
+type openAPIRoute struct {
+	method      string
+	route       string
+	tag         string
+	summary     string
+	deprecated  bool
+	parameters  []*OpenAPIParameter
+	request     interface{}
+	status      int
+	response    interface{}
+	contentType string
+	errors      []int
+	// v1Only the route is not served by the v2 api
+	v1Only bool
+	// unversioned the route is only served at the root
+	unversioned bool
+}
+
+var assetRoute = AssetBaseRoute + "/:" + URLParamTagAsset
+
+// openAPIRoutes returns the routes served by the api version
+func openAPIRoutes(version APIVersion) []*openAPIRoute {
+	eventType := queryParameter(URLQueryTagEventType, "event type (digits if not set)")
+	return []*openAPIRoute{
+		{
+			method: http.MethodGet, route: OracleBaseRoute + RouteGETOraclePublicKey, tag: "oracle",
+			summary:  "Returns the oracle public key",
+			response: &OraclePublicKeyResponse{},
+		},
+		{
+			method: http.MethodGet, route: AssetBaseRoute, tag: "asset",
+			summary:  "Returns the ids of the available assets",
+			response: []string{},
+		},
+		{
+			method: http.MethodGet, route: assetRoute + RouteGETAssetConfig, tag: "asset",
+			summary:  "Returns the asset configuration",
+			response: &AssetConfigResponse{},
+		},
+		{
+			method: http.MethodGet, route: assetRoute + RouteGETAssetRvalue, tag: "asset",
+			summary:    "Returns the announcement (rvalue) of the event published at the requested time, rounded up to the asset frequency",
+			parameters: []*OpenAPIParameter{eventType},
+			response:   versioned(version, &DLCDataResponse{}, &V2EventResponse{}),
+			errors:     []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, route: assetRoute + RouteGETAssetSignature, tag: "asset",
+			summary: "Returns the attestation (signature and value) of the event published at the requested time",
+			parameters: []*OpenAPIParameter{
+				eventType,
+				queryParameter(URLQueryTagWait, "maximum duration to wait for the publish date (ISO8601 or Go duration, up to one minute)"),
+			},
+			response: versioned(version, &DLCDataResponse{}, &V2EventResponse{}),
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusTooEarly},
+		},
+		{
+			method: http.MethodGet, route: assetRoute + RouteGETAssetEvents, tag: "asset",
+			summary: "Returns a page of the announced events of the asset sorted by publish date",
+			parameters: []*OpenAPIParameter{
+				queryParameter(URLQueryTagFrom, "events published at or after this time"),
+				queryParameter(URLQueryTagTo, "events published at or before this time"),
+				queryParameter(URLQueryTagEventType, "event type"),
+				enumQueryParameter(URLQueryTagStatus, "signature status", EventStatusSigned, EventStatusUnsigned),
+				queryParameter(URLQueryTagCursor, "cursor of the page returned as nextCursor"),
+				{Name: URLQueryTagLimit, In: "query", Description: "maximum number of events (100 if not set, up to 1000)",
+					Schema: &OpenAPISchema{Type: "integer"}},
+			},
+			response: versioned(version, &DLCDataPageResponse{}, &V2EventPageResponse{}),
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPost, route: assetRoute + RoutePOSTAssetRvalues, tag: "asset",
+			summary:  "Returns the announcements of several events in request order",
+			request:  &BatchRequest{},
+			response: versioned(version, []*BatchItemResponse{}, []*V2BatchItemResponse{}),
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPost, route: assetRoute + RoutePOSTAssetSignatures, tag: "asset",
+			summary:  "Returns the attestations of several events in request order",
+			request:  &BatchRequest{},
+			response: versioned(version, []*BatchItemResponse{}, []*V2BatchItemResponse{}),
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, route: AssetBaseRoute + RouteGETEventByRvalue, tag: "event",
+			summary:    "Returns the descriptor of an announced event by rvalue, same as " + EventBaseRoute + RouteGETEventByRvalue,
+			deprecated: true, v1Only: true,
+			response: versioned(version, &EventDescriptorResponse{}, &V2EventResponse{}),
+			errors:   []int{http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, route: EventBaseRoute + RouteGETEventByID, tag: "event",
+			summary:  "Returns the descriptor of an announced event by event id (<asset>:<event type>:<publish date>)",
+			response: versioned(version, &EventDescriptorResponse{}, &V2EventResponse{}),
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, route: EventBaseRoute + RouteGETEventByRvalue, tag: "event",
+			summary:  "Returns the descriptor of an announced event by rvalue",
+			response: versioned(version, &EventDescriptorResponse{}, &V2EventResponse{}),
+			errors:   []int{http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, route: EventBaseRoute + RouteGETEventBySignature, tag: "event",
+			summary:  "Returns the descriptor of an attested event by signature",
+			response: versioned(version, &EventDescriptorResponse{}, &V2EventResponse{}),
+			errors:   []int{http.StatusNotFound},
+		},
+		{
+			method: http.MethodPost, route: WebhookBaseRoute + RouteWebhook, tag: "webhook", v1Only: true,
+			summary: "Registers a webhook subscription",
+			request: &WebhookRequest{}, status: http.StatusCreated,
+			response: &WebhookResponse{},
+			errors:   []int{http.StatusBadRequest},
+		},
+		{
+			method: http.MethodGet, route: WebhookBaseRoute + RouteWebhook, tag: "webhook", v1Only: true,
+			summary:  "Returns the webhook subscriptions",
+			response: []*WebhookResponse{},
+		},
+		{
+			method: http.MethodGet, route: WebhookBaseRoute + RouteWebhookID, tag: "webhook", v1Only: true,
+			summary:  "Returns a webhook subscription",
+			response: &WebhookResponse{},
+			errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodDelete, route: WebhookBaseRoute + RouteWebhookID, tag: "webhook", v1Only: true,
+			summary: "Deletes a webhook subscription",
+			status:  http.StatusNoContent,
+			errors:  []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, route: WebhookBaseRoute + RouteGETWebhookDeliveries, tag: "webhook", v1Only: true,
+			summary:    "Returns the latest deliveries of a webhook subscription",
+			parameters: []*OpenAPIParameter{queryParameter(URLQueryTagStatus, "delivery status")},
+			response:   []*WebhookDeliveryResponse{},
+			errors:     []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, route: StreamBaseRoute + RouteGETStream, tag: "stream", v1Only: true,
+			summary: "Streams the announcement and attestation notifications as server sent events (or websocket json messages on upgrade)",
+			parameters: []*OpenAPIParameter{
+				queryParameter(URLQueryTagAsset, "asset ids, comma separated or repeated (all if not set)"),
+				queryParameter(URLQueryTagEventType, "event types, comma separated or repeated (all if not set)"),
+				queryParameter(URLQueryTagCursor, "sequence after which the notifications are sent (latest if not set)"),
+				{Name: HeaderLastEventID, In: "header", Description: "same as cursor, sent by server sent event clients on reconnection",
+					Schema: &OpenAPISchema{Type: "string"}},
+			},
+			response:    &EventNotificationResponse{},
+			contentType: "text/event-stream",
+			errors:      []int{http.StatusBadRequest},
+		},
+		{
+			method: http.MethodGet, route: OpenAPIRoute, tag: "oracle", unversioned: true,
+			summary:  "Returns the OpenAPI document of the api",
+			response: &OpenAPIDocument{},
+		},
+		{
+			method: http.MethodGet, route: RouteGETHealthz, tag: "health", unversioned: true,
+			summary:  "Liveness probe, returns ok while the server is serving requests",
+			response: &HealthResponse{},
+		},
+		{
+			method: http.MethodGet, route: RouteGETReadyz, tag: "health", unversioned: true,
+			summary:  "Readiness probe checking the database, datafeed, oracle key and clock (503 with the same body if a check fails)",
+			response: &HealthResponse{},
+		},
+		{
+			method: http.MethodGet, route: RouteGETMetrics, tag: "oracle", unversioned: true,
+			summary:     "Returns the metrics in the Prometheus text exposition format",
+			response:    "",
+			contentType: string(expfmt.FmtText),
+		},
+		{
+			method: http.MethodGet, route: AdminBaseRoute + RouteAdminAsset, tag: "admin", unversioned: true,
+			summary:  "Returns the stored assets, including the assets without schedule",
+			response: []*AssetResponse{},
+			errors:   []int{http.StatusUnauthorized, http.StatusForbidden},
+		},
+		{
+			method: http.MethodPost, route: AdminBaseRoute + RouteAdminAsset, tag: "admin", unversioned: true,
+			summary: "Creates an asset, served without restart",
+			request: &AssetRequest{}, status: http.StatusCreated,
+			response: &AssetResponse{},
+			errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict},
+		},
+		{
+			method: http.MethodGet, route: AdminBaseRoute + RouteAdminAssetID, tag: "admin", unversioned: true,
+			summary:  "Returns a stored asset",
+			response: &AssetResponse{},
+			errors:   []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		},
+		{
+			method: http.MethodPut, route: AdminBaseRoute + RouteAdminAssetID, tag: "admin", unversioned: true,
+			summary:  "Replaces the configuration of an asset, the announced events are kept",
+			request:  &AssetRequest{},
+			response: &AssetResponse{},
+			errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		},
+		{
+			method: http.MethodDelete, route: AdminBaseRoute + RouteAdminAssetID, tag: "admin", unversioned: true,
+			summary: "Deletes an asset, its events are kept but not served anymore",
+			status:  http.StatusNoContent,
+			errors:  []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		},
+		{
+			method: http.MethodGet, route: AdminBaseRoute + RouteGETAdminAudit, tag: "admin", unversioned: true,
+			summary: "Returns the most recent audit records of the privileged requests",
+			parameters: []*OpenAPIParameter{
+				queryParameter(URLQueryTagPrincipal, "principal identity"),
+				{Name: URLQueryTagLimit, In: "query", Description: "maximum number of records (100 if not set, up to 1000)",
+					Schema: &OpenAPISchema{Type: "integer"}},
+			},
+			response: []*AuditRecordResponse{},
+			errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+	}
+}
+
+// versioned returns the v1 or v2 value depending on the api version
+func versioned(version APIVersion, v1 interface{}, v2 interface{}) interface{} {
+	if version == APIVersion2 {
+		return v2
+	}
+	return v1
+}
+
+func queryParameter(name string, description string) *OpenAPIParameter {
+	return &OpenAPIParameter{Name: name, In: "query", Description: description, Schema: &OpenAPISchema{Type: "string"}}
+}
+
+func enumQueryParameter(name string, description string, values ...string) *OpenAPIParameter {
+	parameter := queryParameter(name, description)
+	parameter.Schema.Enum = values
+	return parameter
+}
+
+var ginRouteParameter = regexp.MustCompile(`[:*]([^/]+)`)
+
+// OpenAPIPath returns the OpenAPI path of a gin route, {param} instead of :param
+func OpenAPIPath(route string) string {
+	return ginRouteParameter.ReplaceAllString(route, "{$1}")
+}
+
+// NewOpenAPIDocument returns the OpenAPI document of the api routes and response types
+func NewOpenAPIDocument(assetIDs []string) *OpenAPIDocument {
+	sortedAssetIDs := append([]string{}, assetIDs...)
+	sort.Strings(sortedAssetIDs)
+	document := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info: OpenAPIInfo{
+			Title:       "P2PDerivatives Oracle",
+			Description: "Announces and attests asset values for DLCs. Times are RFC3339 (normalized to UTC), unix seconds or milliseconds.",
+			Version:     OpenAPIInfoVersion,
+		},
+		Paths:      map[string]OpenAPIPathItem{},
+		Components: OpenAPIComponents{Schemas: map[string]*OpenAPISchema{}},
+	}
+	generator := &openAPISchemaGenerator{schemas: document.Components.Schemas}
+	errorSchema := generator.schemaOf(reflect.TypeOf(ErrorResponse{}))
+
+	// the unversioned routes serve the v1 api
+	trees := []struct {
+		prefix  string
+		version APIVersion
+	}{
+		{"", APIVersion1},
+		{APIVersion1.BaseRoute(), APIVersion1},
+		{APIVersion2.BaseRoute(), APIVersion2},
+	}
+	for _, tree := range trees {
+		for _, route := range openAPIRoutes(tree.version) {
+			if (route.unversioned && tree.prefix != "") || (route.v1Only && tree.version != APIVersion1) {
+				continue
+			}
+			operation := newOpenAPIOperation(generator, errorSchema, route, sortedAssetIDs)
+			if tree.prefix != "" {
+				operation.OperationID = string(tree.version) + strings.ToUpper(operation.OperationID[:1]) + operation.OperationID[1:]
+				operation.Tags = []string{string(tree.version) + " " + route.tag}
+			}
+			path := OpenAPIPath(tree.prefix + route.route)
+			if document.Paths[path] == nil {
+				document.Paths[path] = OpenAPIPathItem{}
+			}
+			document.Paths[path][strings.ToLower(route.method)] = operation
+		}
+	}
+	return document
+}
+
+// newOpenAPIOperation returns the OpenAPI operation of a route, the path parameters are extracted from the route
+func newOpenAPIOperation(
+	generator *openAPISchemaGenerator,
+	errorSchema *OpenAPISchema,
+	route *openAPIRoute,
+	assetIDs []string) *OpenAPIOperation {
+	operation := &OpenAPIOperation{
+		OperationID: openAPIOperationID(route),
+		Tags:        []string{route.tag},
+		Summary:     route.summary,
+		Deprecated:  route.deprecated,
+		Responses:   map[string]*OpenAPIResponse{},
+	}
+	for _, match := range ginRouteParameter.FindAllStringSubmatch(route.route, -1) {
+		parameter := &OpenAPIParameter{Name: match[1], In: "path", Required: true, Schema: &OpenAPISchema{Type: "string"}}
+		switch match[1] {
+		case URLParamTagAsset:
+			parameter.Schema.Enum = assetIDs
+		case URLParamTagTime:
+			parameter.Description = "publish time"
+		}
+		operation.Parameters = append(operation.Parameters, parameter)
+	}
+	operation.Parameters = append(operation.Parameters, route.parameters...)
+
+	if route.request != nil {
+		operation.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content:  jsonContent(generator.schemaOf(reflect.TypeOf(route.request))),
+		}
+	}
+
+	status := route.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := &OpenAPIResponse{Description: http.StatusText(status)}
+	if route.response != nil {
+		contentType := route.contentType
+		if contentType == "" {
+			contentType = gin.MIMEJSON
+		}
+		response.Content = map[string]*OpenAPIMediaType{
+			contentType: {Schema: generator.schemaOf(reflect.TypeOf(route.response))},
+		}
+	}
+	operation.Responses[strconv.Itoa(status)] = response
+	// every route is rate limited
+	for _, errorStatus := range append(route.errors, http.StatusTooManyRequests, http.StatusInternalServerError) {
+		errorResponse := &OpenAPIResponse{Description: http.StatusText(errorStatus), Content: jsonContent(errorSchema)}
+		if errorStatus == http.StatusTooEarly || errorStatus == http.StatusTooManyRequests {
+			errorResponse.Headers = map[string]*OpenAPIHeader{
+				"Retry-After": {Description: "number of seconds after which the request can be retried", Schema: &OpenAPISchema{Type: "integer"}},
+			}
+		}
+		operation.Responses[strconv.Itoa(errorStatus)] = errorResponse
+	}
+	return operation
+}
+
+// openAPIOperationID returns a unique operation id from the route method and static segments
+func openAPIOperationID(route *openAPIRoute) string {
+	id := strings.ToLower(route.method)
+	for _, segment := range strings.Split(route.route, "/") {
+		if segment == "" {
+			continue
+		}
+		if segment[0] == ':' || segment[0] == '*' {
+			segment = "By" + strings.ToUpper(segment[1:2]) + segment[2:]
+		}
+		segment = strings.TrimSuffix(segment, ".json")
+		id += strings.ToUpper(segment[:1]) + segment[1:]
+	}
+	return id
+}
+
+func jsonContent(schema *OpenAPISchema) map[string]*OpenAPIMediaType {
+	return map[string]*OpenAPIMediaType{gin.MIMEJSON: {Schema: schema}}
+}
+
+// openAPISchemaGenerator generates the json schemas of go types, the named structures are added to schemas
+type openAPISchemaGenerator struct {
+	schemas map[string]*OpenAPISchema
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	errorType = reflect.TypeOf(Error{})
+)
+
+func (g *openAPISchemaGenerator) schemaOf(t reflect.Type) *OpenAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	case t == errorType:
+		// marshalled as an error response
+		return g.schemaOf(reflect.TypeOf(ErrorResponse{}))
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &OpenAPISchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &OpenAPISchema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		ref := &OpenAPISchema{Ref: "#/components/schemas/" + t.Name()}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			schema := &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+			// registered before the fields for recursive types
+			g.schemas[t.Name()] = schema
+			g.addProperties(schema, t)
+		}
+		return ref
+	default:
+		return &OpenAPISchema{}
+	}
+}
+
+// addProperties adds the json fields of a structure to a schema, the embedded structures fields are inlined
+func (g *openAPISchemaGenerator) addProperties(schema *OpenAPISchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if index := strings.Index(tag, ","); index >= 0 {
+			name, options = tag[:index], tag[index+1:]
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addProperties(schema, embedded)
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = g.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// NewOpenAPIController returns a controller serving the OpenAPI document and optionally the Swagger UI
+// the asset parameter lists the assets available when the document is requested
+func NewOpenAPIController(assets AssetProvider, swaggerUI bool) *OpenAPIController {
+	return &OpenAPIController{
+		assets:    assets,
+		swaggerUI: swaggerUI,
+	}
+}
+
+// OpenAPIController represents the OpenAPI document api Controller
+type OpenAPIController struct {
+	assets    AssetProvider
+	swaggerUI bool
+}
+
+// Routes list and binds all routes to the router group provided
+func (ct *OpenAPIController) Routes(route *gin.RouterGroup) {
+	route.GET(OpenAPIRoute, ct.GetOpenAPIDocument)
+	if ct.swaggerUI {
+		route.GET(SwaggerUIRoute, ct.GetSwaggerUI)
+	}
+}
+
+// GetOpenAPIDocument handler returns the OpenAPI document of the api
+func (ct *OpenAPIController) GetOpenAPIDocument(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get OpenAPI Document")
+	assetIDs, err := sortedAssetIDs(ct.assets)
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	c.JSON(http.StatusOK, NewOpenAPIDocument(assetIDs))
+}
+
+// GetSwaggerUI handler returns the Swagger UI page browsing the OpenAPI document
+func (ct *OpenAPIController) GetSwaggerUI(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Swagger UI")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
@@ -0,0 +1,150 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/test"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func SetupOpenAPIEngine() (*api.Config, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	apiConfig := &api.Config{
+		AssetConfigs: map[string]api.AssetConfig{"btcusd": *TestAssetConfig, "ethusd": *TestAssetConfig},
+	}
+	engine := gin.New()
+	api.NewOracleAPI(apiConfig, test.NewLogger(), nil, nil, api.StaticAssets(apiConfig.AssetConfigs), nil, nil).Routes(engine.Group(""))
+	return apiConfig, engine
+}
+
+// documentedRoutes returns the "<METHOD> <path>" of all the document operations
+func documentedRoutes(document *api.OpenAPIDocument) []string {
+	routes := []string{}
+	for path, item := range document.Paths {
+		for method := range item {
+			routes = append(routes, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// registeredRoutes returns the "<METHOD> <path>" of all the engine routes, the dispatched asset routes are expanded
+func registeredRoutes(engine *gin.Engine) []string {
+	routes := []string{}
+	registered := map[string]bool{}
+	add := func(method string, path string) {
+		route := method + " " + api.OpenAPIPath(path)
+		if !registered[route] {
+			registered[route] = true
+			routes = append(routes, route)
+		}
+	}
+	for _, info := range engine.Routes() {
+		if !strings.HasSuffix(info.Path, api.RouteAssetAction) {
+			add(info.Method, info.Path)
+			continue
+		}
+		assetBase := strings.TrimSuffix(info.Path, api.RouteAssetAction)
+		for _, action := range api.AssetRoutes() {
+			if action.Method == info.Method {
+				add(info.Method, assetBase+"/:"+api.URLParamTagAsset+action.Path)
+			}
+		}
+		// the v1 asset router also serves the event lookup by rvalue
+		if info.Method == http.MethodGet && !strings.HasPrefix(info.Path, api.APIVersion2.BaseRoute()) {
+			add(info.Method, assetBase+api.RouteGETEventByRvalue)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+func TestOpenAPIDocument_MatchesRegisteredRoutes(t *testing.T) {
+	_, engine := SetupOpenAPIEngine()
+	document := api.NewOpenAPIDocument([]string{"btcusd"})
+
+	assert.Equal(t, registeredRoutes(engine), documentedRoutes(document),
+		"the routes and the OpenAPI document have drifted, update openAPIRoutes")
+}
+
+func TestOpenAPIDocument_SchemaReferences_AreDefined(t *testing.T) {
+	document := api.NewOpenAPIDocument([]string{"btcusd"})
+	raw, err := json.Marshal(document)
+	require.NoError(t, err)
+
+	for _, part := range strings.Split(string(raw), `"$ref":"#/components/schemas/`)[1:] {
+		name := part[:strings.Index(part, `"`)]
+		assert.Contains(t, document.Components.Schemas, name)
+	}
+	dlcData := document.Components.Schemas["DLCDataResponse"]
+	require.NotNil(t, dlcData)
+	assert.Contains(t, dlcData.Properties, "eventType")
+	assert.Contains(t, dlcData.Required, "rvalue")
+	assert.NotContains(t, dlcData.Required, "signature")
+	// embedded response fields are inlined
+	assert.Contains(t, document.Components.Schemas["EventDescriptorResponse"].Properties, "rvalue")
+	// api errors are marshalled as error responses
+	assert.Contains(t, document.Components.Schemas["BatchItemResponse"].Properties["error"].Ref, "ErrorResponse")
+}
+
+func TestOpenAPIDocument_AssetParameter_ListsAssetIDs(t *testing.T) {
+	document := api.NewOpenAPIDocument([]string{"ethusd", "btcusd"})
+
+	operation := document.Paths["/asset/{asset}/rvalue/{time}"]["get"]
+	require.NotNil(t, operation)
+	require.NotEmpty(t, operation.Parameters)
+	assert.Equal(t, "asset", operation.Parameters[0].Name)
+	assert.Equal(t, []string{"btcusd", "ethusd"}, operation.Parameters[0].Schema.Enum)
+	assert.Contains(t, document.Paths["/asset/{asset}/signature/{time}"]["get"].Responses["425"].Headers, "Retry-After")
+}
+
+func TestOpenAPIDocument_Versions_UseVersionResponses(t *testing.T) {
+	document := api.NewOpenAPIDocument([]string{"btcusd"})
+
+	schemaRef := func(path string) string {
+		operation := document.Paths[path]["get"]
+		require.NotNil(t, operation, path)
+		return operation.Responses["200"].Content[gin.MIMEJSON].Schema.Ref
+	}
+	assert.Equal(t, "#/components/schemas/DLCDataResponse", schemaRef("/asset/{asset}/rvalue/{time}"))
+	assert.Equal(t, "#/components/schemas/DLCDataResponse", schemaRef("/v1/asset/{asset}/rvalue/{time}"))
+	assert.Equal(t, "#/components/schemas/V2EventResponse", schemaRef("/v2/asset/{asset}/rvalue/{time}"))
+	assert.Equal(t, "#/components/schemas/V2EventResponse", schemaRef("/v2/event/id/{id}"))
+	assert.Equal(t, "v2GetEventIdById", document.Paths["/v2/event/id/{id}"]["get"].OperationID)
+	assert.NotContains(t, document.Paths, "/v2/webhook")
+	assert.NotContains(t, document.Paths, "/v1/openapi.json")
+}
+
+func TestOpenAPIController_ServesDocumentAndOptionalSwaggerUI(t *testing.T) {
+	for _, swaggerUI := range []bool{false, true} {
+		resp := httptest.NewRecorder()
+		c, r := SetupEngine(resp, api.NewOpenAPIController(api.StaticAssets{"btcusd": *TestAssetConfig}, swaggerUI))
+		c.Request, _ = http.NewRequest(http.MethodGet, api.OpenAPIRoute, nil)
+		r.ServeHTTP(resp, c.Request)
+		if assert.Equal(t, http.StatusOK, resp.Code) {
+			document := &api.OpenAPIDocument{}
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), document))
+			assert.Equal(t, api.OpenAPIVersion, document.OpenAPI)
+			assert.Contains(t, document.Paths, api.OpenAPIRoute)
+		}
+
+		resp = httptest.NewRecorder()
+		c.Request, _ = http.NewRequest(http.MethodGet, api.SwaggerUIRoute, nil)
+		r.ServeHTTP(resp, c.Request)
+		if swaggerUI {
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Contains(t, resp.Body.String(), api.OpenAPIRoute)
+		} else {
+			assert.Equal(t, http.StatusNotFound, resp.Code)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"p2pderivatives-oracle/internal/database/entity"
+	"sync"
+	"time"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// bucketCleanupInterval interval at which the idle token buckets are removed
+const bucketCleanupInterval = time.Minute
+
+// Limit represents a token bucket rate limit, unlimited if PerMinute is 0
+type Limit struct {
+	// PerMinute number of requests per minute (bucket refill rate)
+	PerMinute int
+	// Burst maximum number of requests made at once (bucket size, at least 1)
+	Burst int
+}
+
+func (l Limit) burst() float64 {
+	return math.Max(float64(l.Burst), 1)
+}
+
+func (l Limit) perSecond() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// tokenBucket represents the remaining requests of a client
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket and takes a token, if empty returns false with the duration until a token is available
+func (b *tokenBucket) take(limit Limit, now time.Time) (bool, time.Duration) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(limit.burst(), b.tokens+elapsed*limit.perSecond())
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / limit.perSecond() * float64(time.Second))
+}
+
+// RateLimiter limits the requests with a token bucket per client ip for the anonymous requests
+// and per principal for the authenticated requests
+type RateLimiter struct {
+	mutex     sync.Mutex
+	anonymous Limit
+	principal Limit
+	// AnonymousDailyQuota maximum number of announcements per UTC day and client ip of the anonymous requests, unlimited if 0
+	AnonymousDailyQuota int
+	buckets             map[string]*tokenBucket
+	lastCleanup         time.Time
+}
+
+// NewRateLimiter returns a rate limiter using the anonymous limit per client ip
+// and the principal limit for the principals without their own limit
+func NewRateLimiter(anonymous Limit, principal Limit) *RateLimiter {
+	return &RateLimiter{
+		anonymous: anonymous,
+		principal: principal,
+		buckets:   map[string]*tokenBucket{},
+	}
+}
+
+// Allow takes a token from the bucket of the request, if empty returns false with the duration until it can be retried
+func (l *RateLimiter) Allow(principal *Principal, clientIP string, now time.Time) (bool, time.Duration) {
+	bucketID, limit := anonymousKey(clientIP), l.anonymous
+	if principal.Method != AuthMethodAnonymous {
+		bucketID, limit = principal.Method+":"+principal.ID, l.principal
+		if principal.Limit.PerMinute > 0 {
+			limit = principal.Limit
+		}
+	}
+	if limit.PerMinute <= 0 {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if now.Sub(l.lastCleanup) >= bucketCleanupInterval {
+		l.cleanup(now)
+	}
+	bucket, ok := l.buckets[bucketID]
+	if !ok {
+		bucket = &tokenBucket{tokens: limit.burst(), last: now}
+		l.buckets[bucketID] = bucket
+	}
+	return bucket.take(limit, now)
+}
+
+// cleanup removes the buckets idle for more than the cleanup interval (refilled with the default limits)
+func (l *RateLimiter) cleanup(now time.Time) {
+	for id, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= bucketCleanupInterval {
+			delete(l.buckets, id)
+		}
+	}
+	l.lastCleanup = now
+}
+
+// NewConfigRateLimiter returns the rate limiter of the api configuration
+func NewConfigRateLimiter(config *Config) *RateLimiter {
+	limiter := NewRateLimiter(
+		Limit{PerMinute: config.RateLimitAnonymousPerMinute, Burst: config.RateLimitAnonymousBurst},
+		Limit{PerMinute: config.RateLimitPrincipalPerMinute, Burst: config.RateLimitPrincipalBurst})
+	limiter.AnonymousDailyQuota = config.RateLimitAnonymousDailyQuota
+	return limiter
+}
+
+// anonymousKey returns the key of the rate limit and usage of the anonymous requests of a client ip
+func anonymousKey(clientIP string) string {
+	return "ip:" + clientIP
+}
+
+// ClientIP returns the ip of the peer of a request, the forwarded headers are ignored as they can be set by any client
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Usage counts the announcements (nonce generations) and attestations made by a request,
+// the announcements are limited by the remaining daily quota of the principal (or client ip if anonymous)
+type Usage struct {
+	Announcements int
+	Attestations  int
+	db            *gorm.DB
+	// quota announcements allowed per day, unlimited if 0
+	quota int
+	// reserved announcements counted in the stored usage by CanAnnounce
+	reserved  int
+	resetAt   time.Time
+	principal string
+	day       time.Time
+}
+
+// NewUsage returns the usage of a request of the principal, the anonymous requests are metered per client ip
+// if the limiter has an anonymous quota (otherwise nil is returned, a nil usage is not metered)
+func (l *RateLimiter) NewUsage(db *gorm.DB, principal *Principal, clientIP string, now time.Time) *Usage {
+	day := entity.UsageDay(now)
+	usage := &Usage{db: db, resetAt: day.AddDate(0, 0, 1), principal: principal.ID, quota: principal.DailyQuota, day: day}
+	if principal.Method == AuthMethodAnonymous {
+		if l == nil || l.AnonymousDailyQuota <= 0 {
+			return nil
+		}
+		usage.principal, usage.quota = anonymousKey(clientIP), l.AnonymousDailyQuota
+	}
+	return usage
+}
+
+// Record adds the request and its announcements and attestations to the daily usage of the principal,
+// releasing the announcements reserved but not made (a nil usage is not metered)
+func (u *Usage) Record() error {
+	if u == nil {
+		return nil
+	}
+	return entity.AddAPIUsage(u.db, &entity.APIUsage{
+		Principal:     u.principal,
+		Day:           u.day,
+		Requests:      1,
+		Announcements: u.Announcements - u.reserved,
+		Attestations:  u.Attestations,
+	})
+}
+
+// CanAnnounce reserves an announcement in the stored usage if the quota is limited,
+// returns an error if the announcement quota is exceeded (a nil usage is not metered)
+func (u *Usage) CanAnnounce() error {
+	if u == nil || u.quota <= 0 {
+		return nil
+	}
+	reserved, err := entity.ReserveAPIUsageAnnouncement(u.db, u.principal, u.day, u.quota)
+	if err != nil {
+		return NewUnknownDBError(err)
+	}
+	if !reserved {
+		cause := errors.New("daily announcement quota exceeded")
+		return NewQuotaExceededError(cause, time.Until(u.resetAt))
+	}
+	u.reserved++
+	return nil
+}
+
+// Announce counts an announcement (a nil usage is not metered)
+func (u *Usage) Announce() {
+	if u != nil {
+		u.Announcements++
+	}
+}
+
+// Attest counts an attestation (a nil usage is not metered)
+func (u *Usage) Attest() {
+	if u != nil {
+		u.Attestations++
+	}
+}
+
+// usageOf returns the usage of the request, nil if the request is not metered
+func usageOf(c *gin.Context) *Usage {
+	usage, _ := c.Value(ContextIDUsage).(*Usage)
+	return usage
+}
+
+// RateLimit returns a middleware rejecting the requests over the rate limit of their client,
+// metering the usage of the principals (and of the anonymous client ips) and enforcing their daily announcement quota
+func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := principalOf(c)
+		clientIP := ClientIP(c.Request)
+		now := time.Now().UTC()
+		if limiter != nil {
+			if ok, retryAfter := limiter.Allow(principal, clientIP, now); !ok {
+				c.Error(NewTooManyRequestsError(errors.New("rate limit exceeded"), retryAfter))
+				c.Abort()
+				return
+			}
+		}
+		ormInstance, ok := c.Value(ContextIDOrm).(*orm.ORM)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		usage := limiter.NewUsage(ormInstance.GetDB(), principal, clientIP, now)
+		if usage == nil {
+			c.Next()
+			return
+		}
+		c.Set(ContextIDUsage, usage)
+		c.Next()
+
+		if err := usage.Record(); err != nil {
+			ginlogrus.GetCtxLogger(c).Errorf("Could not record the usage of %s: %v", usage.principal, err)
+		}
+	}
+}
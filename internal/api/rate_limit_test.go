@@ -0,0 +1,203 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	mock_dlccrypto "p2pderivatives-oracle/test/mock/dlccrypto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Allow_LimitsPerClientIPAndPrincipal(t *testing.T) {
+	now := time.Date(2020, time.May, 12, 8, 0, 0, 0, time.UTC)
+	limiter := api.NewRateLimiter(api.Limit{PerMinute: 60, Burst: 2}, api.Limit{PerMinute: 600, Burst: 1})
+	anonymous := &api.Principal{ID: "anonymous", Method: api.AuthMethodAnonymous}
+	client := &api.Principal{ID: "client", Method: api.AuthMethodAPIKey, Role: api.RoleReader}
+	limited := &api.Principal{ID: "limited", Method: api.AuthMethodAPIKey, Limit: api.Limit{PerMinute: 1, Burst: 1}}
+
+	for i := 0; i < 2; i++ {
+		ok, _ := limiter.Allow(anonymous, "192.0.2.1", now)
+		assert.True(t, ok)
+	}
+	ok, retryAfter := limiter.Allow(anonymous, "192.0.2.1", now)
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, retryAfter)
+	ok, _ = limiter.Allow(anonymous, "192.0.2.2", now)
+	assert.True(t, ok, "other client ip")
+	ok, _ = limiter.Allow(anonymous, "192.0.2.1", now.Add(time.Second))
+	assert.True(t, ok, "refilled bucket")
+
+	ok, _ = limiter.Allow(client, "192.0.2.1", now)
+	assert.True(t, ok, "principal bucket")
+	ok, retryAfter = limiter.Allow(client, "192.0.2.1", now)
+	assert.False(t, ok)
+	assert.Equal(t, 100*time.Millisecond, retryAfter)
+
+	ok, _ = limiter.Allow(limited, "192.0.2.1", now)
+	assert.True(t, ok)
+	ok, retryAfter = limiter.Allow(limited, "192.0.2.1", now.Add(30*time.Second))
+	assert.False(t, ok, "own limit of the principal")
+	assert.Equal(t, 30*time.Second, retryAfter)
+}
+
+func TestRateLimiter_Allow_WithoutLimit_AllowsAll(t *testing.T) {
+	limiter := api.NewRateLimiter(api.Limit{}, api.Limit{})
+	anonymous := &api.Principal{ID: "anonymous", Method: api.AuthMethodAnonymous}
+	for i := 0; i < 100; i++ {
+		ok, _ := limiter.Allow(anonymous, "192.0.2.1", time.Now())
+		assert.True(t, ok)
+	}
+}
+
+func TestRateLimit_OverLimit_ReturnsTooManyRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	authenticator, err := api.NewAuthenticator(&api.Config{})
+	require.NoError(t, err)
+	engine.Use(api.ErrorHandler(), api.Authenticate(authenticator),
+		api.RateLimit(api.NewRateLimiter(api.Limit{PerMinute: 1, Burst: 1}, api.Limit{})))
+	engine.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	codes := []int{}
+	for i := 0; i < 2; i++ {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		// the forwarded client ip can be set by the client, the limit applies to the peer address
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		engine.ServeHTTP(resp, req)
+		codes = append(codes, resp.Code)
+		if resp.Code == http.StatusTooManyRequests {
+			assert.Equal(t, "60", resp.Header().Get("Retry-After"))
+			actual := &api.ErrorResponse{}
+			if assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), actual)) {
+				assert.Equal(t, api.TooManyRequestsErrorCode, actual.ErrorCode)
+			}
+		}
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestRateLimit_IssuedKeyWithQuota_MetersAndLimitsAnnouncements(t *testing.T) {
+	// setup an issued key allowed to announce a single event per day
+	key, issued, err := api.NewIssuedAPIKey("client", api.RoleReader, api.Limit{}, 1)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, "p2pdo_"))
+
+	ctrl := gomock.NewController(t)
+	kvalue, rvalue, _, _, err := SetupMockValues()
+	require.NoError(t, err)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Return(kvalue, rvalue, nil).Times(1)
+	oracleService, err := NewTestOracleService()
+	require.NoError(t, err)
+
+	orm := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{}, &entity.APIKey{}, &entity.APIUsage{})
+	orm.GetDB().Create(TestAsset)
+	require.NoError(t, entity.CreateAPIKey(orm.GetDB(), issued))
+
+	authenticator, err := api.NewAuthenticator(&api.Config{})
+	require.NoError(t, err)
+	gin.SetMode(gin.TestMode)
+	meteredEngine := gin.New()
+	meteredEngine.Use(api.ErrorHandler(), func(c *gin.Context) {
+		c.Set(api.ContextIDOracle, oracleService)
+		c.Set(api.ContextIDCryptoService, crypto)
+		c.Set(api.ContextIDOrm, orm)
+	}, api.Authenticate(authenticator), api.RateLimit(nil))
+	api.NewAssetController(TestAsset.AssetID, *TestAssetConfig).Routes(meteredEngine.Group(""))
+
+	// the announced event can still be requested once the quota is exceeded
+	announced := InDbDLCData.PublishedDate.Add(TestAssetConfig.Frequency)
+	dates := []time.Time{announced, announced.Add(TestAssetConfig.Frequency), announced}
+	expected := []int{http.StatusOK, http.StatusTooManyRequests, http.StatusOK}
+	for i, date := range dates {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, GetRouteWithTimeParam(api.RouteGETAssetRvalue, date), nil)
+		req.Header.Set(api.HeaderAPIKey, key)
+		meteredEngine.ServeHTTP(resp, req)
+		assert.Equal(t, expected[i], resp.Code, date.String())
+		if resp.Code == http.StatusTooManyRequests {
+			actual := &api.ErrorResponse{}
+			if assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), actual)) {
+				assert.Equal(t, api.QuotaExceededErrorCode, actual.ErrorCode)
+				assert.NotZero(t, actual.RetryAfter)
+			}
+		}
+	}
+
+	usage, err := entity.FindAPIUsage(orm.GetDB(), "client", entity.UsageDay(time.Now()))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, usage.Requests)
+		assert.Equal(t, 1, usage.Announcements)
+		assert.Equal(t, 0, usage.Attestations)
+	}
+
+	// revoked keys are rejected
+	require.NoError(t, entity.RevokeAPIKey(orm.GetDB(), "client", time.Now()))
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, GetRouteWithTimeParam(api.RouteGETAssetRvalue, announced), nil)
+	req.Header.Set(api.HeaderAPIKey, key)
+	meteredEngine.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestRateLimit_AnonymousQuota_LimitsAnnouncementsPerClientIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	ExpectRandomKeyPairs(crypto, 2)
+	oracleService, err := NewTestOracleService()
+	require.NoError(t, err)
+	orm := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{}, &entity.APIKey{}, &entity.APIUsage{})
+	orm.GetDB().Create(TestAsset)
+
+	authenticator, err := api.NewAuthenticator(&api.Config{})
+	require.NoError(t, err)
+	limiter := api.NewRateLimiter(api.Limit{}, api.Limit{})
+	limiter.AnonymousDailyQuota = 1
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(api.ErrorHandler(), func(c *gin.Context) {
+		c.Set(api.ContextIDOracle, oracleService)
+		c.Set(api.ContextIDCryptoService, crypto)
+		c.Set(api.ContextIDOrm, orm)
+	}, api.Authenticate(authenticator), api.RateLimit(limiter))
+	api.NewAssetController(TestAsset.AssetID, *TestAssetConfig).Routes(engine.Group(""))
+
+	announced := InDbDLCData.PublishedDate.Add(TestAssetConfig.Frequency)
+	requests := []struct {
+		remoteAddr string
+		date       time.Time
+		expected   int
+	}{
+		{"192.0.2.1:1234", announced, http.StatusOK},
+		{"192.0.2.1:1235", announced.Add(TestAssetConfig.Frequency), http.StatusTooManyRequests},
+		{"192.0.2.1:1236", announced, http.StatusOK},
+		{"192.0.2.2:1234", announced.Add(TestAssetConfig.Frequency), http.StatusOK},
+	}
+	for i, r := range requests {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, GetRouteWithTimeParam(api.RouteGETAssetRvalue, r.date), nil)
+		req.RemoteAddr = r.remoteAddr
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		engine.ServeHTTP(resp, req)
+		assert.Equal(t, r.expected, resp.Code, r.remoteAddr)
+	}
+
+	usage, err := entity.FindAPIUsage(orm.GetDB(), "ip:192.0.2.1", entity.UsageDay(time.Now()))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, usage.Requests)
+		assert.Equal(t, 1, usage.Announcements)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"p2pderivatives-oracle/internal/database/entity"
 	"p2pderivatives-oracle/internal/dlccrypto"
 	"time"
@@ -18,6 +19,7 @@ func NewDLCDataResponse(
 		Rvalue:          dlcData.Rvalue,
 		Signature:       dlcData.Signature,
 		Value:           dlcData.Value,
+		Settlement:      dlcData.SettlementMethod,
 	}
 }
 
@@ -30,6 +32,91 @@ type DLCDataResponse struct {
 	Rvalue          string    `json:"rvalue"`
 	Signature       string    `json:"signature,omitempty"`
 	Value           string    `json:"value,omitempty"`
+	Settlement      string    `json:"settlement,omitempty"`
+}
+
+// NewEventDescriptorResponse returns the full descriptor of an event from its response and asset configuration
+func NewEventDescriptorResponse(event *DLCDataResponse, dlcData *entity.DLCData, config AssetConfig) *EventDescriptorResponse {
+	status := EventStatusUnsigned
+	if dlcData.IsSigned() {
+		status = EventStatusSigned
+	}
+	return &EventDescriptorResponse{
+		EventID:         NewEventID(dlcData.AssetID, dlcData.EventType, dlcData.PublishedDate),
+		DLCDataResponse: event,
+		Status:          status,
+		Currency:        config.Currency,
+		HasDecimals:     config.HasDecimals,
+		IndexVersion:    dlcData.IndexVersion,
+	}
+}
+
+// EventDescriptorResponse represents the full descriptor of an event, the DLC data with its id, status
+// and how the attested value is expressed
+type EventDescriptorResponse struct {
+	EventID string `json:"eventId"`
+	*DLCDataResponse
+	Status       string `json:"status"`
+	Currency     string `json:"currency,omitempty"`
+	HasDecimals  bool   `json:"hasDecimals"`
+	IndexVersion string `json:"indexVersion,omitempty"`
+}
+
+// DLCDataPageResponse represents a page of DLC data, NextCursor is set if there is a next page
+type DLCDataPageResponse struct {
+	Events     []*DLCDataResponse `json:"events"`
+	NextCursor string             `json:"nextCursor,omitempty"`
+}
+
+// BatchItemResponse represents the result of an event of a batch request, either the event or the error
+type BatchItemResponse struct {
+	Event *DLCDataResponse `json:"event,omitempty"`
+	Error *Error           `json:"error,omitempty"`
+}
+
+// NewEventNotificationResponse transforms an entity.EventNotification and its notified event to notification response
+// the signature and value of announcement notifications are not included (the event may have been attested since)
+func NewEventNotificationResponse(
+	oraclePubKey *dlccrypto.SchnorrPublicKey,
+	notification *entity.EventNotification,
+	dlcData *entity.DLCData) *EventNotificationResponse {
+	event := NewDLCDataResponse(oraclePubKey, dlcData)
+	if notification.Type == entity.EventNotificationAnnounced {
+		event.Signature, event.Value, event.Settlement = "", "", ""
+	}
+	return &EventNotificationResponse{
+		Sequence:  notification.Sequence,
+		Type:      notification.Type,
+		CreatedAt: notification.CreatedAt,
+		Event:     event,
+	}
+}
+
+// EventNotificationResponse represents an event announcement or attestation notification
+type EventNotificationResponse struct {
+	Sequence  uint64           `json:"sequence"`
+	Type      string           `json:"type"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Event     *DLCDataResponse `json:"event"`
+}
+
+// WebhookPayload represents the json body posted to the webhooks
+type WebhookPayload struct {
+	DeliveryID uint `json:"deliveryId"`
+	EventNotificationResponse
+}
+
+// NewWebhookPayloadEncoder returns the encoder of the webhook deliveries json body (see WebhookPayload)
+func NewWebhookPayloadEncoder(oraclePubKey *dlccrypto.SchnorrPublicKey) func(
+	deliveryID uint,
+	notification *entity.EventNotification,
+	dlcData *entity.DLCData) ([]byte, error) {
+	return func(deliveryID uint, notification *entity.EventNotification, dlcData *entity.DLCData) ([]byte, error) {
+		return json.Marshal(&WebhookPayload{
+			DeliveryID:                deliveryID,
+			EventNotificationResponse: *NewEventNotificationResponse(oraclePubKey, notification, dlcData),
+		})
+	}
 }
 
 // AssetConfigResponse represents the configuration of an asset api
@@ -41,9 +128,77 @@ type AssetConfigResponse struct {
 	Frequency   string          `json:"frequency"`
 	RangeD      string          `json:"range"`
 	EventTypes  map[string]bool `json:"eventTypes"`
+	Settlement  string          `json:"settlement"`
+	// Index composition versions (only for index assets)
+	Index []*IndexCompositionResponse `json:"index,omitempty"`
+}
+
+// IndexCompositionResponse represents a version of the composition of an index asset
+type IndexCompositionResponse struct {
+	Version       string                    `json:"version"`
+	EffectiveFrom time.Time                 `json:"effectiveFrom"`
+	Components    []*IndexComponentResponse `json:"components"`
+}
+
+// IndexComponentResponse represents a weighted component of an index asset
+type IndexComponentResponse struct {
+	Name     string  `json:"name"`
+	Asset    string  `json:"asset"`
+	Currency string  `json:"currency"`
+	Weight   float64 `json:"weight"`
 }
 
 // OraclePublicKeyResponse represents the public key of the oracle
 type OraclePublicKeyResponse struct {
 	PublicKey string `json:"publicKey"`
 }
+
+// NewWebhookResponse transforms a entity.Webhook to webhook response (without the secret)
+func NewWebhookResponse(webhook *entity.Webhook) *WebhookResponse {
+	return &WebhookResponse{
+		ID:        webhook.ID,
+		Owner:     webhook.Owner,
+		URL:       webhook.URL,
+		Assets:    webhook.AssetIDs(),
+		CreatedAt: webhook.CreatedAt,
+	}
+}
+
+// WebhookResponse represents a webhook subscription
+type WebhookResponse struct {
+	ID        uint      `json:"id"`
+	Owner     string    `json:"owner"`
+	URL       string    `json:"url"`
+	Assets    []string  `json:"assets"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NewWebhookDeliveryResponse transforms a entity.WebhookDelivery to webhook delivery response
+func NewWebhookDeliveryResponse(delivery *entity.WebhookDelivery) *WebhookDeliveryResponse {
+	return &WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		Sequence:       delivery.NotificationSequence,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+}
+
+// WebhookDeliveryResponse represents an entry of a webhook delivery log
+type WebhookDeliveryResponse struct {
+	ID             uint       `json:"id"`
+	WebhookID      uint       `json:"webhookId"`
+	Sequence       uint64     `json:"sequence"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt"`
+	ResponseStatus int        `json:"responseStatus,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/oracle"
+	"time"
+)
+
+// NewV2EventResponse transforms a entity.DLCData to a v2 event response
+func NewV2EventResponse(
+	oraclePubKey *dlccrypto.SchnorrPublicKey,
+	dlcData *entity.DLCData,
+	config AssetConfig) *V2EventResponse {
+	status := dlcData.Status
+	switch {
+	case dlcData.IsSigned():
+		status = entity.DLCDataStatusAttested
+	case status == "":
+		status = entity.DLCDataStatusAnnounced
+	}
+	rawEventType, _, _ := oracle.ParseEventType(dlcData.EventType)
+	response := &V2EventResponse{
+		EventID: NewEventID(dlcData.AssetID, dlcData.EventType, dlcData.PublishedDate),
+		KeyID:   oraclePubKey.EncodeToString(),
+		Status:  status,
+		Descriptor: &V2EventDescriptorResponse{
+			AssetID:     dlcData.AssetID,
+			Asset:       config.Asset,
+			Currency:    config.Currency,
+			EventType:   dlcData.EventType,
+			OutcomeType: v2OutcomeType(rawEventType),
+			HasDecimals: config.HasDecimals,
+		},
+		Nonces:     []string{dlcData.Rvalue},
+		Signatures: []string{},
+		Timestamps: &V2EventTimestampsResponse{
+			AnnouncedAt: dlcData.CreatedAt,
+			PublishAt:   dlcData.PublishedDate,
+		},
+	}
+	if dlcData.IsSigned() {
+		attestedAt := dlcData.UpdatedAt
+		response.Signatures = []string{dlcData.Signature}
+		response.Outcome = &V2EventOutcomeResponse{
+			Value:        dlcData.Value,
+			Settlement:   dlcData.SettlementMethod,
+			IndexVersion: dlcData.IndexVersion,
+		}
+		response.Timestamps.AttestedAt = &attestedAt
+	}
+	return response
+}
+
+// v2OutcomeType returns the type of the attested outcomes of an event type
+func v2OutcomeType(rawEventType string) string {
+	if rawEventType == "above" {
+		return V2OutcomeTypeBoolean
+	}
+	return V2OutcomeTypeNumeric
+}
+
+const (
+	// V2OutcomeTypeNumeric outcome of the events attesting a number (digits)
+	V2OutcomeTypeNumeric = "numeric"
+	// V2OutcomeTypeBoolean outcome of the events attesting true or false (above(<threshold>))
+	V2OutcomeTypeBoolean = "boolean"
+)
+
+// V2EventResponse represents an event of the v2 api, each nonce is used for the signature of the same index
+type V2EventResponse struct {
+	EventID    string                     `json:"eventId"`
+	KeyID      string                     `json:"keyId"`
+	Status     string                     `json:"status"`
+	Descriptor *V2EventDescriptorResponse `json:"descriptor"`
+	Nonces     []string                   `json:"nonces"`
+	Signatures []string                   `json:"signatures"`
+	Outcome    *V2EventOutcomeResponse    `json:"outcome,omitempty"`
+	Timestamps *V2EventTimestampsResponse `json:"timestamps"`
+}
+
+// V2EventDescriptorResponse represents what an event attests and how its outcome is expressed
+type V2EventDescriptorResponse struct {
+	AssetID     string `json:"assetId"`
+	Asset       string `json:"asset"`
+	Currency    string `json:"currency"`
+	EventType   string `json:"eventType"`
+	OutcomeType string `json:"outcomeType"`
+	HasDecimals bool   `json:"hasDecimals"`
+}
+
+// V2EventOutcomeResponse represents the attested outcome of an event and how it has been computed
+type V2EventOutcomeResponse struct {
+	Value        string `json:"value"`
+	Settlement   string `json:"settlement,omitempty"`
+	IndexVersion string `json:"indexVersion,omitempty"`
+}
+
+// V2EventTimestampsResponse represents the lifecycle dates of an event
+type V2EventTimestampsResponse struct {
+	AnnouncedAt time.Time  `json:"announcedAt"`
+	PublishAt   time.Time  `json:"publishAt"`
+	AttestedAt  *time.Time `json:"attestedAt,omitempty"`
+}
+
+// V2EventPageResponse represents a page of v2 events, NextCursor is set if there is a next page
+type V2EventPageResponse struct {
+	Events     []*V2EventResponse `json:"events"`
+	NextCursor string             `json:"nextCursor,omitempty"`
+}
+
+// V2BatchItemResponse represents the result of an event of a v2 batch request, either the event or the error
+type V2BatchItemResponse struct {
+	Event *V2EventResponse `json:"event,omitempty"`
+	Error *Error           `json:"error,omitempty"`
+}
@@ -0,0 +1,325 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/oracle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// URLQueryTagAsset Tag to be used to select assets (repeated or comma separated)
+	URLQueryTagAsset = "asset"
+	// URLQueryTagCursor Tag to be used to resume a stream after a notification sequence
+	URLQueryTagCursor = "cursor"
+	// HeaderLastEventID header sent by server sent events clients when reconnecting (used as cursor)
+	HeaderLastEventID = "Last-Event-ID"
+	// RouteGETStream relative GET route to stream the event notifications
+	RouteGETStream = ""
+)
+
+const (
+	streamBatchSize           = 100
+	streamWriteTimeout        = 10 * time.Second
+	defaultStreamPollInterval = time.Second
+	defaultStreamHeartbeat    = 15 * time.Second
+	defaultStreamGapTimeout   = 10 * time.Second
+)
+
+// StreamController represents the event notification stream api Controller
+type StreamController struct {
+	assets         AssetProvider
+	pollInterval   time.Duration
+	heartbeat      time.Duration
+	gapTimeout     time.Duration
+	allowedOrigins map[string]bool
+}
+
+// NewStreamController creates a new Controller structure with the given parameters.
+// default intervals are used if not set, the websocket connections from browsers are only accepted
+// from the allowed origins ("*" for any origin)
+func NewStreamController(
+	assets AssetProvider,
+	pollInterval time.Duration,
+	heartbeat time.Duration,
+	gapTimeout time.Duration,
+	allowedOrigins []string) Controller {
+	if pollInterval <= 0 {
+		pollInterval = defaultStreamPollInterval
+	}
+	if heartbeat <= 0 {
+		heartbeat = defaultStreamHeartbeat
+	}
+	if gapTimeout <= 0 {
+		gapTimeout = defaultStreamGapTimeout
+	}
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	return &StreamController{
+		assets:         assets,
+		pollInterval:   pollInterval,
+		heartbeat:      heartbeat,
+		gapTimeout:     gapTimeout,
+		allowedOrigins: origins,
+	}
+}
+
+// Routes list and binds all routes to the router group provided
+func (ct *StreamController) Routes(route *gin.RouterGroup) {
+	route.GET(RouteGETStream, ct.GetStream)
+}
+
+// StreamFilter represents the notifications selected by a stream and its current position
+type StreamFilter struct {
+	// Cursor sequence of the last read notification (sent or not selected)
+	Cursor uint64
+	// AssetIDs and EventTypes selected, all if empty
+	AssetIDs   []string
+	EventTypes []string
+}
+
+// GetStream handler streams the announcement and attestation notifications of the requested assets and event types
+// after the cursor (or the Last-Event-ID header, the latest notification if none) as server sent events,
+// or as websocket json messages if the request is a websocket upgrade
+func (ct *StreamController) GetStream(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Stream")
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	filter, err := ct.parseStreamFilter(c, db)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if isWebSocketUpgrade(c.Request) {
+		ct.streamWebSocket(c, db, filter)
+		return
+	}
+	ct.streamServerSentEvents(c, db, filter)
+}
+
+func (ct *StreamController) streamServerSentEvents(c *gin.Context, db *gorm.DB, filter *StreamFilter) {
+	logger := ginlogrus.GetCtxLogger(c)
+	oracleInstance := c.MustGet(ContextIDOracle).(*oracle.Oracle)
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	send := func(notification *EventNotificationResponse) error {
+		data, err := json.Marshal(notification)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", notification.Sequence, notification.Type, data)
+		c.Writer.Flush()
+		return err
+	}
+	heartbeat := func() error {
+		_, err := fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		c.Writer.Flush()
+		return err
+	}
+	err := StreamNotifications(
+		c.Request.Context(), db, oracleInstance.PublicKey, filter, ct.pollInterval, ct.heartbeat, ct.gapTimeout, send, heartbeat)
+	if err != nil {
+		logger.Warnf("Event stream closed: %v", err)
+	}
+}
+
+func (ct *StreamController) streamWebSocket(c *gin.Context, db *gorm.DB, filter *StreamFilter) {
+	logger := ginlogrus.GetCtxLogger(c)
+	oracleInstance := c.MustGet(ContextIDOracle).(*oracle.Oracle)
+	server := websocket.Server{
+		Handshake: ct.checkOrigin,
+		Handler: func(ws *websocket.Conn) {
+			ctx, cancel := context.WithCancel(c.Request.Context())
+			defer cancel()
+			// received messages are ignored, a read failure means the connection is closed
+			go func() {
+				defer cancel()
+				var message string
+				for websocket.Message.Receive(ws, &message) == nil {
+				}
+			}()
+
+			send := func(notification *EventNotificationResponse) error {
+				ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+				return websocket.JSON.Send(ws, notification)
+			}
+			heartbeat := func() error {
+				ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+				ws.PayloadType = websocket.PingFrame
+				defer func() { ws.PayloadType = websocket.TextFrame }()
+				_, err := ws.Write([]byte{})
+				return err
+			}
+			err := StreamNotifications(
+				ctx, db, oracleInstance.PublicKey, filter, ct.pollInterval, ct.heartbeat, ct.gapTimeout, send, heartbeat)
+			if err != nil {
+				logger.Warnf("Event stream closed: %v", err)
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkOrigin rejects the websocket handshakes of the browsers (sending an Origin header) from an origin not allowed,
+// so that a page of another site cannot open a stream with the credentials of the user (non browser clients are accepted)
+func (ct *StreamController) checkOrigin(config *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" || ct.allowedOrigins["*"] || ct.allowedOrigins[origin] {
+		return nil
+	}
+	return errors.Errorf("origin %s is not allowed", origin)
+}
+
+// StreamNotifications sends the notifications after the filter cursor as they are created (polled every poll interval)
+// until the context is done or an error occurs, heartbeat is called when the stream has been idle for the heartbeat interval.
+// The notifications following a missing sequence are held until it is committed or the gap timeout expires
+func StreamNotifications(
+	ctx context.Context,
+	db *gorm.DB,
+	oraclePubKey *dlccrypto.SchnorrPublicKey,
+	filter *StreamFilter,
+	pollInterval time.Duration,
+	heartbeatInterval time.Duration,
+	gapTimeout time.Duration,
+	send func(notification *EventNotificationResponse) error,
+	heartbeat func() error) error {
+	pollTicker := time.NewTicker(pollInterval)
+	defer pollTicker.Stop()
+	heartbeatTimer := time.NewTimer(heartbeatInterval)
+	defer heartbeatTimer.Stop()
+	for {
+		for {
+			cursor := filter.Cursor
+			notifications, err := nextNotifications(db, oraclePubKey, filter, time.Now().Add(-gapTimeout))
+			if err != nil {
+				return err
+			}
+			for _, notification := range notifications {
+				if err := send(notification); err != nil {
+					return err
+				}
+			}
+			if len(notifications) > 0 {
+				heartbeatTimer.Reset(heartbeatInterval)
+			}
+			if filter.Cursor == cursor {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-pollTicker.C:
+		case <-heartbeatTimer.C:
+			if err := heartbeat(); err != nil {
+				return err
+			}
+			heartbeatTimer.Reset(heartbeatInterval)
+		}
+	}
+}
+
+// nextNotifications returns the selected notifications after the filter cursor and moves the cursor after the notifications read,
+// the cursor is not moved past a missing sequence unless the notification following it was created before settledBefore
+func nextNotifications(
+	db *gorm.DB,
+	oraclePubKey *dlccrypto.SchnorrPublicKey,
+	filter *StreamFilter,
+	settledBefore time.Time) ([]*EventNotificationResponse, error) {
+	notifications, cursor, err := entity.FindEventNotificationsAfterFiltered(
+		db, filter.Cursor, filter.AssetIDs, filter.EventTypes, settledBefore, streamBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]*EventNotificationResponse, len(notifications))
+	for i := range notifications {
+		notification := &notifications[i]
+		dlcData, err := entity.FindDLCDataPublishedAt(db, notification.AssetID, notification.PublishedDate, notification.EventType)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "could not find the event of notification %d", notification.Sequence)
+		}
+		responses[i] = NewEventNotificationResponse(oraclePubKey, notification, dlcData)
+	}
+	filter.Cursor = cursor
+	return responses, nil
+}
+
+func (ct *StreamController) parseStreamFilter(c *gin.Context, db *gorm.DB) (*StreamFilter, error) {
+	cursor := c.Query(URLQueryTagCursor)
+	if cursor == "" {
+		cursor = c.GetHeader(HeaderLastEventID)
+	}
+	return NewStreamFilter(ct.assets, db,
+		splitQueryValues(c.QueryArray(URLQueryTagAsset)), splitQueryValues(c.QueryArray(URLQueryTagEventType)), cursor)
+}
+
+// NewStreamFilter returns the filter of a stream of the given assets (which should be available) and event types,
+// starting after the cursor (a notification sequence) or after the latest notification if not set
+func NewStreamFilter(assets AssetProvider, db *gorm.DB, assetIDs []string, eventTypes []string, cursor string) (*StreamFilter, error) {
+	filter := &StreamFilter{AssetIDs: assetIDs, EventTypes: eventTypes}
+	for _, assetID := range filter.AssetIDs {
+		_, ok, err := findAssetConfig(assets, assetID)
+		if err != nil {
+			return nil, NewUnknownDBError(err)
+		}
+		if !ok {
+			cause := errors.Errorf("unknown asset %s", assetID)
+			return nil, NewBadRequestError(InvalidQueryBadRequestErrorCode, cause, URLQueryTagAsset)
+		}
+	}
+
+	if cursor == "" {
+		last, err := entity.FindLastEventNotificationSequence(db)
+		if err != nil {
+			return nil, NewUnknownDBError(err)
+		}
+		filter.Cursor = last
+		return filter, nil
+	}
+	sequence, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return nil, NewBadRequestError(InvalidQueryBadRequestErrorCode, err, URLQueryTagCursor)
+	}
+	filter.Cursor = sequence
+	return filter, nil
+}
+
+// splitQueryValues returns the non empty values of a repeated and/or comma separated query parameter
+func splitQueryValues(values []string) []string {
+	split := []string{}
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				split = append(split, item)
+			}
+		}
+	}
+	return split
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
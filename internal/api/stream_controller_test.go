@@ -0,0 +1,166 @@
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+var streamTestDate = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+const streamTestOrigin = "https://app.example.com"
+
+// SetupStreamServer returns a started server serving the stream api at /stream
+func SetupStreamServer(t *testing.T) (*httptest.Server, *orm.ORM) {
+	gin.SetMode(gin.TestMode)
+	oracleInstance, err := NewTestOracleService()
+	require.NoError(t, err)
+	ormInstance := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{})
+	ormInstance.GetDB().Create(&entity.Asset{AssetID: "btcusd"})
+	ormInstance.GetDB().Create(&entity.Asset{AssetID: "ethusd"})
+	r := gin.New()
+	r.Use(api.ErrorHandler(), func(c *gin.Context) {
+		c.Set(api.ContextIDOrm, ormInstance)
+		c.Set(api.ContextIDOracle, oracleInstance)
+	})
+	controller := api.NewStreamController(api.StaticAssets{"btcusd": *TestAssetConfig, "ethusd": *TestAssetConfig},
+		10*time.Millisecond, time.Hour, time.Minute, []string{streamTestOrigin})
+	controller.Routes(r.Group(api.StreamBaseRoute))
+	return httptest.NewServer(r), ormInstance
+}
+
+func CreateStreamTestEvent(t *testing.T, ormInstance *orm.ORM, assetID string, publishDate time.Time) {
+	key := assetID + publishDate.Format(time.RFC3339)
+	_, err := entity.CreateDLCData(ormInstance.GetDB(), assetID, publishDate, "digits", "k"+key, "r"+key)
+	require.NoError(t, err)
+}
+
+type serverSentEvent struct {
+	id        string
+	eventType string
+	data      string
+}
+
+func readServerSentEvent(t *testing.T, reader *bufio.Reader) serverSentEvent {
+	event := serverSentEvent{}
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if event.data != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStreamController_GetStream_ServerSentEvents_StreamsFilteredFromCursor(t *testing.T) {
+	server, ormInstance := SetupStreamServer(t)
+	defer server.Close()
+	CreateStreamTestEvent(t, ormInstance, "btcusd", streamTestDate)
+	CreateStreamTestEvent(t, ormInstance, "ethusd", streamTestDate)
+
+	resp, err := http.Get(server.URL + api.StreamBaseRoute + "?asset=btcusd&cursor=0")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+
+	first := readServerSentEvent(t, reader)
+	assert.Equal(t, "1", first.id)
+	assert.Equal(t, entity.EventNotificationAnnounced, first.eventType)
+
+	// live events after the connection
+	CreateStreamTestEvent(t, ormInstance, "ethusd", streamTestDate.Add(time.Hour))
+	CreateStreamTestEvent(t, ormInstance, "btcusd", streamTestDate.Add(time.Hour))
+	second := readServerSentEvent(t, reader)
+	assert.Equal(t, "4", second.id)
+	notification := &api.EventNotificationResponse{}
+	assert.NoError(t, json.Unmarshal([]byte(second.data), notification))
+	assert.Equal(t, "btcusd", notification.Event.AssetID)
+	assert.Equal(t, streamTestDate.Add(time.Hour), notification.Event.PublishedDate)
+}
+
+func TestStreamController_GetStream_LastEventID_ResumesAfterIt(t *testing.T) {
+	server, ormInstance := SetupStreamServer(t)
+	defer server.Close()
+	CreateStreamTestEvent(t, ormInstance, "btcusd", streamTestDate)
+	CreateStreamTestEvent(t, ormInstance, "btcusd", streamTestDate.Add(time.Hour))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+api.StreamBaseRoute, nil)
+	req.Header.Set(api.HeaderLastEventID, "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	event := readServerSentEvent(t, bufio.NewReader(resp.Body))
+	assert.Equal(t, "2", event.id)
+}
+
+func TestStreamController_GetStream_WebSocket_StreamsNewNotifications(t *testing.T) {
+	server, ormInstance := SetupStreamServer(t)
+	defer server.Close()
+	// already created events are not sent without cursor
+	CreateStreamTestEvent(t, ormInstance, "btcusd", streamTestDate)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + api.StreamBaseRoute + "?eventType=digits"
+	ws, err := websocket.Dial(wsURL, "", streamTestOrigin)
+	require.NoError(t, err)
+	defer ws.Close()
+	CreateStreamTestEvent(t, ormInstance, "ethusd", streamTestDate)
+
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	notification := &api.EventNotificationResponse{}
+	require.NoError(t, websocket.JSON.Receive(ws, notification))
+	assert.Equal(t, uint64(2), notification.Sequence)
+	assert.Equal(t, entity.EventNotificationAnnounced, notification.Type)
+	assert.Equal(t, "ethusd", notification.Event.AssetID)
+	assert.Equal(t, "rethusd"+streamTestDate.Format(time.RFC3339), notification.Event.Rvalue)
+}
+
+func TestStreamController_GetStream_WebSocket_OriginNotAllowed_RejectsHandshake(t *testing.T) {
+	server, _ := SetupStreamServer(t)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + api.StreamBaseRoute
+	_, err := websocket.Dial(wsURL, "", "https://attacker.example.com")
+
+	assert.Error(t, err)
+}
+
+func TestStreamController_GetStream_InvalidQuery_ReturnsBadRequest(t *testing.T) {
+	server, _ := SetupStreamServer(t)
+	defer server.Close()
+	for _, query := range []string{"?asset=unknown", "?cursor=abc", "?asset=btcusd,unknown"} {
+		resp, err := http.Get(server.URL + api.StreamBaseRoute + query)
+		require.NoError(t, err)
+		if assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query) {
+			actual := &api.ErrorResponse{}
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(actual))
+			assert.Equal(t, api.InvalidQueryBadRequestErrorCode, actual.ErrorCode)
+		}
+		resp.Body.Close()
+	}
+}
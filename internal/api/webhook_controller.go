@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/netguard"
+	"strconv"
+
+	ginlogrus "github.com/Bose/go-gin-logrus"
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+const (
+	// URLParamTagID Tag to use as id parameter in route
+	URLParamTagID = "id"
+	// URLQueryTagStatus Tag to be used to filter by status
+	URLQueryTagStatus = "status"
+	// RouteWebhook relative route of the webhook collection
+	RouteWebhook = ""
+	// RouteWebhookID relative route of a webhook
+	RouteWebhookID = "/:" + URLParamTagID
+	// RouteGETWebhookDeliveries relative GET route to retrieve the delivery log of a webhook
+	RouteGETWebhookDeliveries = "/:" + URLParamTagID + "/deliveries"
+
+	webhookMinSecretLength = 16
+	webhookDeliveriesLimit = 100
+)
+
+// WebhookRequest represents the webhook registration request body
+type WebhookRequest struct {
+	URL string `json:"url"`
+	// Assets asset ids notified (all assets if empty)
+	Assets []string `json:"assets"`
+	// Secret key used to sign the notifications with HMAC-SHA256
+	Secret string `json:"secret"`
+}
+
+// WebhookController represents the webhook subscriptions api Controller,
+// the webhooks are only visible to the principal which registered them (and the admins)
+type WebhookController struct {
+	assets AssetProvider
+	guard  *netguard.Guard
+}
+
+// NewWebhookController creates a new Controller structure with the given parameters.
+// the webhook urls should resolve to addresses allowed by the guard
+func NewWebhookController(assets AssetProvider, guard *netguard.Guard) Controller {
+	return &WebhookController{
+		assets: assets,
+		guard:  guard,
+	}
+}
+
+// Routes list and binds all routes to the router group provided
+func (ct *WebhookController) Routes(route *gin.RouterGroup) {
+	route.POST(RouteWebhook, ct.PostWebhook)
+	route.GET(RouteWebhook, ct.GetWebhooks)
+	route.GET(RouteWebhookID, ct.GetWebhook)
+	route.DELETE(RouteWebhookID, ct.DeleteWebhook)
+	route.GET(RouteGETWebhookDeliveries, ct.GetWebhookDeliveries)
+}
+
+// PostWebhook handler registers a new webhook subscription
+func (ct *WebhookController) PostWebhook(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Post Webhook")
+	request := &WebhookRequest{}
+	if err := c.ShouldBindJSON(request); err != nil {
+		c.Error(NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, err, "body"))
+		return
+	}
+	if err := ct.validateWebhookRequest(c.Request.Context(), request); err != nil {
+		c.Error(err)
+		return
+	}
+
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	webhook, err := entity.CreateWebhook(db, principalOf(c).Owner(), request.URL, request.Assets, request.Secret)
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	c.JSON(http.StatusCreated, NewWebhookResponse(webhook))
+}
+
+// GetWebhooks handler returns the webhook subscriptions of the caller (all subscriptions for the admins)
+func (ct *WebhookController) GetWebhooks(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Webhooks")
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	principal := principalOf(c)
+	var webhooks []entity.Webhook
+	var err error
+	if principal.Role.Includes(RoleAdmin) {
+		webhooks, err = entity.FindWebhooks(db)
+	} else {
+		webhooks, err = entity.FindWebhooksOfOwner(db, principal.Owner())
+	}
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	response := make([]*WebhookResponse, len(webhooks))
+	for i := range webhooks {
+		response[i] = NewWebhookResponse(&webhooks[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetWebhook handler returns a webhook subscription
+func (ct *WebhookController) GetWebhook(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Webhook")
+	webhook, err := findWebhook(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, NewWebhookResponse(webhook))
+}
+
+// DeleteWebhook handler deletes a webhook subscription, its pending deliveries are abandoned
+func (ct *WebhookController) DeleteWebhook(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Delete Webhook")
+	webhook, err := findWebhook(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	if err := entity.DeleteWebhook(db, webhook.ID); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			c.Error(NewRecordNotFoundDBError(err, "webhook "+c.Param(URLParamTagID)))
+			return
+		}
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries handler returns the most recent deliveries of a webhook (optionally filtered by status)
+func (ct *WebhookController) GetWebhookDeliveries(c *gin.Context) {
+	ginlogrus.SetCtxLoggerHeader(c, "request-header", "Get Webhook Deliveries")
+	webhook, err := findWebhook(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	deliveries, err := entity.FindWebhookDeliveries(db, webhook.ID, c.Query(URLQueryTagStatus), webhookDeliveriesLimit)
+	if err != nil {
+		c.Error(NewUnknownDBError(err))
+		return
+	}
+	response := make([]*WebhookDeliveryResponse, len(deliveries))
+	for i := range deliveries {
+		response[i] = NewWebhookDeliveryResponse(&deliveries[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (ct *WebhookController) validateWebhookRequest(ctx context.Context, request *WebhookRequest) error {
+	parsedURL, err := url.Parse(request.URL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		cause := errors.Errorf("webhook url should be an absolute http(s) url %s", request.URL)
+		return NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, cause, "url")
+	}
+	if err := ct.guard.CheckURL(ctx, request.URL); err != nil {
+		cause := errors.WithMessage(err, "webhook url should resolve to public addresses")
+		return NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, cause, "url")
+	}
+	if len(request.Secret) < webhookMinSecretLength {
+		cause := errors.Errorf("webhook secret should be at least %d characters", webhookMinSecretLength)
+		return NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, cause, "secret")
+	}
+	for _, assetID := range request.Assets {
+		_, ok, err := findAssetConfig(ct.assets, assetID)
+		if err != nil {
+			return NewUnknownDBError(err)
+		}
+		if !ok {
+			cause := errors.Errorf("unknown asset %s", assetID)
+			return NewBadRequestError(InvalidRequestBodyBadRequestErrorCode, cause, "assets")
+		}
+	}
+	return nil
+}
+
+// findWebhook returns the webhook of the id route parameter,
+// the webhooks of the other principals are reported as not found (unless the caller is an admin)
+func findWebhook(c *gin.Context) (*entity.Webhook, error) {
+	id, err := parseID(c)
+	if err != nil {
+		return nil, err
+	}
+	db := c.MustGet(ContextIDOrm).(*orm.ORM).GetDB()
+	webhook, err := entity.FindWebhook(db, id)
+	if err == nil && !principalOf(c).owns(webhook.Owner) {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, NewRecordNotFoundDBError(err, "webhook "+c.Param(URLParamTagID))
+		}
+		return nil, NewUnknownDBError(err)
+	}
+	return webhook, nil
+}
+
+func parseID(c *gin.Context) (uint, error) {
+	idStr := c.Param(URLParamTagID)
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return 0, NewBadRequestError(InvalidIDBadRequestErrorCode, err, idStr)
+	}
+	return uint(id), nil
+}
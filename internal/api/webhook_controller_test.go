@@ -0,0 +1,229 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/netguard"
+	"p2pderivatives-oracle/test"
+	"strings"
+	"testing"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testWebhookSecret = "0123456789abcdef"
+	testWebhookOwner  = api.AuthMethodAPIKey + ":caller"
+)
+
+var testWebhookCaller = &api.Principal{ID: "caller", Method: api.AuthMethodAPIKey, Role: api.RoleAttester}
+
+// testResolver resolves the host names of the test urls
+type testResolver map[string][]string
+
+func (r testResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs := []net.IPAddr{}
+	for _, ip := range r[host] {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func SetupWebhookEngine(recorder *httptest.ResponseRecorder) (*gin.Context, *gin.Engine, *orm.ORM) {
+	return SetupWebhookEngineAs(recorder, testWebhookCaller)
+}
+
+func SetupWebhookEngineAs(recorder *httptest.ResponseRecorder, principal *api.Principal) (*gin.Context, *gin.Engine, *orm.ORM) {
+	guard := &netguard.Guard{Resolver: testResolver{
+		"example.com":          {"93.184.216.34"},
+		"internal.example.com": {"10.0.0.1"},
+	}}
+	controller := api.NewWebhookController(api.StaticAssets{"btcusd": *TestAssetConfig, "ethusd": *TestAssetConfig}, guard)
+	ormInstance := test.NewOrm(&entity.Webhook{}, &entity.WebhookDelivery{})
+	setup := func(c *gin.Context) {
+		c.Set(api.ContextIDOrm, ormInstance)
+		c.Set(api.ContextIDPrincipal, principal)
+	}
+	c, r := SetupEngine(recorder, controller, api.ErrorHandler(), setup)
+	return c, r, ormInstance
+}
+
+func TestWebhookController_PostWebhook_Valid_ReturnsCreatedWithoutSecret(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r, ormInstance := SetupWebhookEngine(resp)
+	body := `{"url":"https://example.com/hook","assets":["btcusd"],"secret":"` + testWebhookSecret + `"}`
+	c.Request, _ = http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+
+	r.ServeHTTP(resp, c.Request)
+
+	if assert.Equal(t, http.StatusCreated, resp.Code) {
+		assert.NotContains(t, resp.Body.String(), testWebhookSecret)
+		actual := &api.WebhookResponse{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), actual))
+		assert.Equal(t, "https://example.com/hook", actual.URL)
+		assert.Equal(t, []string{"btcusd"}, actual.Assets)
+		inDB, err := entity.FindWebhook(ormInstance.GetDB(), actual.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, testWebhookSecret, inDB.Secret)
+		assert.Equal(t, testWebhookOwner, inDB.Owner)
+	}
+}
+
+func TestWebhookController_PostWebhook_Invalid_ReturnsBadRequest(t *testing.T) {
+	bodies := []string{
+		`{"url":"example.com/hook","secret":"` + testWebhookSecret + `"}`,
+		`{"url":"https://example.com/hook","secret":"short"}`,
+		`{"url":"https://example.com/hook","assets":["unknown"],"secret":"` + testWebhookSecret + `"}`,
+		`{"url":"http://127.0.0.1:8080/hook","secret":"` + testWebhookSecret + `"}`,
+		`{"url":"http://169.254.169.254/latest/meta-data","secret":"` + testWebhookSecret + `"}`,
+		`{"url":"https://internal.example.com/hook","secret":"` + testWebhookSecret + `"}`,
+		`not json`,
+	}
+	for _, body := range bodies {
+		resp := httptest.NewRecorder()
+		c, r, _ := SetupWebhookEngine(resp)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+
+		r.ServeHTTP(resp, c.Request)
+
+		if assert.Equal(t, http.StatusBadRequest, resp.Code, body) {
+			actual := &api.ErrorResponse{}
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), actual))
+			assert.Equal(t, api.InvalidRequestBodyBadRequestErrorCode, actual.ErrorCode)
+		}
+	}
+}
+
+func TestWebhookController_GetWebhooks_ReturnsOwned(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r, ormInstance := SetupWebhookEngine(resp)
+	_, err := entity.CreateWebhook(ormInstance.GetDB(), testWebhookOwner, "https://example.com/first", []string{}, testWebhookSecret)
+	assert.NoError(t, err)
+	_, err = entity.CreateWebhook(ormInstance.GetDB(), "apikey:other", "https://example.com/other", []string{}, testWebhookSecret)
+	assert.NoError(t, err)
+	_, err = entity.CreateWebhook(ormInstance.GetDB(), testWebhookOwner, "https://example.com/second", []string{"ethusd"}, testWebhookSecret)
+	assert.NoError(t, err)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+
+	r.ServeHTTP(resp, c.Request)
+
+	if assert.Equal(t, http.StatusOK, resp.Code) {
+		actual := []*api.WebhookResponse{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &actual))
+		if assert.Len(t, actual, 2) {
+			assert.Equal(t, []string{}, actual[0].Assets)
+			assert.Equal(t, []string{"ethusd"}, actual[1].Assets)
+		}
+	}
+}
+
+func TestWebhookController_GetWebhooks_Admin_ReturnsAll(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r, ormInstance := SetupWebhookEngineAs(resp, &api.Principal{ID: "operator", Method: api.AuthMethodAPIKey, Role: api.RoleAdmin})
+	_, err := entity.CreateWebhook(ormInstance.GetDB(), testWebhookOwner, "https://example.com/first", []string{}, testWebhookSecret)
+	assert.NoError(t, err)
+	_, err = entity.CreateWebhook(ormInstance.GetDB(), "apikey:other", "https://example.com/other", []string{}, testWebhookSecret)
+	assert.NoError(t, err)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+
+	r.ServeHTTP(resp, c.Request)
+
+	if assert.Equal(t, http.StatusOK, resp.Code) {
+		actual := []*api.WebhookResponse{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &actual))
+		if assert.Len(t, actual, 2) {
+			assert.Equal(t, testWebhookOwner, actual[0].Owner)
+			assert.Equal(t, "apikey:other", actual[1].Owner)
+		}
+	}
+}
+
+func TestWebhookController_DeleteWebhook_OtherOwner_ReturnsNotFound(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r, ormInstance := SetupWebhookEngine(resp)
+	webhook, err := entity.CreateWebhook(ormInstance.GetDB(), "apikey:other", "https://example.com/hook", []string{}, testWebhookSecret)
+	assert.NoError(t, err)
+	c.Request, _ = http.NewRequest(http.MethodDelete, "/1", nil)
+
+	r.ServeHTTP(resp, c.Request)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	_, err = entity.FindWebhook(ormInstance.GetDB(), webhook.ID)
+	assert.NoError(t, err)
+}
+
+func TestWebhookController_GetWebhookDeliveries_OtherOwner_ReturnsNotFound(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r, ormInstance := SetupWebhookEngine(resp)
+	_, err := entity.CreateWebhook(ormInstance.GetDB(), "apikey:other", "https://example.com/hook", []string{}, testWebhookSecret)
+	assert.NoError(t, err)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/1/deliveries", nil)
+
+	r.ServeHTTP(resp, c.Request)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestWebhookController_DeleteWebhook_Present_ReturnsNoContent(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r, ormInstance := SetupWebhookEngine(resp)
+	webhook, err := entity.CreateWebhook(ormInstance.GetDB(), testWebhookOwner, "https://example.com/hook", []string{}, testWebhookSecret)
+	assert.NoError(t, err)
+	c.Request, _ = http.NewRequest(http.MethodDelete, "/1", nil)
+
+	r.ServeHTTP(resp, c.Request)
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	_, err = entity.FindWebhook(ormInstance.GetDB(), webhook.ID)
+	assert.Error(t, err)
+}
+
+func TestWebhookController_GetWebhook_NotPresent_ReturnsNotFound(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r, _ := SetupWebhookEngine(resp)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/42", nil)
+
+	r.ServeHTTP(resp, c.Request)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestWebhookController_GetWebhook_InvalidID_ReturnsBadRequest(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r, _ := SetupWebhookEngine(resp)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/abc", nil)
+
+	r.ServeHTTP(resp, c.Request)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestWebhookController_GetWebhookDeliveries_FilteredByStatus(t *testing.T) {
+	resp := httptest.NewRecorder()
+	c, r, ormInstance := SetupWebhookEngine(resp)
+	db := ormInstance.GetDB()
+	webhook, err := entity.CreateWebhook(db, testWebhookOwner, "https://example.com/hook", []string{}, testWebhookSecret)
+	assert.NoError(t, err)
+	db.Create(&entity.WebhookDelivery{WebhookID: webhook.ID, NotificationSequence: 1, Status: entity.WebhookDeliveryDelivered})
+	db.Create(&entity.WebhookDelivery{WebhookID: webhook.ID, NotificationSequence: 2, Status: entity.WebhookDeliveryFailed, Attempts: 2, LastError: "timeout"})
+	c.Request, _ = http.NewRequest(http.MethodGet, "/1/deliveries?status=failed", nil)
+
+	r.ServeHTTP(resp, c.Request)
+
+	if assert.Equal(t, http.StatusOK, resp.Code) {
+		actual := []*api.WebhookDeliveryResponse{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &actual))
+		if assert.Len(t, actual, 1) {
+			assert.Equal(t, uint64(2), actual[0].Sequence)
+			assert.Equal(t, 2, actual[0].Attempts)
+			assert.Equal(t, "timeout", actual[0].LastError)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package bitcoind
+
+import (
+	"encoding/json"
+	"p2pderivatives-oracle/internal/datafeed"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+)
+
+// metricsProvider provider name of the datafeed metrics
+const metricsProvider = "bitcoind"
+
+// NewClient returns a new bitcoind JSON-RPC Client (not initialized)
+func NewClient(config *Config) *Client {
+	return &Client{
+		config:      config,
+		initialized: false,
+	}
+}
+
+// NewDataFeeds returns an initialized client for each asset symbol configured with an on-chain metric
+func NewDataFeeds(config *Config) map[string]datafeed.DataFeed {
+	client := NewClient(config)
+	client.Initialize()
+	feeds := map[string]datafeed.DataFeed{}
+	for asset := range config.Assets {
+		feeds[strings.ToLower(asset)] = client
+	}
+	return feeds
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      string        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type blockHeader struct {
+	Hash   string `json:"hash"`
+	Height int64  `json:"height"`
+	Time   int64  `json:"time"`
+}
+
+type blockStats struct {
+	FeeRatePercentiles []float64 `json:"feerate_percentiles"`
+}
+
+// Client represents a bitcoind JSON-RPC client serving on-chain metrics as asset values
+// the value at a given time is the metric of the last block whose timestamp is before or at this time
+// (block timestamps are assumed to be increasing, which bitcoin only enforces loosely)
+type Client struct {
+	datafeed.DataFeed
+	config      *Config
+	httpClient  *resty.Client
+	initialized bool
+}
+
+// Initialize initializes the http client
+func (c *Client) Initialize() {
+	c.httpClient = resty.New()
+	c.httpClient.SetHostURL(c.config.URL)
+	c.httpClient.SetHeader("Content-Type", "application/json")
+	if c.config.User != "" {
+		c.httpClient.SetBasicAuth(c.config.User, c.config.Password)
+	}
+	c.initialized = true
+}
+
+// IsInitialized returns true if the Client has been initialized
+func (c *Client) IsInitialized() bool {
+	return c.initialized
+}
+
+// FindCurrentAssetPrice returns the metric of the asset at the chain tip
+func (c *Client) FindCurrentAssetPrice(assetID string, currency string) (*float64, error) {
+	assetConfig, err := c.findAssetConfig(assetID)
+	if err != nil {
+		return nil, err
+	}
+	var height int64
+	if err := c.call("getblockcount", &height); err != nil {
+		return nil, err
+	}
+	return c.findMetric(assetConfig, height)
+}
+
+// FindPastAssetPrice returns the metric of the asset at the last block mined before or at the date
+func (c *Client) FindPastAssetPrice(assetID string, currency string, date time.Time) (*float64, error) {
+	assetConfig, err := c.findAssetConfig(assetID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(date) {
+		return nil, errors.New("date should be before now")
+	}
+	height, err := c.findHeightAt(date)
+	if err != nil {
+		return nil, err
+	}
+	return c.findMetric(assetConfig, height)
+}
+
+// FindPastAssetCandles returns one candle per block mined between from (excluded) and to (included),
+// the candle value being the metric of the block
+func (c *Client) FindPastAssetCandles(assetID string, currency string, from time.Time, to time.Time) ([]datafeed.Candle, error) {
+	assetConfig, err := c.findAssetConfig(assetID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(to) {
+		return nil, errors.New("date should be before now")
+	}
+	if !from.Before(to) {
+		return nil, errors.New("from date should be before to date")
+	}
+	fromHeight, err := c.findHeightAt(from)
+	if err != nil {
+		return nil, err
+	}
+	toHeight, err := c.findHeightAt(to)
+	if err != nil {
+		return nil, err
+	}
+	candles := []datafeed.Candle{}
+	for height := fromHeight + 1; height <= toHeight; height++ {
+		header, err := c.findBlockHeader(height)
+		if err != nil {
+			return nil, err
+		}
+		value, err := c.findMetric(assetConfig, height)
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, datafeed.Candle{
+			Time:  time.Unix(header.Time, 0).UTC(),
+			Open:  *value,
+			High:  *value,
+			Low:   *value,
+			Close: *value,
+		})
+	}
+	return candles, nil
+}
+
+func (c *Client) findAssetConfig(assetID string) (*AssetConfig, error) {
+	if !c.IsInitialized() {
+		return nil, errors.New("bitcoind client is not initialized")
+	}
+	for asset, assetConfig := range c.config.Assets {
+		if strings.EqualFold(asset, assetID) {
+			return &assetConfig, nil
+		}
+	}
+	return nil, errors.Errorf("no on-chain metric configured for asset %s", assetID)
+}
+
+func (c *Client) findMetric(assetConfig *AssetConfig, height int64) (*float64, error) {
+	var value float64
+	switch assetConfig.Metric {
+	case MetricBlockHeight:
+		value = float64(height)
+	case MetricMedianFeeRate:
+		stats := &blockStats{}
+		if err := c.call("getblockstats", stats, height, []string{"feerate_percentiles"}); err != nil {
+			return nil, err
+		}
+		// percentiles are 10th, 25th, 50th, 75th and 90th
+		if len(stats.FeeRatePercentiles) != 5 {
+			return nil, errors.Errorf("invalid fee rate percentiles for block %d", height)
+		}
+		value = stats.FeeRatePercentiles[2]
+	case MetricHashrate:
+		blocks := assetConfig.HashrateBlocks
+		if blocks == 0 {
+			blocks = defaultHashrateBlocks
+		}
+		if err := c.call("getnetworkhashps", &value, blocks, height); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unknown on-chain metric %s", assetConfig.Metric)
+	}
+	return &value, nil
+}
+
+// findHeightAt returns the height of the last block with a timestamp before or at the date
+func (c *Client) findHeightAt(date time.Time) (int64, error) {
+	var tip int64
+	if err := c.call("getblockcount", &tip); err != nil {
+		return 0, err
+	}
+	var searchErr error
+	// index of the first block after the date
+	index := sort.Search(int(tip)+1, func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		header, err := c.findBlockHeader(int64(i))
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return header.Time > date.Unix()
+	})
+	if searchErr != nil {
+		return 0, searchErr
+	}
+	if index == 0 {
+		return 0, errors.Errorf("no block mined before %s", date.String())
+	}
+	return int64(index - 1), nil
+}
+
+func (c *Client) findBlockHeader(height int64) (*blockHeader, error) {
+	var hash string
+	if err := c.call("getblockhash", &hash, height); err != nil {
+		return nil, err
+	}
+	header := &blockHeader{}
+	if err := c.call("getblockheader", header, hash); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func (c *Client) call(method string, result interface{}, params ...interface{}) (err error) {
+	started := time.Now()
+	defer func() { datafeed.ObserveCall(metricsProvider, started, err) }()
+	if params == nil {
+		params = []interface{}{}
+	}
+	req := c.httpClient.R()
+	req.SetBody(&rpcRequest{JSONRPC: "1.0", ID: "p2pdoracle", Method: method, Params: params})
+	resp, err := req.Post("/")
+	if err != nil {
+		return errors.WithMessagef(err, "error while sending %s request to bitcoind", method)
+	}
+	rpcResp := &rpcResponse{}
+	// bitcoind returns the json-rpc error with a http error status
+	if err := json.Unmarshal(resp.Body(), rpcResp); err != nil {
+		return errors.Errorf("invalid bitcoind %s response, status %d: %s", method, resp.StatusCode(), resp.String())
+	}
+	if rpcResp.Error != nil {
+		return errors.Errorf("bitcoind %s error %d: %s", method, rpcResp.Error.Code, rpcResp.Error.Message)
+	}
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return errors.WithMessagef(err, "invalid bitcoind %s result", method)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package bitcoind
+
+const (
+	// MetricBlockHeight height of the last block mined at the requested time
+	MetricBlockHeight = "blockheight"
+	// MetricMedianFeeRate median fee rate (sat/vB) of the last block mined at the requested time
+	MetricMedianFeeRate = "medianfeerate"
+	// MetricHashrate estimated network hashrate (H/s) at the last block mined at the requested time
+	MetricHashrate = "hashrate"
+
+	defaultHashrateBlocks = 120
+)
+
+// Config represents the bitcoind datafeed configuration
+type Config struct {
+	// URL bitcoind JSON-RPC endpoint (ex: http://localhost:8332)
+	URL      string `configkey:"bitcoind.url" validate:"required"`
+	User     string `configkey:"bitcoind.user"`
+	Password string `configkey:"bitcoind.password"`
+	// Assets on-chain metric served for each asset symbol
+	Assets map[string]AssetConfig `configkey:"bitcoind.assets" validate:"required,min=1,dive"`
+}
+
+// AssetConfig represents the on-chain metric served for an asset
+type AssetConfig struct {
+	Metric string `configkey:"metric" validate:"required,oneof=blockheight medianfeerate hashrate"`
+	// HashrateBlocks number of blocks used to estimate the hashrate (120 if not set)
+	HashrateBlocks int `configkey:"hashrateBlocks" validate:"min=0"`
+}
@@ -0,0 +1,187 @@
+package bitcoind_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/bitcoind"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTip = 100
+
+var genesisDate = time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+// NewTestServer returns a bitcoind JSON-RPC stand-in with a block mined every ten minutes from genesisDate
+func NewTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pass", password)
+		req := struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		var result interface{}
+		switch req.Method {
+		case "getblockcount":
+			result = testTip
+		case "getblockhash":
+			var height int64
+			json.Unmarshal(req.Params[0], &height)
+			if height < 0 || height > testTip {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, `{"result":null,"error":{"code":-8,"message":"Block height out of range"}}`)
+				return
+			}
+			result = fmt.Sprintf("hash%d", height)
+		case "getblockheader":
+			var hash string
+			json.Unmarshal(req.Params[0], &hash)
+			height, _ := strconv.ParseInt(strings.TrimPrefix(hash, "hash"), 10, 64)
+			result = map[string]interface{}{
+				"hash":   hash,
+				"height": height,
+				"time":   genesisDate.Add(time.Duration(height) * 10 * time.Minute).Unix(),
+			}
+		case "getblockstats":
+			var height float64
+			json.Unmarshal(req.Params[0], &height)
+			result = map[string]interface{}{"feerate_percentiles": []float64{1, 2, height, 200, 300}}
+		case "getnetworkhashps":
+			var blocks, height float64
+			json.Unmarshal(req.Params[0], &blocks)
+			json.Unmarshal(req.Params[1], &height)
+			result = blocks*1000 + height
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"result":null,"error":{"code":-32601,"message":"Method not found"}}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result, "error": nil})
+	}))
+}
+
+func NewTestClient(url string) *bitcoind.Client {
+	return bitcoind.NewClient(&bitcoind.Config{
+		URL:      url,
+		User:     "user",
+		Password: "pass",
+		Assets: map[string]bitcoind.AssetConfig{
+			"blockheight": {Metric: bitcoind.MetricBlockHeight},
+			"feerate":     {Metric: bitcoind.MetricMedianFeeRate},
+			"hashrate":    {Metric: bitcoind.MetricHashrate, HashrateBlocks: 10},
+		},
+	})
+}
+
+func TestClient_FindPastAssetPrice_NotInitialized_ReturnsError(t *testing.T) {
+	client := NewTestClient("http://localhost")
+	assert.False(t, client.IsInitialized())
+	val, err := client.FindPastAssetPrice("blockheight", "blocks", genesisDate)
+	assert.Error(t, err)
+	assert.Nil(t, val)
+}
+
+func TestClient_FindCurrentAssetPrice_ReturnsTipHeight(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	client := NewTestClient(server.URL)
+	client.Initialize()
+
+	val, err := client.FindCurrentAssetPrice("blockheight", "blocks")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(testTip), *val)
+}
+
+func TestClient_FindPastAssetPrice_ReturnsMetricOfLastBlockBeforeDate(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	client := NewTestClient(server.URL)
+	client.Initialize()
+	date := genesisDate.Add(425 * time.Minute)
+
+	tests := []struct {
+		asset    string
+		expected float64
+	}{
+		{"blockheight", 42},
+		{"BlockHeight", 42},
+		{"feerate", 42},
+		{"hashrate", 10042},
+	}
+	for _, test := range tests {
+		val, err := client.FindPastAssetPrice(test.asset, "unit", date)
+		if assert.NoError(t, err, test.asset) {
+			assert.Equal(t, test.expected, *val, test.asset)
+		}
+	}
+}
+
+func TestClient_FindPastAssetPrice_AtBlockTime_ReturnsBlock(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	client := NewTestClient(server.URL)
+	client.Initialize()
+
+	val, err := client.FindPastAssetPrice("blockheight", "blocks", genesisDate.Add(100*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, *val)
+}
+
+func TestClient_FindPastAssetPrice_BeforeGenesis_ReturnsError(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	client := NewTestClient(server.URL)
+	client.Initialize()
+
+	val, err := client.FindPastAssetPrice("blockheight", "blocks", genesisDate.Add(-time.Minute))
+	assert.Error(t, err)
+	assert.Nil(t, val)
+}
+
+func TestClient_FindPastAssetPrice_UnknownAsset_ReturnsError(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	client := NewTestClient(server.URL)
+	client.Initialize()
+
+	val, err := client.FindPastAssetPrice("btc", "usd", genesisDate)
+	assert.Error(t, err)
+	assert.Nil(t, val)
+}
+
+func TestClient_FindPastAssetCandles_ReturnsOneCandlePerBlock(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	client := NewTestClient(server.URL)
+	client.Initialize()
+
+	candles, err := client.FindPastAssetCandles("feerate", "satvb", genesisDate.Add(100*time.Minute), genesisDate.Add(135*time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, candles, 3) {
+		for i, candle := range candles {
+			assert.Equal(t, genesisDate.Add(time.Duration(11+i)*10*time.Minute), candle.Time)
+			assert.Equal(t, float64(11+i), candle.Close)
+		}
+	}
+}
+
+func TestNewDataFeeds_ReturnsFeedPerAsset(t *testing.T) {
+	feeds := bitcoind.NewDataFeeds(&bitcoind.Config{
+		URL: "http://localhost",
+		Assets: map[string]bitcoind.AssetConfig{
+			"BlockHeight": {Metric: bitcoind.MetricBlockHeight},
+		},
+	})
+	assert.Len(t, feeds, 1)
+	assert.Contains(t, feeds, "blockheight")
+}
@@ -3,6 +3,7 @@ package cryptocompare
 import (
 	"fmt"
 	"p2pderivatives-oracle/internal/datafeed"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,6 +17,13 @@ const (
 	pricePastHourRoute   = "/v2/histohour"
 	pricePastMinuteRoute = "/v2/histominute"
 	limitPastResponse    = 1
+	// maxPastCandlesLimit maximum limit accepted by the cryptocompare history routes
+	maxPastCandlesLimit = 2000
+	// maxPastCandlesPages maximum number of requests made for a candle window
+	maxPastCandlesPages = 10
+
+	// metricsProvider provider name of the datafeed metrics
+	metricsProvider = "cryptocompare"
 )
 
 // NewClient returns a new CryptoCompare Client (not initialized)
@@ -34,8 +42,12 @@ type apiPastPriceResponse struct {
 		// Data data response with time (like one element for each minute/hour)
 		// the requested time should be the last element
 		Data []struct {
-			Time  int64   `json:"time"`
-			Close float64 `json:"close"`
+			Time       int64   `json:"time"`
+			Open       float64 `json:"open"`
+			High       float64 `json:"high"`
+			Low        float64 `json:"low"`
+			Close      float64 `json:"close"`
+			VolumeFrom float64 `json:"volumefrom"`
 		} `json:"Data"`
 	} `json:"Data"`
 }
@@ -117,13 +129,86 @@ func (c *Client) FindPastAssetPrice(assetID string, currency string, date time.T
 	return &value, nil
 }
 
+// FindPastAssetCandles sends GET requests to the CryptoCompare API to retrieve the candles of an asset
+// covering the window between from and to, with minute precision during seven days and hour precision after.
+// The cryptocompare candles are timestamped with their open time, so the candles opening in [from, to) are returned
+// (the candle opening at to covers the trading after it), the window is paged by maxPastCandlesLimit candles
+func (c *Client) FindPastAssetCandles(assetID string, currency string, from time.Time, to time.Time) ([]datafeed.Candle, error) {
+	now := time.Now()
+	if now.Before(to) {
+		return nil, errors.New("date should be before now")
+	}
+	if !from.Before(to) {
+		return nil, errors.New("from date should be before to date")
+	}
+	precisionRoute, precision := pricePastMinuteRoute, time.Minute
+	// before seven days (minute precision are stored only seven days in cryptocompare)
+	if from.Before(now.Add(-time.Hour * 168)) {
+		precisionRoute, precision = pricePastHourRoute, time.Hour
+	}
+	// each request returns limit + 1 candles
+	if maxWindow := time.Duration(maxPastCandlesPages*(maxPastCandlesLimit+1)) * precision; to.Sub(from) > maxWindow {
+		return nil, errors.Errorf("candle window should not be longer than %s with %s precision", maxWindow, precision)
+	}
+
+	candles := []datafeed.Candle{}
+	// open time of the last candle of the page, the pages are requested backward from to
+	end := to.Add(-precision)
+	for !end.Before(from) {
+		limit := int64(end.Sub(from) / precision)
+		if limit > maxPastCandlesLimit {
+			limit = maxPastCandlesLimit
+		}
+		if limit < limitPastResponse {
+			limit = limitPastResponse
+		}
+		route := fmt.Sprintf(
+			precisionRoute+"?fsym=%s&tsym=%s&toTs=%d&limit=%d",
+			assetID,
+			currency,
+			end.Unix(),
+			limit)
+		resp, err := c.getAssetPrice(route, apiPastPriceResponse{})
+		if err != nil {
+			return nil, err
+		}
+
+		res := resp.Result().(*apiPastPriceResponse)
+		for _, data := range res.Data.Data {
+			candleTime := time.Unix(data.Time, 0).UTC()
+			if candleTime.Before(from) || !candleTime.Before(to) || candleTime.After(end) {
+				continue
+			}
+			candles = append(candles, datafeed.Candle{
+				Time:   candleTime,
+				Open:   data.Open,
+				High:   data.High,
+				Low:    data.Low,
+				Close:  data.Close,
+				Volume: data.VolumeFrom,
+			})
+		}
+		end = end.Add(-time.Duration(limit+1) * precision)
+	}
+	sort.Slice(candles, func(i, j int) bool {
+		return candles[i].Time.Before(candles[j].Time)
+	})
+	return candles, nil
+}
+
 func (c *Client) getAssetPrice(route string, resultType interface{}) (*resty.Response, error) {
 	if !c.IsInitialized() {
 		return nil, errors.New("crypto compare client is not initialized")
 	}
 	req := c.httpClient.R()
 	req.SetResult(resultType)
+	started := time.Now()
 	resp, err := req.Get(route)
+	if err == nil && resp.IsError() {
+		datafeed.ObserveCall(metricsProvider, started, errors.Errorf("status %d", resp.StatusCode()))
+	} else {
+		datafeed.ObserveCall(metricsProvider, started, err)
+	}
 	if err != nil {
 		return nil, errors.WithMessagef(err, "error while sending a request to cryptocompare api %v", resp.String())
 	}
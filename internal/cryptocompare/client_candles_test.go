@@ -0,0 +1,99 @@
+package cryptocompare_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/cryptocompare"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewTestHistoryServer returns a server of the cryptocompare history routes with a candle per minute or hour,
+// whose close is its open time (unix seconds), and the list of the requested limits
+func NewTestHistoryServer(t *testing.T) (*httptest.Server, *[]int) {
+	limits := &[]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		precision := int64(60)
+		if r.URL.Path == "/v2/histohour" {
+			precision = 3600
+		}
+		toTs, err := strconv.ParseInt(r.URL.Query().Get("toTs"), 10, 64)
+		require.NoError(t, err)
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		require.NoError(t, err)
+		if limit > 2000 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		*limits = append(*limits, limit)
+		last := toTs - toTs%precision
+		data := []map[string]interface{}{}
+		for i := int64(limit); i >= 0; i-- {
+			candleTime := last - i*precision
+			data = append(data, map[string]interface{}{"time": candleTime, "close": float64(candleTime)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"Data": map[string]interface{}{"Data": data}})
+	}))
+	return server, limits
+}
+
+func NewTestHistoryClient(server *httptest.Server) *cryptocompare.Client {
+	client := cryptocompare.NewClient(&cryptocompare.Config{APIBaseURL: server.URL, APIKey: "key"})
+	client.Initialize()
+	return client
+}
+
+func TestClient_FindPastAssetCandles_LongWindow_PagesAndExcludesLastCandle(t *testing.T) {
+	server, limits := NewTestHistoryServer(t)
+	defer server.Close()
+	client := NewTestHistoryClient(server)
+	to := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	from := to.Add(-60 * time.Hour)
+
+	candles, err := client.FindPastAssetCandles("btc", "usd", from, to)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{2000, 1598}, *limits)
+	if assert.Len(t, candles, 3600) {
+		assert.Equal(t, from, candles[0].Time)
+		assert.Equal(t, to.Add(-time.Minute), candles[len(candles)-1].Time)
+		for i := 1; i < len(candles); i++ {
+			assert.Equal(t, time.Minute, candles[i].Time.Sub(candles[i-1].Time))
+		}
+	}
+}
+
+func TestClient_FindPastAssetCandles_ShortWindow_ReturnsCandlesOpeningInWindow(t *testing.T) {
+	server, limits := NewTestHistoryServer(t)
+	defer server.Close()
+	client := NewTestHistoryClient(server)
+	to := time.Now().UTC().Truncate(time.Minute).Add(-time.Hour)
+	from := to.Add(-5 * time.Minute)
+
+	candles, err := client.FindPastAssetCandles("btc", "usd", from, to)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{4}, *limits)
+	if assert.Len(t, candles, 5) {
+		assert.Equal(t, float64(from.Unix()), candles[0].Close)
+		assert.Equal(t, to.Add(-time.Minute), candles[4].Time)
+	}
+}
+
+func TestClient_FindPastAssetCandles_TooLongWindow_ReturnsError(t *testing.T) {
+	server, limits := NewTestHistoryServer(t)
+	defer server.Close()
+	client := NewTestHistoryClient(server)
+	to := time.Now().UTC().Add(-time.Hour)
+
+	_, err := client.FindPastAssetCandles("btc", "usd", to.Add(-1000*24*time.Hour), to)
+
+	assert.Error(t, err)
+	assert.Empty(t, *limits)
+}
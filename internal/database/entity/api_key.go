@@ -0,0 +1,169 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// ErrAPIKeyAlreadyExists is returned when trying to issue an api key with the name of an existing one
+var ErrAPIKeyAlreadyExists = errors.New("api key already exists")
+
+// APIKey represents the db model of an issued api key, only the hash of the key is stored
+type APIKey struct {
+	ID        uint `gorm:"primary_key"`
+	CreatedAt time.Time
+	// Name identity of the key holder, used as principal
+	Name string `gorm:"unique_index;not null"`
+	// SHA256 hex encoded sha256 of the key
+	SHA256 string `gorm:"unique_index;not null"`
+	Role   string `gorm:"not null"`
+	// RatePerMinute and Burst token bucket of the key, the api defaults are used if 0
+	RatePerMinute int
+	Burst         int
+	// DailyQuota maximum number of announcements per UTC day, unlimited if 0
+	DailyQuota int
+	RevokedAt  *time.Time
+}
+
+// IsRevoked returns true if the api key has been revoked
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// CreateAPIKey will create an api key, ErrAPIKeyAlreadyExists is returned if a key with the same name exists
+func CreateAPIKey(db *gorm.DB, key *APIKey) error {
+	tx := db.Begin()
+	count := 0
+	if err := tx.Model(&APIKey{}).Where("name = ?", key.Name).Count(&count).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count > 0 {
+		tx.Rollback()
+		return errors.Wrapf(ErrAPIKeyAlreadyExists, "api key %s", key.Name)
+	}
+	if err := tx.Create(key).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// FindAPIKeys returns all the issued api keys (including the revoked ones) sorted by name
+func FindAPIKeys(db *gorm.DB) ([]APIKey, error) {
+	keys := []APIKey{}
+	err := db.Order("name").Find(&keys).Error
+	return keys, err
+}
+
+// FindActiveAPIKey will try to find the api key which is not revoked from its hash
+func FindActiveAPIKey(db *gorm.DB, sha256 string) (*APIKey, error) {
+	key := &APIKey{}
+	err := db.Where("sha256 = ? AND revoked_at IS NULL", sha256).First(key).Error
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// RevokeAPIKey will revoke the api key of the name, gorm.ErrRecordNotFound is returned if no active key has the name
+func RevokeAPIKey(db *gorm.DB, name string, now time.Time) error {
+	res := db.Model(&APIKey{}).Where("name = ? AND revoked_at IS NULL", name).Update("revoked_at", now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// APIUsage represents the db model of the daily usage counters of a principal
+type APIUsage struct {
+	Principal string `gorm:"primary_key"`
+	// Day UTC day of the counters (at midnight)
+	Day           time.Time `gorm:"primary_key"`
+	Requests      int       `gorm:"not null;default:0"`
+	Announcements int       `gorm:"not null;default:0"`
+	Attestations  int       `gorm:"not null;default:0"`
+}
+
+// UsageDay returns the day of the usage counters of a time
+func UsageDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// AddAPIUsage will add the counters of the usage to the stored counters of its principal and day
+func AddAPIUsage(db *gorm.DB, usage *APIUsage) error {
+	increment := func() (bool, error) {
+		res := db.Model(&APIUsage{}).
+			Where("principal = ? AND day = ?", usage.Principal, usage.Day).
+			Updates(map[string]interface{}{
+				"requests":      gorm.Expr("requests + ?", usage.Requests),
+				"announcements": gorm.Expr("announcements + ?", usage.Announcements),
+				"attestations":  gorm.Expr("attestations + ?", usage.Attestations),
+			})
+		return res.RowsAffected == 1, res.Error
+	}
+	if updated, err := increment(); err != nil || updated {
+		return err
+	}
+	if err := db.Create(usage).Error; err != nil {
+		// a concurrent request may have created the counters first
+		if updated, errIncrement := increment(); errIncrement != nil || !updated {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReserveAPIUsageAnnouncement will count an announcement of a principal for a day if its counters are below the quota
+// (unlimited if 0), the check and the increment being a single statement. Returns false if the quota is reached
+func ReserveAPIUsageAnnouncement(db *gorm.DB, principal string, day time.Time, quota int) (bool, error) {
+	increment := func() (bool, error) {
+		req := db.Model(&APIUsage{}).Where("principal = ? AND day = ?", principal, day)
+		if quota > 0 {
+			req = req.Where("announcements < ?", quota)
+		}
+		res := req.Update("announcements", gorm.Expr("announcements + 1"))
+		return res.RowsAffected == 1, res.Error
+	}
+	if reserved, err := increment(); err != nil || reserved {
+		return reserved, err
+	}
+	if _, err := FindAPIUsage(db, principal, day); !gorm.IsRecordNotFoundError(err) {
+		// the counters exist and are at the quota
+		return false, err
+	}
+	if err := db.Create(&APIUsage{Principal: principal, Day: day, Announcements: 1}).Error; err != nil {
+		// a concurrent request may have created the counters first
+		if reserved, errIncrement := increment(); errIncrement != nil || reserved {
+			return reserved, errIncrement
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// FindAPIUsage will try to find the usage counters of a principal for a day
+func FindAPIUsage(db *gorm.DB, principal string, day time.Time) (*APIUsage, error) {
+	usage := &APIUsage{}
+	err := db.Where("principal = ? AND day = ?", principal, day).First(usage).Error
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// FindAPIUsages returns the usage counters since a day, optionally filtered by principal, most recent first
+func FindAPIUsages(db *gorm.DB, principal string, from time.Time) ([]APIUsage, error) {
+	usages := []APIUsage{}
+	req := db.Where("day >= ?", from)
+	if principal != "" {
+		req = req.Where("principal = ?", principal)
+	}
+	err := req.Order("day DESC").Order("principal").Find(&usages).Error
+	return usages, err
+}
@@ -0,0 +1,83 @@
+package entity_test
+
+import (
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_APIKey_CreateFindRevoke(t *testing.T) {
+	db := test.NewOrm(&entity.APIKey{}).GetDB()
+	key := &entity.APIKey{Name: "client", SHA256: "aa", Role: "reader", DailyQuota: 10}
+	require.NoError(t, entity.CreateAPIKey(db, key))
+
+	err := entity.CreateAPIKey(db, &entity.APIKey{Name: "client", SHA256: "bb", Role: "reader"})
+	assert.True(t, errors.Is(err, entity.ErrAPIKeyAlreadyExists))
+
+	found, err := entity.FindActiveAPIKey(db, "aa")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "client", found.Name)
+		assert.Equal(t, 10, found.DailyQuota)
+	}
+
+	require.NoError(t, entity.RevokeAPIKey(db, "client", time.Now()))
+	_, err = entity.FindActiveAPIKey(db, "aa")
+	assert.True(t, gorm.IsRecordNotFoundError(err))
+	assert.True(t, gorm.IsRecordNotFoundError(entity.RevokeAPIKey(db, "client", time.Now())))
+
+	keys, err := entity.FindAPIKeys(db)
+	if assert.NoError(t, err) && assert.Len(t, keys, 1) {
+		assert.True(t, keys[0].IsRevoked())
+	}
+}
+
+func Test_AddAPIUsage_IncrementsDailyCounters(t *testing.T) {
+	db := test.NewOrm(&entity.APIUsage{}).GetDB()
+	now := time.Date(2020, time.May, 12, 8, 30, 0, 0, time.UTC)
+	day := entity.UsageDay(now)
+	assert.Equal(t, time.Date(2020, time.May, 12, 0, 0, 0, 0, time.UTC), day)
+
+	require.NoError(t, entity.AddAPIUsage(db, &entity.APIUsage{Principal: "client", Day: day, Requests: 1, Announcements: 2}))
+	require.NoError(t, entity.AddAPIUsage(db, &entity.APIUsage{Principal: "client", Day: day, Requests: 1, Attestations: 1}))
+	require.NoError(t, entity.AddAPIUsage(db, &entity.APIUsage{Principal: "client", Day: day.AddDate(0, 0, -1), Requests: 1}))
+
+	usage, err := entity.FindAPIUsage(db, "client", day)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, usage.Requests)
+		assert.Equal(t, 2, usage.Announcements)
+		assert.Equal(t, 1, usage.Attestations)
+	}
+	usages, err := entity.FindAPIUsages(db, "client", day.AddDate(0, 0, -1))
+	if assert.NoError(t, err) && assert.Len(t, usages, 2) {
+		assert.Equal(t, day, usages[0].Day.UTC())
+	}
+}
+
+func Test_ReserveAPIUsageAnnouncement_StopsAtQuota(t *testing.T) {
+	db := test.NewOrm(&entity.APIUsage{}).GetDB()
+	day := entity.UsageDay(time.Date(2020, time.May, 12, 8, 30, 0, 0, time.UTC))
+
+	for i := 0; i < 2; i++ {
+		reserved, err := entity.ReserveAPIUsageAnnouncement(db, "client", day, 2)
+		assert.NoError(t, err)
+		assert.True(t, reserved)
+	}
+	reserved, err := entity.ReserveAPIUsageAnnouncement(db, "client", day, 2)
+	assert.NoError(t, err)
+	assert.False(t, reserved, "quota reached")
+	reserved, err = entity.ReserveAPIUsageAnnouncement(db, "client", day.AddDate(0, 0, 1), 2)
+	assert.NoError(t, err)
+	assert.True(t, reserved, "next day")
+
+	usage, err := entity.FindAPIUsage(db, "client", day)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, usage.Announcements)
+		assert.Equal(t, 0, usage.Requests)
+	}
+}
@@ -2,15 +2,34 @@ package entity
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
 )
 
-// Asset represents an asset currency
+// ErrAssetAlreadyExists is returned when trying to create an asset which already exists
+var ErrAssetAlreadyExists = errors.New("asset already exists")
+
+// Asset represents an asset currency and how its events are scheduled and attested
 type Asset struct {
 	Base
 	AssetID     string `gorm:"primarykey" gorm:"uniqueIndex"`
 	Description string
-	DLCData     []DLCData `gorm:"foreignkey:AssetID"`
+	// Symbol asset symbol requested to the datafeed
+	Symbol      string
+	Currency    string
+	HasDecimals bool
+	StartDate   time.Time
+	Frequency   time.Duration
+	Range       time.Duration
+	// EventTypes comma separated list of the supported event types
+	EventTypes string
+	// Settlement method used to compute the attested value
+	Settlement string
+	// Index json encoded index composition versions (index assets only)
+	Index   string
+	DLCData []DLCData `gorm:"foreignkey:AssetID"`
 }
 
 // FindAsset will try to find in the db the asset corresponding to the id
@@ -24,3 +43,64 @@ func FindAsset(db *gorm.DB, assetID string) (*Asset, error) {
 	err := db.Where("asset_id = ?", assetID).First(filterCondition).Error
 	return filterCondition, err
 }
+
+// FindAssets returns all the assets sorted by id
+func FindAssets(db *gorm.DB) ([]Asset, error) {
+	assets := []Asset{}
+	err := db.Order("asset_id").Find(&assets).Error
+	return assets, err
+}
+
+// CreateAsset will create an asset, ErrAssetAlreadyExists is returned if an asset with the same id exists
+func CreateAsset(db *gorm.DB, asset *Asset) error {
+	tx := db.Begin()
+	count := 0
+	if err := tx.Model(&Asset{}).Where("asset_id = ?", asset.AssetID).Count(&count).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count > 0 {
+		tx.Rollback()
+		return errors.Wrapf(ErrAssetAlreadyExists, "asset %s", asset.AssetID)
+	}
+	if err := tx.Create(asset).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// UpdateAsset will replace the configuration of an existing asset
+func UpdateAsset(db *gorm.DB, asset *Asset) (*Asset, error) {
+	res := db.Model(&Asset{}).Where("asset_id = ?", asset.AssetID).Updates(map[string]interface{}{
+		"description":  asset.Description,
+		"symbol":       asset.Symbol,
+		"currency":     asset.Currency,
+		"has_decimals": asset.HasDecimals,
+		"start_date":   asset.StartDate,
+		"frequency":    asset.Frequency,
+		"range":        asset.Range,
+		"event_types":  asset.EventTypes,
+		"settlement":   asset.Settlement,
+		"index":        asset.Index,
+	})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return FindAsset(db, asset.AssetID)
+}
+
+// DeleteAsset will soft delete an asset, its events are kept
+func DeleteAsset(db *gorm.DB, assetID string) error {
+	res := db.Where("asset_id = ?", assetID).Delete(&Asset{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -4,8 +4,10 @@ import (
 	"p2pderivatives-oracle/internal/database/entity"
 	"p2pderivatives-oracle/test"
 	"testing"
+	"time"
 
 	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -28,3 +30,43 @@ func Test_FindAsset_NotPresent_ReturnsRecordNotFoundError(t *testing.T) {
 	_, err := entity.FindAsset(db, "invalid asset")
 	assert.EqualError(t, err, gorm.ErrRecordNotFound.Error())
 }
+
+func Test_CreateAsset_AlreadyExists_ReturnsError(t *testing.T) {
+	db := test.NewOrm(&entity.Asset{}).GetDB()
+	assert.NoError(t, entity.CreateAsset(db, &entity.Asset{AssetID: "test"}))
+
+	err := entity.CreateAsset(db, &entity.Asset{AssetID: "test"})
+	assert.True(t, errors.Is(err, entity.ErrAssetAlreadyExists))
+}
+
+func Test_UpdateAsset_Present_ReplacesConfiguration(t *testing.T) {
+	db := test.NewOrm(&entity.Asset{}).GetDB()
+	assert.NoError(t, entity.CreateAsset(db, &entity.Asset{AssetID: "test", Symbol: "btc", HasDecimals: true, Frequency: time.Hour}))
+
+	actual, err := entity.UpdateAsset(db, &entity.Asset{AssetID: "test", Symbol: "eth", Frequency: time.Minute})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "eth", actual.Symbol)
+		assert.False(t, actual.HasDecimals)
+		assert.Equal(t, time.Minute, actual.Frequency)
+	}
+	_, err = entity.UpdateAsset(db, &entity.Asset{AssetID: "unknown"})
+	assert.True(t, gorm.IsRecordNotFoundError(err))
+}
+
+func Test_DeleteAsset_Present_NotFoundAnymore(t *testing.T) {
+	db := test.NewOrm(&entity.Asset{}).GetDB()
+	assert.NoError(t, entity.CreateAsset(db, &entity.Asset{AssetID: "b"}))
+	assert.NoError(t, entity.CreateAsset(db, &entity.Asset{AssetID: "a"}))
+
+	assert.NoError(t, entity.DeleteAsset(db, "b"))
+
+	_, err := entity.FindAsset(db, "b")
+	assert.True(t, gorm.IsRecordNotFoundError(err))
+	assets, err := entity.FindAssets(db)
+	if assert.NoError(t, err) && assert.Len(t, assets, 1) {
+		assert.Equal(t, "a", assets[0].AssetID)
+	}
+	assert.True(t, gorm.IsRecordNotFoundError(entity.DeleteAsset(db, "b")))
+	// can be created again once deleted
+	assert.NoError(t, entity.CreateAsset(db, &entity.Asset{AssetID: "b"}))
+}
@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// AuditRecord represents the db model of a privileged api request and the identity of its principal
+type AuditRecord struct {
+	ID        uint `gorm:"primary_key"`
+	CreatedAt time.Time
+	// Principal identity of the caller (api key name, token subject or certificate common name)
+	Principal  string `gorm:"index;not null"`
+	AuthMethod string `gorm:"not null"`
+	Role       string
+	Method     string `gorm:"not null"`
+	Path       string `gorm:"not null"`
+	// Status http status of the response (denied requests are recorded too)
+	Status    int
+	RequestID string
+}
+
+// CreateAuditRecord will create an audit record
+func CreateAuditRecord(db *gorm.DB, record *AuditRecord) error {
+	return db.Create(record).Error
+}
+
+// FindAuditRecords returns the most recent audit records first, optionally filtered by principal
+func FindAuditRecords(db *gorm.DB, principal string, limit int) ([]AuditRecord, error) {
+	records := []AuditRecord{}
+	req := db
+	if principal != "" {
+		req = req.Where("principal = ?", principal)
+	}
+	err := req.Order("id DESC").Limit(limit).Find(&records).Error
+	return records, err
+}
@@ -0,0 +1,29 @@
+package entity_test
+
+import (
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FindAuditRecords_ReturnsLatestFirstFiltered(t *testing.T) {
+	db := test.NewOrm(&entity.AuditRecord{}).GetDB()
+	for _, principal := range []string{"alice", "bob", "alice"} {
+		require.NoError(t, entity.CreateAuditRecord(db, &entity.AuditRecord{
+			Principal: principal, AuthMethod: "apikey", Method: "POST", Path: "/admin/asset", Status: 201,
+		}))
+	}
+
+	all, err := entity.FindAuditRecords(db, "", 2)
+	assert.NoError(t, err)
+	if assert.Len(t, all, 2) {
+		assert.Equal(t, uint(3), all[0].ID)
+		assert.Equal(t, "bob", all[1].Principal)
+	}
+	filtered, err := entity.FindAuditRecords(db, "alice", 10)
+	assert.NoError(t, err)
+	assert.Len(t, filtered, 2)
+}
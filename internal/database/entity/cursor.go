@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// Cursor represents the db model of the position of a named consumer in the event notifications sequence
+type Cursor struct {
+	Base
+	Name     string `gorm:"primary_key"`
+	Sequence uint64
+}
+
+// FindCursorSequence will retrieve the sequence of the named cursor (0 if it does not exist)
+func FindCursorSequence(db *gorm.DB, name string) (uint64, error) {
+	cursor := &Cursor{}
+	err := db.Where(&Cursor{Name: name}).First(cursor).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return cursor.Sequence, nil
+}
+
+// SaveCursor will create or update the sequence of the named cursor
+func SaveCursor(db *gorm.DB, name string, sequence uint64) error {
+	cursor := &Cursor{}
+	err := db.Where(&Cursor{Name: name}).Assign(&Cursor{Sequence: sequence}).FirstOrCreate(cursor).Error
+	return err
+}
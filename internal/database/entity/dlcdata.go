@@ -1,11 +1,21 @@
 package entity
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/jinzhu/gorm"
 )
 
+const (
+	// DLCDataStatusAnnounced the event rvalue has been generated, not attested yet
+	DLCDataStatusAnnounced = "announced"
+	// DLCDataStatusFailed the last attestation attempt failed, it will be retried
+	DLCDataStatusFailed = "failed"
+	// DLCDataStatusAttested the event has been signed
+	DLCDataStatusAttested = "attested"
+)
+
 // DLCData represents the db model of the oracle data rvalue/signature relative an asset
 type DLCData struct {
 	Base
@@ -13,9 +23,21 @@ type DLCData struct {
 	AssetID       string    `gorm:"primary_key"`
 	EventType     string    `gorm:"primary_key"`
 	Rvalue        string    `gorm:"unique;not null"`
-	Signature     string
+	Signature     string    `gorm:"index"`
 	Value         string
-	Asset         Asset `gorm:"association_foreignkey:AssetID" json:"-"`
+	// SettlementMethod method used to compute the value from the datafeed
+	SettlementMethod string
+	// IndexVersion version of the index composition used to compute the value (index assets only)
+	IndexVersion string
+	// Status attestation state of the event (announced, failed or attested)
+	Status string `gorm:"index"`
+	// Attempts number of failed attestation attempts
+	Attempts int
+	// NextAttemptAt date before which the attestation should not be retried after a failure
+	NextAttemptAt *time.Time
+	// LastError error of the last failed attestation attempt
+	LastError string
+	Asset     Asset `gorm:"association_foreignkey:AssetID" json:"-"`
 
 	// TODO should be stored somewhere secure
 	Kvalue string `gorm:"unique;not null" json:"-"`
@@ -28,6 +50,7 @@ func (m *DLCData) IsSigned() bool {
 
 // CreateDLCData will try to create a DLCData with a new Rvalue corresponding to an asset and publishDate
 // if already in db, it will return the value found with no error
+// an announcement notification is created in the same transaction
 func CreateDLCData(db *gorm.DB, assetID string, publishDate time.Time, eventType string, signingk string, rvalue string) (*DLCData, error) {
 	tx := db.Begin()
 
@@ -37,9 +60,17 @@ func CreateDLCData(db *gorm.DB, assetID string, publishDate time.Time, eventType
 		EventType:     eventType,
 		Kvalue:        signingk,
 		Rvalue:        rvalue,
+		Status:        DLCDataStatusAnnounced,
 	}
 
-	tx = tx.Create(newDLCData)
+	if err := tx.Create(newDLCData).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := createEventNotification(tx, EventNotificationAnnounced, newDLCData); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	err := tx.Commit().Error
 	if err != nil {
@@ -49,6 +80,48 @@ func CreateDLCData(db *gorm.DB, assetID string, publishDate time.Time, eventType
 	return newDLCData, nil
 }
 
+// CreateDLCDataIfNotExists inserts a new dlcData unless one is already stored with the same primary key
+// (INSERT ... ON CONFLICT DO NOTHING), returning the stored dlcData and false in that case.
+// The other errors (including an rvalue or kvalue collision) are returned
+func CreateDLCDataIfNotExists(db *gorm.DB, assetID string, publishDate time.Time, eventType string, signingk string, rvalue string) (*DLCData, bool, error) {
+	tx := db.Begin()
+
+	newDLCData := &DLCData{
+		PublishedDate: publishDate,
+		AssetID:       assetID,
+		EventType:     eventType,
+		Kvalue:        signingk,
+		Rvalue:        rvalue,
+		Status:        DLCDataStatusAnnounced,
+	}
+
+	insert := tx.Set("gorm:insert_option", "ON CONFLICT (published_date, asset_id, event_type) DO NOTHING").Create(newDLCData)
+	// on postgres the insert is read with its RETURNING clause, which returns no row if the insert is ignored
+	ignored := insert.Error == sql.ErrNoRows || (insert.Error == nil && insert.RowsAffected == 0)
+	if insert.Error != nil && !ignored {
+		tx.Rollback()
+		return nil, false, insert.Error
+	}
+	if ignored {
+		// created concurrently, the insert is ignored
+		tx.Rollback()
+		stored, err := FindDLCDataPublishedAt(db, assetID, publishDate, eventType)
+		if err != nil {
+			return nil, false, err
+		}
+		return stored, false, nil
+	}
+	if err := createEventNotification(tx, EventNotificationAnnounced, newDLCData); err != nil {
+		tx.Rollback()
+		return nil, false, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, false, err
+	}
+	return newDLCData, true, nil
+}
+
 // FindDLCDataPublishedNear will try to retrieve the oldest dlcData which has been published between nearTime and rangeD
 // limit
 func FindDLCDataPublishedNear(db *gorm.DB, assetID string, eventType string, nearTime time.Time, rangeD time.Duration) (*DLCData, error) {
@@ -118,28 +191,59 @@ func FindDLCDataWithRValue(db *gorm.DB, rvalue string) (*DLCData, error) {
 	return dlcData, nil
 }
 
+// FindDLCDataWithSignature will try to retrieve asset dlcData with the specific signature
+// from database
+func FindDLCDataWithSignature(db *gorm.DB, signature string) (*DLCData, error) {
+	dlcData := &DLCData{}
+	if signature == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	err := db.Where("signature = ?", signature).First(dlcData).Error
+	if err != nil {
+		return nil, err
+	}
+	return dlcData, nil
+}
+
 // UpdateDLCDataSignatureAndValue will try to update signature and value of the DLCData if it exists
 // and if the DLCdata is not already signed
 func UpdateDLCDataSignatureAndValue(db *gorm.DB, assetID string, publishDate time.Time, eventType string, sig string, value string) (*DLCData, error) {
+	return UpdateDLCDataAttestation(db, assetID, publishDate, eventType, DLCData{Signature: sig, Value: value})
+}
+
+// UpdateDLCDataAttestation will try to update the attestation fields (signature, value and valuation details)
+// of the DLCData if it exists and if the DLCdata is not already signed
+// an attestation notification is created in the same transaction
+func UpdateDLCDataAttestation(db *gorm.DB, assetID string, publishDate time.Time, eventType string, attestation DLCData) (*DLCData, error) {
 	tx := db.Begin()
 	filterCondition := &DLCData{
 		AssetID:       assetID,
 		EventType:     eventType,
 		PublishedDate: publishDate,
 	}
-	tx = tx.Model(filterCondition)
+	update := tx.Model(filterCondition)
 	// ensure that the signature and value are empty, doesn't work in using filterCondition (ignored)
-	tx = tx.Where("signature = ?", "").Where("value = ?", "")
+	update = update.Where("signature = ?", "").Where("value = ?", "")
 
-	tx = tx.Updates(DLCData{Signature: sig, Value: value})
-	if err := tx.Error; err != nil {
+	update = update.Updates(DLCData{
+		Signature:        attestation.Signature,
+		Value:            attestation.Value,
+		SettlementMethod: attestation.SettlementMethod,
+		IndexVersion:     attestation.IndexVersion,
+		Status:           DLCDataStatusAttested,
+	})
+	if err := update.Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
-	if tx.RowsAffected == 0 {
+	if update.RowsAffected == 0 {
 		tx.Rollback()
 	} else {
+		if err := createEventNotification(tx, EventNotificationAttested, filterCondition); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 		err := tx.Commit().Error
 		if err != nil {
 			return nil, err
@@ -148,3 +252,197 @@ func UpdateDLCDataSignatureAndValue(db *gorm.DB, assetID string, publishDate tim
 
 	return FindDLCDataPublishedAt(db, assetID, publishDate, eventType)
 }
+
+// CreateDLCDataBatch will create all the DLCData in a single transaction
+// if one of them cannot be created (like already in db), none is created
+// an announcement notification is created for each of them
+func CreateDLCDataBatch(db *gorm.DB, dlcDataList []*DLCData) error {
+	tx := db.Begin()
+	for _, dlcData := range dlcDataList {
+		if dlcData.Status == "" {
+			dlcData.Status = DLCDataStatusAnnounced
+		}
+		if err := tx.Create(dlcData).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := createEventNotification(tx, EventNotificationAnnounced, dlcData); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// FindDLCDataPublishedDates will retrieve the publish dates of the asset dlcData
+// published between from and to (included) sorted by date
+func FindDLCDataPublishedDates(db *gorm.DB, assetID string, eventType string, from time.Time, to time.Time) ([]time.Time, error) {
+	dlcDataList := []DLCData{}
+	filterCondition := &DLCData{
+		AssetID:   assetID,
+		EventType: eventType,
+	}
+	req := db.Where(filterCondition)
+	req = req.Where("published_date BETWEEN ? AND ?", from, to)
+	req = req.Order("published_date ASC")
+	err := req.Select("published_date").Find(&dlcDataList).Error
+	if err != nil {
+		return nil, err
+	}
+	dates := make([]time.Time, len(dlcDataList))
+	for i, dlcData := range dlcDataList {
+		dates[i] = dlcData.PublishedDate
+	}
+	return dates, nil
+}
+
+// DLCDataQuery represents the filters of a dlcData listing of an asset, zero values are not filtered
+type DLCDataQuery struct {
+	AssetID   string
+	EventType string
+	// From and To bounds of the publish date (included)
+	From time.Time
+	To   time.Time
+	// Signed selects the signed (true) or unsigned (false) dlcData
+	Signed *bool
+	// AfterPublishedDate and AfterEventType position after which the listing starts (keyset pagination)
+	AfterPublishedDate time.Time
+	AfterEventType     string
+}
+
+// FindDLCDataList will retrieve the asset dlcData matching the query sorted by publish date and event type
+func FindDLCDataList(db *gorm.DB, query *DLCDataQuery, limit int) ([]DLCData, error) {
+	req := db.Where("asset_id = ?", query.AssetID)
+	if query.EventType != "" {
+		req = req.Where("event_type = ?", query.EventType)
+	}
+	if !query.From.IsZero() {
+		req = req.Where("published_date >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		req = req.Where("published_date <= ?", query.To)
+	}
+	if query.Signed != nil {
+		if *query.Signed {
+			req = req.Where("signature <> ?", "")
+		} else {
+			req = req.Where("signature = ?", "")
+		}
+	}
+	if !query.AfterPublishedDate.IsZero() {
+		req = req.Where(
+			"published_date > ? OR (published_date = ? AND event_type > ?)",
+			query.AfterPublishedDate, query.AfterPublishedDate, query.AfterEventType)
+	}
+	dlcDataList := []DLCData{}
+	err := req.Order("published_date ASC").Order("event_type ASC").Limit(limit).Find(&dlcDataList).Error
+	if err != nil {
+		return nil, err
+	}
+	return dlcDataList, nil
+}
+
+// AddDLCDataIndexes adds the dlcData indexes which cannot be declared on the model
+// (the primary key starting with the publish date, listings of an asset need an index starting with the asset)
+func AddDLCDataIndexes(db *gorm.DB) error {
+	if err := db.Model(&DLCData{}).AddIndex("idx_dlc_data_asset_published_date", "asset_id", "published_date").Error; err != nil {
+		return err
+	}
+	// last attestation date per asset (metrics)
+	return db.Model(&DLCData{}).AddIndex("idx_dlc_data_asset_updated_at", "asset_id", "updated_at").Error
+}
+
+// BackfillDLCDataStatus sets the status of the dlcData stored before the status was introduced,
+// attested if signed and announced otherwise
+func BackfillDLCDataStatus(db *gorm.DB) error {
+	withoutStatus := db.Unscoped().Model(&DLCData{}).Where("status IS NULL OR status = ?", "")
+	if err := withoutStatus.Where("signature <> ?", "").UpdateColumn("status", DLCDataStatusAttested).Error; err != nil {
+		return err
+	}
+	return withoutStatus.Where("signature IS NULL OR signature = ?", "").UpdateColumn("status", DLCDataStatusAnnounced).Error
+}
+
+// FindDLCDataToAttest will retrieve the unsigned dlcData of the assets published before or at a date
+// and which are not waiting for a retry delay at now, sorted by publish date
+func FindDLCDataToAttest(db *gorm.DB, assetIDs []string, publishedBefore time.Time, now time.Time, limit int) ([]DLCData, error) {
+	dlcDataList := []DLCData{}
+	req := db.Where("signature = ?", "")
+	req = req.Where("asset_id IN (?)", assetIDs)
+	req = req.Where("published_date <= ?", publishedBefore)
+	req = req.Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now)
+	req = req.Order("published_date ASC").Limit(limit)
+	err := req.Find(&dlcDataList).Error
+	if err != nil {
+		return nil, err
+	}
+	return dlcDataList, nil
+}
+
+// CountOverdueDLCData returns the number of unsigned dlcData published before the given date per asset
+// (the assets without overdue dlcData are not included)
+func CountOverdueDLCData(db *gorm.DB, publishedBefore time.Time) (map[string]int, error) {
+	rows, err := db.Model(&DLCData{}).
+		Select("asset_id, count(*)").
+		Where("signature = ?", "").
+		Where("published_date < ?", publishedBefore).
+		Group("asset_id").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var assetID string
+		var count int
+		if err := rows.Scan(&assetID, &count); err != nil {
+			return nil, err
+		}
+		counts[assetID] = count
+	}
+	return counts, rows.Err()
+}
+
+// FindLastAttestationDates returns the date of the last attestation per asset
+// (the assets without attested dlcData are not included)
+func FindLastAttestationDates(db *gorm.DB) (map[string]time.Time, error) {
+	last := db.Model(&DLCData{}).
+		Select("asset_id, max(updated_at) AS updated_at").
+		Where("signature <> ?", "").
+		Group("asset_id").
+		SubQuery()
+	// joined back to read the date from its column, as the aggregate loses its type on sqlite
+	rows, err := db.Model(&DLCData{}).
+		Select("dlc_data.asset_id, dlc_data.updated_at").
+		Joins("JOIN ? AS last ON last.asset_id = dlc_data.asset_id AND last.updated_at = dlc_data.updated_at", last).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dates := map[string]time.Time{}
+	for rows.Next() {
+		var assetID string
+		var date time.Time
+		if err := rows.Scan(&assetID, &date); err != nil {
+			return nil, err
+		}
+		dates[assetID] = date
+	}
+	return dates, rows.Err()
+}
+
+// UpdateDLCDataAttestationFailure will record a failed attestation attempt of an unsigned DLCData
+func UpdateDLCDataAttestationFailure(db *gorm.DB, assetID string, publishDate time.Time, eventType string, attempts int, nextAttemptAt time.Time, lastError string) error {
+	filterCondition := &DLCData{
+		AssetID:       assetID,
+		EventType:     eventType,
+		PublishedDate: publishDate,
+	}
+	return db.Model(filterCondition).Where("signature = ?", "").Updates(map[string]interface{}{
+		"status":          DLCDataStatusFailed,
+		"attempts":        attempts,
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	}).Error
+}
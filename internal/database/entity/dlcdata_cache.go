@@ -0,0 +1,114 @@
+package entity
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var dlcDataCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "oracle_dlc_data_cache_requests_total",
+	Help: "Number of DLC data lookups by publish date served by the in-process cache (hit) or the database (miss).",
+}, []string{"result"})
+
+// DLCDataCache is an in-process LRU cache of the signed DLC data in front of FindDLCDataPublishedAt,
+// the signed DLC data never change while the unsigned ones are always read from the database
+// a nil cache reads every DLC data from the database
+type DLCDataCache struct {
+	mutex   sync.Mutex
+	size    int
+	entries *list.List
+	index   map[dlcDataKey]*list.Element
+}
+
+// dlcDataKey represents the primary key of a DLC data
+type dlcDataKey struct {
+	assetID     string
+	eventType   string
+	publishDate int64
+}
+
+func newDLCDataKey(assetID string, publishDate time.Time, eventType string) dlcDataKey {
+	return dlcDataKey{assetID: assetID, eventType: eventType, publishDate: publishDate.UnixNano()}
+}
+
+// NewDLCDataCache returns a cache of at most size signed DLC data, nil (no cache) if size is not positive
+func NewDLCDataCache(size int) *DLCDataCache {
+	if size <= 0 {
+		return nil
+	}
+	return &DLCDataCache{
+		size:    size,
+		entries: list.New(),
+		index:   map[dlcDataKey]*list.Element{},
+	}
+}
+
+// FindDLCDataPublishedAt returns the cached signed DLC data at the publish date,
+// or reads it from the database and caches it if signed
+func (c *DLCDataCache) FindDLCDataPublishedAt(db *gorm.DB, assetID string, publishDate time.Time, eventType string) (*DLCData, error) {
+	if c == nil {
+		return FindDLCDataPublishedAt(db, assetID, publishDate, eventType)
+	}
+	if dlcData := c.get(newDLCDataKey(assetID, publishDate, eventType)); dlcData != nil {
+		dlcDataCacheRequests.WithLabelValues("hit").Inc()
+		return dlcData, nil
+	}
+	dlcDataCacheRequests.WithLabelValues("miss").Inc()
+	dlcData, err := FindDLCDataPublishedAt(db, assetID, publishDate, eventType)
+	if err != nil {
+		return nil, err
+	}
+	c.Add(dlcData)
+	return dlcData, nil
+}
+
+// Add caches a DLC data if it is signed, evicting the least recently used one if the cache is full
+func (c *DLCDataCache) Add(dlcData *DLCData) {
+	if c == nil || !dlcData.IsSigned() {
+		return
+	}
+	key := newDLCDataKey(dlcData.AssetID, dlcData.PublishedDate, dlcData.EventType)
+	cached := *dlcData
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.index[key]; ok {
+		element.Value = &cached
+		c.entries.MoveToFront(element)
+		return
+	}
+	c.index[key] = c.entries.PushFront(&cached)
+	if c.entries.Len() > c.size {
+		oldest := c.entries.Back()
+		c.entries.Remove(oldest)
+		evicted := oldest.Value.(*DLCData)
+		delete(c.index, newDLCDataKey(evicted.AssetID, evicted.PublishedDate, evicted.EventType))
+	}
+}
+
+// Len returns the number of cached DLC data
+func (c *DLCDataCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.entries.Len()
+}
+
+// get returns a copy of the cached DLC data, nil if not cached
+func (c *DLCDataCache) get(key dlcDataKey) *DLCData {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.index[key]
+	if !ok {
+		return nil
+	}
+	c.entries.MoveToFront(element)
+	dlcData := *element.Value.(*DLCData)
+	return &dlcData
+}
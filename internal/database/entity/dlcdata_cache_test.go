@@ -0,0 +1,70 @@
+package entity_test
+
+import (
+	"fmt"
+	"p2pderivatives-oracle/internal/database/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DLCDataCache_Signed_ReadFromCache(t *testing.T) {
+	db := GetInitializedDB()
+	cache := entity.NewDLCDataCache(10)
+	date := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	_, err := entity.CreateDLCData(db, "test", date, "digits", "kvalue", "rvalue")
+	require.NoError(t, err)
+
+	// unsigned, not cached
+	_, err = cache.FindDLCDataPublishedAt(db, "test", date, "digits")
+	require.NoError(t, err)
+	assert.Equal(t, 0, cache.Len())
+
+	signed, err := entity.UpdateDLCDataSignatureAndValue(db, "test", date, "digits", "signature", "9000")
+	require.NoError(t, err)
+	cache.Add(signed)
+	db.Delete(&entity.DLCData{}, "asset_id = ?", "test")
+
+	actual, err := cache.FindDLCDataPublishedAt(db, "test", date, "digits")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "signature", actual.Signature)
+	}
+}
+
+func Test_DLCDataCache_Full_EvictsLeastRecentlyUsed(t *testing.T) {
+	db := GetInitializedDB()
+	cache := entity.NewDLCDataCache(2)
+	date := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		publishDate := date.Add(time.Duration(i) * time.Hour)
+		_, err := entity.CreateDLCData(db, "test", publishDate, "digits", fmt.Sprint("kvalue", i), fmt.Sprint("rvalue", i))
+		require.NoError(t, err)
+		_, err = entity.UpdateDLCDataSignatureAndValue(db, "test", publishDate, "digits", "signature", "9000")
+		require.NoError(t, err)
+	}
+
+	for _, hours := range []int{0, 1, 0, 2} {
+		_, err := cache.FindDLCDataPublishedAt(db, "test", date.Add(time.Duration(hours)*time.Hour), "digits")
+		require.NoError(t, err)
+	}
+	db.Delete(&entity.DLCData{}, "asset_id = ?", "test")
+
+	assert.Equal(t, 2, cache.Len())
+	_, err := cache.FindDLCDataPublishedAt(db, "test", date, "digits")
+	assert.NoError(t, err)
+	_, err = cache.FindDLCDataPublishedAt(db, "test", date.Add(time.Hour), "digits")
+	assert.Error(t, err)
+}
+
+func Test_DLCDataCache_Nil_ReadFromDatabase(t *testing.T) {
+	db := GetInitializedDB()
+	cache := entity.NewDLCDataCache(0)
+
+	_, err := cache.FindDLCDataPublishedAt(db, "test", time.Now(), "digits")
+
+	assert.Nil(t, cache)
+	assert.Error(t, err)
+	assert.Equal(t, 0, cache.Len())
+}
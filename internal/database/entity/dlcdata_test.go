@@ -1,6 +1,7 @@
 package entity_test
 
 import (
+	"fmt"
 	"p2pderivatives-oracle/internal/database/entity"
 	"p2pderivatives-oracle/test"
 	"testing"
@@ -11,7 +12,7 @@ import (
 )
 
 func GetInitializedDB() *gorm.DB {
-	db := test.NewOrm(&entity.Asset{}, &entity.DLCData{}).GetDB()
+	db := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{}).GetDB()
 	db.Create(&entity.Asset{AssetID: "test"})
 	return db
 }
@@ -51,6 +52,45 @@ func Test_CreateDLCData_Present_ReturnsError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_CreateDLCDataIfNotExists_NotPresent_CreatesDLCData(t *testing.T) {
+	db := GetInitializedDB()
+	now := time.Now().UTC()
+
+	actual, created, err := entity.CreateDLCDataIfNotExists(db, "test", now, "digits", "kvalue", "rvalue")
+
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "rvalue", actual.Rvalue)
+	notifications, _ := entity.FindEventNotificationsAfter(db, 0, time.Now(), 10)
+	assert.Len(t, notifications, 1)
+}
+
+func Test_CreateDLCDataIfNotExists_Present_ReturnsStoredDLCData(t *testing.T) {
+	db := GetInitializedDB()
+	now := time.Now().UTC()
+	_, err := entity.CreateDLCData(db, "test", now, "digits", "kvalue1", "rvalue1")
+	assert.NoError(t, err)
+
+	actual, created, err := entity.CreateDLCDataIfNotExists(db, "test", now, "digits", "kvalue2", "rvalue2")
+
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "rvalue1", actual.Rvalue)
+	notifications, _ := entity.FindEventNotificationsAfter(db, 0, time.Now(), 10)
+	assert.Len(t, notifications, 1)
+}
+
+func Test_CreateDLCDataIfNotExists_RvalueCollision_ReturnsError(t *testing.T) {
+	db := GetInitializedDB()
+	now := time.Now().UTC()
+	_, err := entity.CreateDLCData(db, "test", now, "digits", "kvalue1", "rvalue1")
+	assert.NoError(t, err)
+
+	_, _, err = entity.CreateDLCDataIfNotExists(db, "test", now.Add(time.Hour), "digits", "kvalue2", "rvalue1")
+
+	assert.Error(t, err)
+}
+
 func Test_FindDLCDataPublishedNear_NotPresent_ReturnsRecordNotFoundError(t *testing.T) {
 	db := GetInitializedDB()
 	now := time.Now()
@@ -162,3 +202,124 @@ func assertDLCDataEqual(assertSub *assert.Assertions, expected *entity.DLCData,
 	assertSub.Equal(expected.Signature, actual.Signature)
 	assertSub.Equal(expected.Value, actual.Value)
 }
+
+func Test_CreateDLCDataBatch_OnePresent_CreatesNone(t *testing.T) {
+	db := GetInitializedDB()
+	date := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	_, err := entity.CreateDLCData(db, "test", date, "digits", "k0", "r0")
+	assert.NoError(t, err)
+
+	err = entity.CreateDLCDataBatch(db, []*entity.DLCData{
+		{AssetID: "test", PublishedDate: date.Add(time.Hour), EventType: "digits", Kvalue: "k1", Rvalue: "r1"},
+		{AssetID: "test", PublishedDate: date, EventType: "digits", Kvalue: "k2", Rvalue: "r2"},
+	})
+	assert.Error(t, err)
+	dates, err := entity.FindDLCDataPublishedDates(db, "test", "digits", date, date.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, dates, 1)
+}
+
+func Test_FindDLCDataPublishedDates_ReturnsSortedDatesInRange(t *testing.T) {
+	db := GetInitializedDB()
+	date := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	err := entity.CreateDLCDataBatch(db, []*entity.DLCData{
+		{AssetID: "test", PublishedDate: date.Add(2 * time.Hour), EventType: "digits", Kvalue: "k2", Rvalue: "r2"},
+		{AssetID: "test", PublishedDate: date.Add(time.Hour), EventType: "digits", Kvalue: "k1", Rvalue: "r1"},
+		{AssetID: "test", PublishedDate: date.Add(time.Hour), EventType: "above(1)", Kvalue: "k3", Rvalue: "r3"},
+		{AssetID: "test", PublishedDate: date.Add(3 * time.Hour), EventType: "digits", Kvalue: "k4", Rvalue: "r4"},
+	})
+	assert.NoError(t, err)
+
+	dates, err := entity.FindDLCDataPublishedDates(db, "test", "digits", date, date.Add(2*time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, dates, 2) {
+		assert.True(t, date.Add(time.Hour).Equal(dates[0]))
+		assert.True(t, date.Add(2*time.Hour).Equal(dates[1]))
+	}
+}
+
+func Test_BackfillDLCDataStatus_WithoutStatus_SetsStatusFromSignature(t *testing.T) {
+	db := GetInitializedDB()
+	date := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// stored before the status was introduced
+	db.Create(&entity.DLCData{AssetID: "test", PublishedDate: date, EventType: "digits", Kvalue: "k0", Rvalue: "r0", Signature: "s"})
+	db.Create(&entity.DLCData{AssetID: "test", PublishedDate: date.Add(time.Hour), EventType: "digits", Kvalue: "k1", Rvalue: "r1"})
+	db.Create(&entity.DLCData{AssetID: "test", PublishedDate: date.Add(2 * time.Hour), EventType: "digits", Kvalue: "k2", Rvalue: "r2", Status: entity.DLCDataStatusFailed})
+
+	assert.NoError(t, entity.BackfillDLCDataStatus(db))
+
+	expected := []string{entity.DLCDataStatusAttested, entity.DLCDataStatusAnnounced, entity.DLCDataStatusFailed}
+	for i, status := range expected {
+		dlcData, err := entity.FindDLCDataPublishedAt(db, "test", date.Add(time.Duration(i)*time.Hour), "digits")
+		if assert.NoError(t, err) {
+			assert.Equal(t, status, dlcData.Status, dlcData.Rvalue)
+		}
+	}
+}
+
+func Test_FindDLCDataList_FiltersAndPaginates(t *testing.T) {
+	db := GetInitializedDB()
+	assert.NoError(t, entity.AddDLCDataIndexes(db))
+	date := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, eventType := range []string{"digits", "above(10)", "digits", "digits"} {
+		publishDate := date.Add(time.Duration(i/2) * time.Hour)
+		if i == 3 {
+			publishDate = date.Add(5 * time.Hour)
+		}
+		_, err := entity.CreateDLCData(db, "test", publishDate, eventType, fmt.Sprint("k", i), fmt.Sprint("r", i))
+		assert.NoError(t, err)
+	}
+	_, err := entity.UpdateDLCDataAttestation(db, "test", date, "digits", entity.DLCData{Signature: "s", Value: "1"})
+	assert.NoError(t, err)
+
+	// sorted by publish date and event type
+	all, err := entity.FindDLCDataList(db, &entity.DLCDataQuery{AssetID: "test"}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"r1", "r0", "r2", "r3"}, rvalues(all))
+
+	// keyset pagination
+	page, err := entity.FindDLCDataList(db, &entity.DLCDataQuery{
+		AssetID:            "test",
+		AfterPublishedDate: date,
+		AfterEventType:     "above(10)",
+	}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"r0", "r2"}, rvalues(page))
+
+	signed := false
+	filtered, err := entity.FindDLCDataList(db, &entity.DLCDataQuery{
+		AssetID:   "test",
+		EventType: "digits",
+		From:      date,
+		To:        date.Add(time.Hour),
+		Signed:    &signed,
+	}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"r2"}, rvalues(filtered))
+}
+
+func rvalues(dlcDataList []entity.DLCData) []string {
+	values := make([]string, len(dlcDataList))
+	for i, dlcData := range dlcDataList {
+		values[i] = dlcData.Rvalue
+	}
+	return values
+}
+
+func Test_FindDLCDataWithSignature_ReturnsSignedOnly(t *testing.T) {
+	db := GetInitializedDB()
+	date := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	_, err := entity.CreateDLCData(db, "test", date, "digits", "k1", "r1")
+	assert.NoError(t, err)
+	_, err = entity.CreateDLCData(db, "test", date.Add(time.Hour), "digits", "k2", "r2")
+	assert.NoError(t, err)
+	_, err = entity.UpdateDLCDataAttestation(db, "test", date, "digits", entity.DLCData{Signature: "s1", Value: "1"})
+	assert.NoError(t, err)
+
+	actual, err := entity.FindDLCDataWithSignature(db, "s1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "r1", actual.Rvalue)
+	}
+	_, err = entity.FindDLCDataWithSignature(db, "")
+	assert.EqualError(t, err, gorm.ErrRecordNotFound.Error())
+}
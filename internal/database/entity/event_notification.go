@@ -0,0 +1,121 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// EventNotificationAnnounced notification of an event announcement (rvalue generated)
+	EventNotificationAnnounced = "announced"
+	// EventNotificationAttested notification of an event attestation (value signed)
+	EventNotificationAttested = "attested"
+)
+
+// EventNotification represents the db model of an event announcement or attestation notification,
+// created in the same transaction as the event change (outbox) with a monotonic sequence
+type EventNotification struct {
+	Sequence      uint64 `gorm:"primary_key;AUTO_INCREMENT"`
+	CreatedAt     time.Time
+	Type          string    `gorm:"not null"`
+	AssetID       string    `gorm:"not null"`
+	EventType     string    `gorm:"not null"`
+	PublishedDate time.Time `gorm:"not null"`
+}
+
+// FindEventNotificationsAfter will retrieve the notifications with a sequence strictly greater than the given one
+// sorted by sequence, up to the first gap in the sequences (see contiguousEventNotifications)
+func FindEventNotificationsAfter(db *gorm.DB, sequence uint64, settledBefore time.Time, limit int) ([]EventNotification, error) {
+	notifications := []EventNotification{}
+	err := db.Where("sequence > ?", sequence).Order("sequence ASC").Limit(limit).Find(&notifications).Error
+	if err != nil {
+		return nil, err
+	}
+	return contiguousEventNotifications(notifications, sequence, settledBefore), nil
+}
+
+// contiguousEventNotifications returns the notifications (sorted by sequence) before the first gap after the sequence.
+// The sequences are taken when the notifications are inserted but become visible when their transaction commits,
+// a missing sequence may be a notification still to be committed, so a consumer moving its cursor past it would never read it.
+// A gap is only skipped once the following notification has been created before settledBefore,
+// the missing sequence being then considered as rolled back
+func contiguousEventNotifications(notifications []EventNotification, sequence uint64, settledBefore time.Time) []EventNotification {
+	for i, notification := range notifications {
+		if notification.Sequence != sequence+1 && !notification.CreatedAt.Before(settledBefore) {
+			return notifications[:i]
+		}
+		sequence = notification.Sequence
+	}
+	return notifications
+}
+
+// FindEventNotificationsAfterFiltered will retrieve the notifications with a sequence strictly greater than the given one
+// up to the first gap in the sequences (see contiguousEventNotifications) of the given assets and event types (all if empty)
+// sorted by sequence, limit being the number of notifications read before filtering.
+// The sequence of the last notification read is returned as the position from which to continue
+func FindEventNotificationsAfterFiltered(
+	db *gorm.DB,
+	sequence uint64,
+	assetIDs []string,
+	eventTypes []string,
+	settledBefore time.Time,
+	limit int) ([]EventNotification, uint64, error) {
+	read, err := FindEventNotificationsAfter(db, sequence, settledBefore, limit)
+	if err != nil {
+		return nil, sequence, err
+	}
+	notifications := []EventNotification{}
+	for _, notification := range read {
+		if containsOrEmpty(assetIDs, notification.AssetID) && containsOrEmpty(eventTypes, notification.EventType) {
+			notifications = append(notifications, notification)
+		}
+		sequence = notification.Sequence
+	}
+	return notifications, sequence, nil
+}
+
+func containsOrEmpty(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// FindLastEventNotificationSequence will retrieve the greatest notification sequence, 0 if there is none
+func FindLastEventNotificationSequence(db *gorm.DB) (uint64, error) {
+	notification := &EventNotification{}
+	err := db.Order("sequence DESC").First(notification).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return notification.Sequence, nil
+}
+
+// FindEventNotification will try to retrieve a notification by sequence
+// from database
+func FindEventNotification(db *gorm.DB, sequence uint64) (*EventNotification, error) {
+	notification := &EventNotification{}
+	err := db.Where("sequence = ?", sequence).First(notification).Error
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+func createEventNotification(tx *gorm.DB, notificationType string, dlcData *DLCData) error {
+	return tx.Create(&EventNotification{
+		Type:          notificationType,
+		AssetID:       dlcData.AssetID,
+		EventType:     dlcData.EventType,
+		PublishedDate: dlcData.PublishedDate,
+	}).Error
+}
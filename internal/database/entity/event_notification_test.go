@@ -0,0 +1,91 @@
+package entity_test
+
+import (
+	"p2pderivatives-oracle/internal/database/entity"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FindEventNotificationsAfterFiltered_ReturnsMatchingSorted(t *testing.T) {
+	db := GetInitializedDB()
+	db.Create(&entity.Asset{AssetID: "other"})
+	date := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	_, err := entity.CreateDLCData(db, "test", date, "digits", "k1", "r1")
+	assert.NoError(t, err)
+	_, err = entity.CreateDLCData(db, "other", date, "digits", "k2", "r2")
+	assert.NoError(t, err)
+	_, err = entity.CreateDLCData(db, "test", date, "bigsize", "k3", "r3")
+	assert.NoError(t, err)
+	_, err = entity.UpdateDLCDataAttestation(db, "test", date, "digits", entity.DLCData{Signature: "s", Value: "1"})
+	assert.NoError(t, err)
+
+	actual, next, err := entity.FindEventNotificationsAfterFiltered(db, 1, []string{"test"}, []string{"digits"}, time.Now(), 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), next)
+	if assert.Len(t, actual, 1) {
+		assert.Equal(t, uint64(4), actual[0].Sequence)
+		assert.Equal(t, entity.EventNotificationAttested, actual[0].Type)
+	}
+	all, _, err := entity.FindEventNotificationsAfterFiltered(db, 0, nil, nil, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, all, 4)
+}
+
+func Test_FindEventNotificationsAfter_Gap_StopsBeforeGapUntilSettled(t *testing.T) {
+	db := GetInitializedDB()
+	now := time.Now()
+	for _, sequence := range []uint64{1, 2, 4} {
+		notification := &entity.EventNotification{
+			Sequence:      sequence,
+			CreatedAt:     now,
+			Type:          entity.EventNotificationAnnounced,
+			AssetID:       "test",
+			EventType:     "digits",
+			PublishedDate: now,
+		}
+		assert.NoError(t, db.Create(notification).Error)
+	}
+
+	pending, err := entity.FindEventNotificationsAfter(db, 0, now.Add(-time.Minute), 10)
+	assert.NoError(t, err)
+	settled, err := entity.FindEventNotificationsAfter(db, 0, now.Add(time.Second), 10)
+	assert.NoError(t, err)
+
+	assert.Len(t, pending, 2)
+	assert.Len(t, settled, 3)
+}
+
+func Test_FindEventNotificationsAfterFiltered_NoneMatching_ReturnsReadPosition(t *testing.T) {
+	db := GetInitializedDB()
+	date := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	_, err := entity.CreateDLCData(db, "test", date, "digits", "k1", "r1")
+	assert.NoError(t, err)
+	_, err = entity.CreateDLCData(db, "test", date.Add(time.Hour), "digits", "k2", "r2")
+	assert.NoError(t, err)
+
+	actual, next, err := entity.FindEventNotificationsAfterFiltered(db, 0, []string{"other"}, nil, time.Now(), 10)
+
+	assert.NoError(t, err)
+	assert.Empty(t, actual)
+	assert.Equal(t, uint64(2), next)
+}
+
+func Test_FindLastEventNotificationSequence_ReturnsGreatest(t *testing.T) {
+	db := GetInitializedDB()
+	last, err := entity.FindLastEventNotificationSequence(db)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), last)
+
+	date := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	_, err = entity.CreateDLCData(db, "test", date, "digits", "k1", "r1")
+	assert.NoError(t, err)
+	_, err = entity.CreateDLCData(db, "test", date.Add(time.Hour), "digits", "k2", "r2")
+	assert.NoError(t, err)
+
+	last, err = entity.FindLastEventNotificationSequence(db)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), last)
+}
@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// ErrIndexCompositionMismatch is returned when trying to save an index composition version
+// which is already stored with a different content
+var ErrIndexCompositionMismatch = errors.New("index composition version already stored with a different content")
+
+// IndexComposition represents the db model of an index composition version,
+// kept so that past attestations of an index asset remain explainable
+type IndexComposition struct {
+	Base
+	AssetID       string `gorm:"primary_key"`
+	Version       string `gorm:"primary_key"`
+	EffectiveFrom time.Time
+	// Components json encoded list of the index components and weights
+	Components string `gorm:"not null"`
+}
+
+// SaveIndexComposition will store an index composition version if not already present
+// an index composition version is immutable, if it exists with a different content an error is returned
+func SaveIndexComposition(db *gorm.DB, assetID string, version string, effectiveFrom time.Time, components string) (*IndexComposition, error) {
+	composition := &IndexComposition{}
+	filterCondition := &IndexComposition{
+		AssetID: assetID,
+		Version: version,
+	}
+	attrs := &IndexComposition{
+		EffectiveFrom: effectiveFrom,
+		Components:    components,
+	}
+	err := db.Where(filterCondition).Attrs(attrs).FirstOrCreate(composition).Error
+	if err != nil {
+		return nil, err
+	}
+	if !composition.EffectiveFrom.Equal(effectiveFrom) || composition.Components != components {
+		return nil, errors.Wrapf(ErrIndexCompositionMismatch, "asset %s version %s", assetID, version)
+	}
+	return composition, nil
+}
+
+// FindIndexComposition will try to retrieve an index composition version
+// from database
+func FindIndexComposition(db *gorm.DB, assetID string, version string) (*IndexComposition, error) {
+	composition := &IndexComposition{}
+	filterCondition := &IndexComposition{
+		AssetID: assetID,
+		Version: version,
+	}
+	err := db.Where(filterCondition).First(composition).Error
+	if err != nil {
+		return nil, err
+	}
+	return composition, nil
+}
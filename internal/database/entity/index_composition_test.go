@@ -0,0 +1,56 @@
+package entity_test
+
+import (
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func GetInitializedIndexDB() *gorm.DB {
+	db := test.NewOrm(&entity.Asset{}, &entity.IndexComposition{}).GetDB()
+	db.Create(&entity.Asset{AssetID: "test"})
+	return db
+}
+
+func Test_SaveIndexComposition_NotPresent_ReturnsCreated(t *testing.T) {
+	db := GetInitializedIndexDB()
+	effectiveFrom := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	actual, err := entity.SaveIndexComposition(db, "test", "v1", effectiveFrom, "[]")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", actual.Version)
+	inDB, err := entity.FindIndexComposition(db, "test", "v1")
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", inDB.Components)
+	assert.True(t, effectiveFrom.Equal(inDB.EffectiveFrom))
+}
+
+func Test_SaveIndexComposition_PresentSameContent_ReturnsStored(t *testing.T) {
+	db := GetInitializedIndexDB()
+	effectiveFrom := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	_, err := entity.SaveIndexComposition(db, "test", "v1", effectiveFrom, "[]")
+	assert.NoError(t, err)
+	_, err = entity.SaveIndexComposition(db, "test", "v1", effectiveFrom, "[]")
+	assert.NoError(t, err)
+}
+
+func Test_SaveIndexComposition_PresentDifferentContent_ReturnsMismatchError(t *testing.T) {
+	db := GetInitializedIndexDB()
+	effectiveFrom := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	_, err := entity.SaveIndexComposition(db, "test", "v1", effectiveFrom, "[]")
+	assert.NoError(t, err)
+	_, err = entity.SaveIndexComposition(db, "test", "v1", effectiveFrom, "[{}]")
+	assert.Error(t, err)
+	inDB, err := entity.FindIndexComposition(db, "test", "v1")
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", inDB.Components)
+}
+
+func Test_FindIndexComposition_NotPresent_ReturnsRecordNotFoundError(t *testing.T) {
+	db := GetInitializedIndexDB()
+	_, err := entity.FindIndexComposition(db, "test", "v1")
+	assert.EqualError(t, err, gorm.ErrRecordNotFound.Error())
+}
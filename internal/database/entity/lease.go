@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Lease represents the db model of a named lock held by a single process until it expires,
+// used to elect the replica running a background job
+type Lease struct {
+	Base
+	Name      string `gorm:"primary_key"`
+	Holder    string `gorm:"not null"`
+	ExpiresAt time.Time
+}
+
+// AcquireLease will try to acquire or renew the named lease for the holder until now + duration
+// returns false with no error if the lease is held by another holder and has not expired
+func AcquireLease(db *gorm.DB, name string, holder string, now time.Time, duration time.Duration) (bool, error) {
+	expiresAt := now.Add(duration)
+	tx := db.Model(&Lease{}).
+		Where("name = ?", name).
+		Where("holder = ? OR expires_at < ?", holder, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": expiresAt})
+	if err := tx.Error; err != nil {
+		return false, err
+	}
+	if tx.RowsAffected == 1 {
+		return true, nil
+	}
+
+	err := db.Create(&Lease{Name: name, Holder: holder, ExpiresAt: expiresAt}).Error
+	if err != nil {
+		// a concurrent holder may have created the lease first
+		if _, errFind := FindLease(db, name); errFind == nil {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseLease will release the named lease if it is held by the holder
+func ReleaseLease(db *gorm.DB, name string, holder string) error {
+	return db.Unscoped().Where("name = ? AND holder = ?", name, holder).Delete(&Lease{}).Error
+}
+
+// FindLease will try to retrieve the named lease
+// from database
+func FindLease(db *gorm.DB, name string) (*Lease, error) {
+	lease := &Lease{}
+	err := db.Where(&Lease{Name: name}).First(lease).Error
+	if err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
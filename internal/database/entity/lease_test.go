@@ -0,0 +1,63 @@
+package entity_test
+
+import (
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AcquireLease_NotPresent_ReturnsAcquired(t *testing.T) {
+	db := test.NewOrm(&entity.Lease{}).GetDB()
+	now := time.Now().UTC()
+	acquired, err := entity.AcquireLease(db, "job", "first", now, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	lease, err := entity.FindLease(db, "job")
+	assert.NoError(t, err)
+	assert.Equal(t, "first", lease.Holder)
+}
+
+func Test_AcquireLease_HeldByOther_ReturnsNotAcquired(t *testing.T) {
+	db := test.NewOrm(&entity.Lease{}).GetDB()
+	now := time.Now().UTC()
+	_, err := entity.AcquireLease(db, "job", "first", now, time.Minute)
+	assert.NoError(t, err)
+
+	acquired, err := entity.AcquireLease(db, "job", "second", now.Add(30*time.Second), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	// renewal by the holder
+	acquired, err = entity.AcquireLease(db, "job", "first", now.Add(30*time.Second), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func Test_AcquireLease_Expired_ReturnsAcquired(t *testing.T) {
+	db := test.NewOrm(&entity.Lease{}).GetDB()
+	now := time.Now().UTC()
+	_, err := entity.AcquireLease(db, "job", "first", now, time.Minute)
+	assert.NoError(t, err)
+
+	acquired, err := entity.AcquireLease(db, "job", "second", now.Add(2*time.Minute), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	lease, err := entity.FindLease(db, "job")
+	assert.NoError(t, err)
+	assert.Equal(t, "second", lease.Holder)
+}
+
+func Test_ReleaseLease_Released_CanBeAcquiredByOther(t *testing.T) {
+	db := test.NewOrm(&entity.Lease{}).GetDB()
+	now := time.Now().UTC()
+	_, err := entity.AcquireLease(db, "job", "first", now, time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, entity.ReleaseLease(db, "job", "first"))
+
+	acquired, err := entity.AcquireLease(db, "job", "second", now, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+}
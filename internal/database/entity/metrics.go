@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var dbErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "oracle_db_errors_total",
+	Help: "Number of failed database operations (records not found excluded).",
+}, []string{"operation"})
+
+// RegisterMetricsCallbacks registers the gorm callbacks counting the failed database operations
+func RegisterMetricsCallbacks(db *gorm.DB) {
+	callbacks := db.Callback()
+	callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("metrics:create", countErrors("create"))
+	callbacks.Query().After("gorm:after_query").Register("metrics:query", countErrors("query"))
+	callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("metrics:update", countErrors("update"))
+	callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("metrics:delete", countErrors("delete"))
+	callbacks.RowQuery().After("gorm:row_query").Register("metrics:row_query", countErrors("row_query"))
+}
+
+func countErrors(operation string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		if err := scope.DB().Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+			dbErrors.WithLabelValues(operation).Inc()
+		}
+	}
+}
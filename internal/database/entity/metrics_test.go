@@ -0,0 +1,58 @@
+package entity_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/test"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CountOverdueDLCData_ReturnsUnsignedPerAsset(t *testing.T) {
+	db := GetInitializedDB()
+	date := time.Date(2020, time.May, 12, 8, 0, 0, 0, time.UTC)
+	for i, rvalue := range []string{"r1", "r2", "r3"} {
+		_, err := entity.CreateDLCData(db, "test", date.Add(time.Duration(i)*time.Hour), "digits", "k"+rvalue, rvalue)
+		require.NoError(t, err)
+	}
+	_, err := entity.UpdateDLCDataAttestation(db, "test", date, "digits", entity.DLCData{Signature: "s", Value: "1"})
+	require.NoError(t, err)
+
+	counts, err := entity.CountOverdueDLCData(db, date.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"test": 1}, counts)
+
+	_, err = entity.CreateDLCData(db, "other", date, "digits", "kr4", "r4")
+	require.NoError(t, err)
+	_, err = entity.UpdateDLCDataAttestation(db, "other", date, "digits", entity.DLCData{Signature: "s4", Value: "1"})
+	require.NoError(t, err)
+	dates, err := entity.FindLastAttestationDates(db)
+	if assert.NoError(t, err) && assert.Len(t, dates, 2) {
+		for _, assetID := range []string{"test", "other"} {
+			attested, err := entity.FindDLCDataPublishedAt(db, assetID, date, "digits")
+			require.NoError(t, err)
+			assert.True(t, attested.UpdatedAt.Equal(dates[assetID]), dates[assetID].String())
+		}
+	}
+}
+
+func Test_RegisterMetricsCallbacks_CountsErrors(t *testing.T) {
+	db := test.NewOrm(&entity.Asset{}).GetDB()
+	entity.RegisterMetricsCallbacks(db)
+
+	_, err := entity.FindAsset(db, "unknown")
+	assert.True(t, gorm.IsRecordNotFoundError(err))
+	// the lease table is not migrated
+	_, err = entity.FindLease(db, "unknown")
+	assert.Error(t, err)
+
+	resp := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, resp.Body.String(), `oracle_db_errors_total{operation="query"} 1`)
+}
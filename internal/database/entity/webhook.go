@@ -0,0 +1,204 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// WebhookDeliveryPending the delivery has not been attempted yet
+	WebhookDeliveryPending = "pending"
+	// WebhookDeliveryFailed the last delivery attempt failed, it will be retried
+	WebhookDeliveryFailed = "failed"
+	// WebhookDeliveryDelivered the notification has been delivered
+	WebhookDeliveryDelivered = "delivered"
+	// WebhookDeliveryAbandoned the delivery failed too many times and won't be retried
+	WebhookDeliveryAbandoned = "abandoned"
+)
+
+// Webhook represents the db model of a webhook subscription to the event notifications
+type Webhook struct {
+	Base
+	ID uint `gorm:"primary_key"`
+	// Owner principal which registered the webhook (empty for the webhooks registered before the owners were stored)
+	Owner string `gorm:"index"`
+	URL   string `gorm:"not null"`
+	// Assets comma separated list of the asset ids notified (all assets if empty)
+	Assets string
+	// Secret key used to sign the notifications with HMAC-SHA256
+	Secret string `gorm:"not null" json:"-"`
+}
+
+// AssetIDs returns the list of the asset ids notified (empty for all assets)
+func (m *Webhook) AssetIDs() []string {
+	if m.Assets == "" {
+		return []string{}
+	}
+	return strings.Split(m.Assets, ",")
+}
+
+// Matches returns true if the webhook is subscribed to the asset notifications
+func (m *Webhook) Matches(assetID string) bool {
+	if m.Assets == "" {
+		return true
+	}
+	for _, id := range m.AssetIDs() {
+		if id == assetID {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery represents the db model of the delivery of a notification to a webhook (delivery log)
+type WebhookDelivery struct {
+	Base
+	ID                   uint   `gorm:"primary_key"`
+	WebhookID            uint   `gorm:"unique_index:idx_webhook_delivery_notification;not null"`
+	NotificationSequence uint64 `gorm:"unique_index:idx_webhook_delivery_notification;not null"`
+	Status               string `gorm:"index;not null"`
+	Attempts             int
+	NextAttemptAt        time.Time `gorm:"index"`
+	// ResponseStatus http status code of the last attempt (0 if no response)
+	ResponseStatus int
+	LastError      string
+	DeliveredAt    *time.Time
+}
+
+// CreateWebhook will create a webhook subscription
+func CreateWebhook(db *gorm.DB, owner string, url string, assetIDs []string, secret string) (*Webhook, error) {
+	webhook := &Webhook{
+		Owner:  owner,
+		URL:    url,
+		Assets: strings.Join(assetIDs, ","),
+		Secret: secret,
+	}
+	if err := db.Create(webhook).Error; err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// FindWebhook will try to retrieve a webhook subscription
+// from database
+func FindWebhook(db *gorm.DB, id uint) (*Webhook, error) {
+	webhook := &Webhook{}
+	err := db.Where("id = ?", id).First(webhook).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// FindWebhooks will retrieve all the webhook subscriptions sorted by id
+func FindWebhooks(db *gorm.DB) ([]Webhook, error) {
+	webhooks := []Webhook{}
+	err := db.Order("id ASC").Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// FindWebhooksOfOwner will retrieve the webhook subscriptions registered by an owner sorted by id
+func FindWebhooksOfOwner(db *gorm.DB, owner string) ([]Webhook, error) {
+	webhooks := []Webhook{}
+	err := db.Where("owner = ?", owner).Order("id ASC").Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook will delete a webhook subscription, its pending deliveries are abandoned
+func DeleteWebhook(db *gorm.DB, id uint) error {
+	tx := db.Begin()
+	res := tx.Where("id = ?", id).Delete(&Webhook{})
+	if res.Error != nil {
+		tx.Rollback()
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+	err := tx.Model(&WebhookDelivery{}).
+		Where("webhook_id = ? AND status IN (?)", id, []string{WebhookDeliveryPending, WebhookDeliveryFailed}).
+		Update("status", WebhookDeliveryAbandoned).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// CreateWebhookDeliveries will create the pending deliveries of the notifications to the matching webhooks
+// and move the dispatch cursor to the last notification in a single transaction
+func CreateWebhookDeliveries(db *gorm.DB, cursorName string, webhooks []Webhook, notifications []EventNotification, now time.Time) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	tx := db.Begin()
+	for _, notification := range notifications {
+		for _, webhook := range webhooks {
+			if !webhook.Matches(notification.AssetID) {
+				continue
+			}
+			err := tx.Create(&WebhookDelivery{
+				WebhookID:            webhook.ID,
+				NotificationSequence: notification.Sequence,
+				Status:               WebhookDeliveryPending,
+				NextAttemptAt:        now,
+			}).Error
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	if err := SaveCursor(tx, cursorName, notifications[len(notifications)-1].Sequence); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// FindWebhookDeliveriesToSend will retrieve the pending or failed deliveries due at now sorted by id
+func FindWebhookDeliveriesToSend(db *gorm.DB, now time.Time, limit int) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	req := db.Where("status IN (?)", []string{WebhookDeliveryPending, WebhookDeliveryFailed})
+	req = req.Where("next_attempt_at <= ?", now)
+	err := req.Order("id ASC").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// FindWebhookDeliveries will retrieve the most recent deliveries of a webhook, optionally filtered by status
+func FindWebhookDeliveries(db *gorm.DB, webhookID uint, status string, limit int) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	req := db.Where("webhook_id = ?", webhookID)
+	if status != "" {
+		req = req.Where("status = ?", status)
+	}
+	err := req.Order("id DESC").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery will save the result of a delivery attempt
+func UpdateWebhookDelivery(db *gorm.DB, delivery *WebhookDelivery) error {
+	return db.Model(&WebhookDelivery{ID: delivery.ID}).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"response_status": delivery.ResponseStatus,
+		"last_error":      delivery.LastError,
+		"delivered_at":    delivery.DeliveredAt,
+	}).Error
+}
@@ -5,6 +5,7 @@ import "time"
 // DataFeed interface represents a datafeed with any sorts of data
 type DataFeed interface {
 	AssetPriceFeed
+	AssetCandleFeed
 }
 
 // AssetPriceFeed interface represents a datafeed which implemented price related services
@@ -12,3 +13,22 @@ type AssetPriceFeed interface {
 	FindCurrentAssetPrice(assetID string, currency string) (*float64, error)
 	FindPastAssetPrice(assetID string, currency string, date time.Time) (*float64, error)
 }
+
+// AssetCandleFeed interface represents a datafeed which can provide a series of candles of an asset price
+type AssetCandleFeed interface {
+	// FindPastAssetCandles returns the candles of the trading between from and to sorted by time:
+	// the candles opening in [from, to) for the feeds returning periods (cryptocompare),
+	// the points sampled in ]from, to] for the feeds returning samples (file, http, bitcoind)
+	FindPastAssetCandles(assetID string, currency string, from time.Time, to time.Time) ([]Candle, error)
+}
+
+// Candle represents the price movement of an asset during a time period (time being the period start),
+// or a sampled value (time being the sample time, open, high, low and close being the value)
+type Candle struct {
+	Time   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
@@ -25,6 +25,16 @@ func (d *dummyDataFeed) FindPastAssetPrice(assetID string, currency string, date
 	return &f, nil
 }
 
+// FindPastAssetCandles returns one constant candle per minute
+func (d *dummyDataFeed) FindPastAssetCandles(assetID string, currency string, from time.Time, to time.Time) ([]Candle, error) {
+	f := d.config.ReturnValue
+	candles := []Candle{}
+	for t := from.Truncate(time.Minute).Add(time.Minute); !t.After(to); t = t.Add(time.Minute) {
+		candles = append(candles, Candle{Time: t, Open: f, High: f, Low: f, Close: f, Volume: 1})
+	}
+	return candles, nil
+}
+
 // DummyConfig configuration for the dummy Datafeed
 type DummyConfig struct {
 	ReturnValue float64 `configkey:"dummy.returnValue" validate:"required"`
@@ -0,0 +1,371 @@
+package datafeed
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MissingPointReject returns an error if there is no point at the requested date
+	MissingPointReject = "reject"
+	// MissingPointPrevious uses the most recent point before the requested date
+	MissingPointPrevious = "previous"
+	// MissingPointInterpolate linearly interpolates between the surrounding points
+	MissingPointInterpolate = "interpolate"
+)
+
+// FileConfig configuration for the file replay Datafeed
+// the file extension defines the format: .csv (with header asset,currency,time,value[,volume]) or .jsonl
+type FileConfig struct {
+	Path string `configkey:"file.path" validate:"required"`
+	// MissingPolicy policy to apply if there is no point at the requested date (reject by default)
+	MissingPolicy string `configkey:"file.missingPolicy" validate:"omitempty,oneof=reject previous interpolate"`
+	// MaxGap maximum distance to the points used to resolve a missing point (no limit if not set)
+	MaxGap time.Duration `configkey:"file.maxGap,duration,iso8601"`
+	// ReloadInterval interval at which the file is checked for modification (no hot-reload if not set)
+	ReloadInterval time.Duration `configkey:"file.reloadInterval,duration,iso8601"`
+}
+
+// NewFileDataFeed returns a datafeed replaying time series from a file (not initialized)
+func NewFileDataFeed(config *FileConfig) *FileDataFeed {
+	return &FileDataFeed{
+		config:      config,
+		initialized: false,
+	}
+}
+
+// FileDataFeed represents a datafeed replaying per asset time series loaded from a file
+type FileDataFeed struct {
+	DataFeed
+	config *FileConfig
+	// lifecycle serializes Initialize and Finalize
+	lifecycle sync.Mutex
+	// mutex guards the loaded series, the reload state and the initialized flag
+	mutex       sync.RWMutex
+	series      map[string][]filePoint
+	modTime     time.Time
+	reloadErr   error
+	stop        chan struct{}
+	initialized bool
+}
+
+type filePoint struct {
+	Asset    string    `json:"asset"`
+	Currency string    `json:"currency"`
+	Time     time.Time `json:"time"`
+	Value    float64   `json:"value"`
+	Volume   float64   `json:"volume"`
+}
+
+// Initialize loads the file and starts the hot-reload if configured
+func (f *FileDataFeed) Initialize() error {
+	f.lifecycle.Lock()
+	defer f.lifecycle.Unlock()
+	if f.IsInitialized() {
+		return nil
+	}
+	if err := f.load(); err != nil {
+		return err
+	}
+	if f.config.ReloadInterval > 0 {
+		f.stop = make(chan struct{})
+		go f.watch(f.stop)
+	}
+	f.setInitialized(true)
+	return nil
+}
+
+// IsInitialized returns true if the datafeed has been initialized
+func (f *FileDataFeed) IsInitialized() bool {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.initialized
+}
+
+// Finalize stops the hot-reload of the file
+func (f *FileDataFeed) Finalize() {
+	f.lifecycle.Lock()
+	defer f.lifecycle.Unlock()
+	if f.stop != nil {
+		close(f.stop)
+		f.stop = nil
+	}
+	f.setInitialized(false)
+}
+
+func (f *FileDataFeed) setInitialized(initialized bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.initialized = initialized
+}
+
+// ReloadError returns the error of the last reload attempt (the previous series are kept in that case)
+func (f *FileDataFeed) ReloadError() error {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.reloadErr
+}
+
+// FindCurrentAssetPrice returns the value of the series at the current time
+func (f *FileDataFeed) FindCurrentAssetPrice(assetID string, currency string) (*float64, error) {
+	return f.FindPastAssetPrice(assetID, currency, time.Now().UTC())
+}
+
+// FindPastAssetPrice returns the value of the series at the requested date,
+// applying the missing point policy if there is no point at this date
+func (f *FileDataFeed) FindPastAssetPrice(assetID string, currency string, date time.Time) (*float64, error) {
+	points, err := f.findSeries(assetID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// index of the first point at or after the date
+	i := sort.Search(len(points), func(i int) bool {
+		return !points[i].Time.Before(date)
+	})
+	if i < len(points) && points[i].Time.Equal(date) {
+		value := points[i].Value
+		return &value, nil
+	}
+
+	switch f.config.MissingPolicy {
+	case MissingPointPrevious:
+		if i > 0 && f.isInGap(points[i-1].Time, date) {
+			value := points[i-1].Value
+			return &value, nil
+		}
+	case MissingPointInterpolate:
+		if i > 0 && i < len(points) && f.isInGap(points[i-1].Time, date) && f.isInGap(date, points[i].Time) {
+			prev, next := points[i-1], points[i]
+			ratio := float64(date.Sub(prev.Time)) / float64(next.Time.Sub(prev.Time))
+			value := prev.Value + ratio*(next.Value-prev.Value)
+			return &value, nil
+		}
+	}
+
+	return nil, errors.Errorf("no value for %s%s at %s in replay file", assetID, currency, date.String())
+}
+
+// FindPastAssetCandles returns one candle per point of the series in the ]from, to] interval
+func (f *FileDataFeed) FindPastAssetCandles(assetID string, currency string, from time.Time, to time.Time) ([]Candle, error) {
+	points, err := f.findSeries(assetID, currency)
+	if err != nil {
+		return nil, err
+	}
+	candles := []Candle{}
+	for _, point := range points {
+		if !point.Time.After(from) || point.Time.After(to) {
+			continue
+		}
+		candles = append(candles, Candle{
+			Time:   point.Time,
+			Open:   point.Value,
+			High:   point.Value,
+			Low:    point.Value,
+			Close:  point.Value,
+			Volume: point.Volume,
+		})
+	}
+	return candles, nil
+}
+
+func (f *FileDataFeed) isInGap(from time.Time, to time.Time) bool {
+	return f.config.MaxGap == 0 || to.Sub(from) <= f.config.MaxGap
+}
+
+func (f *FileDataFeed) findSeries(assetID string, currency string) ([]filePoint, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	if !f.initialized {
+		return nil, errors.New("file datafeed is not initialized")
+	}
+	points, ok := f.series[seriesKey(assetID, currency)]
+	if !ok {
+		return nil, errors.Errorf("no series for %s%s in replay file", assetID, currency)
+	}
+	return points, nil
+}
+
+func (f *FileDataFeed) watch(stop <-chan struct{}) {
+	ticker := time.NewTicker(f.config.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(f.config.Path)
+			if err != nil {
+				f.setReloadError(err)
+				continue
+			}
+			f.mutex.RLock()
+			modified := !info.ModTime().Equal(f.modTime)
+			f.mutex.RUnlock()
+			if modified {
+				f.setReloadError(f.load())
+			}
+		}
+	}
+}
+
+func (f *FileDataFeed) setReloadError(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.reloadErr = err
+}
+
+func (f *FileDataFeed) load() error {
+	file, err := os.Open(f.config.Path)
+	if err != nil {
+		return errors.WithMessage(err, "could not open replay file")
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return errors.WithMessage(err, "could not read replay file information")
+	}
+
+	var points []filePoint
+	switch strings.ToLower(filepath.Ext(f.config.Path)) {
+	case ".csv":
+		points, err = readCSVPoints(file)
+	case ".jsonl":
+		points, err = readJSONLPoints(file)
+	default:
+		err = errors.Errorf("unsupported replay file format %s", f.config.Path)
+	}
+	if err != nil {
+		return err
+	}
+
+	series := map[string][]filePoint{}
+	for _, point := range points {
+		key := seriesKey(point.Asset, point.Currency)
+		series[key] = append(series[key], point)
+	}
+	for key, values := range series {
+		sort.Slice(values, func(i, j int) bool {
+			return values[i].Time.Before(values[j].Time)
+		})
+		for i := 1; i < len(values); i++ {
+			if values[i].Time.Equal(values[i-1].Time) {
+				return errors.Errorf("duplicated point for %s at %s in replay file", key, values[i].Time.String())
+			}
+		}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.series = series
+	f.modTime = info.ModTime()
+	return nil
+}
+
+func seriesKey(assetID string, currency string) string {
+	return strings.ToLower(assetID) + "/" + strings.ToLower(currency)
+}
+
+func readCSVPoints(r io.Reader) ([]filePoint, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not read replay file header")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"asset", "currency", "time", "value"} {
+		if _, ok := columns[required]; !ok {
+			return nil, errors.Errorf("missing column %s in replay file header", required)
+		}
+	}
+	volumeColumn, hasVolume := columns["volume"]
+
+	points := []filePoint{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid replay file line %d", line)
+		}
+		if len(record) < len(header) {
+			return nil, errors.Errorf("invalid replay file line %d, expected %d columns", line, len(header))
+		}
+		date, err := parsePointTime(record[columns["time"]])
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid time at replay file line %d", line)
+		}
+		value, err := strconv.ParseFloat(record[columns["value"]], 64)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid value at replay file line %d", line)
+		}
+		point := filePoint{
+			Asset:    record[columns["asset"]],
+			Currency: record[columns["currency"]],
+			Time:     date,
+			Value:    value,
+		}
+		if hasVolume && record[volumeColumn] != "" {
+			point.Volume, err = strconv.ParseFloat(record[volumeColumn], 64)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid volume at replay file line %d", line)
+			}
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+func readJSONLPoints(r io.Reader) ([]filePoint, error) {
+	points := []filePoint{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		point := filePoint{}
+		if err := json.Unmarshal([]byte(text), &point); err != nil {
+			return nil, errors.WithMessagef(err, "invalid replay file line %d", line)
+		}
+		if point.Asset == "" || point.Currency == "" || point.Time.IsZero() {
+			return nil, errors.Errorf("invalid replay file line %d, asset, currency and time are required", line)
+		}
+		point.Time = point.Time.UTC()
+		points = append(points, point)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithMessage(err, "could not read replay file")
+	}
+	return points, nil
+}
+
+// parsePointTime parses a RFC3339 time or a unix timestamp in seconds
+func parsePointTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.UTC(), nil
+}
@@ -0,0 +1,165 @@
+package datafeed_test
+
+import (
+	"io/ioutil"
+	"os"
+	"p2pderivatives-oracle/internal/datafeed"
+	"p2pderivatives-oracle/test"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	replayStart = time.Date(2020, time.March, 1, 10, 0, 0, 0, time.UTC)
+	replayFiles = []string{"replay.csv", "replay.jsonl"}
+)
+
+func NewTestFileDataFeed(t *testing.T, file string, policy string, maxGap time.Duration) *datafeed.FileDataFeed {
+	feed := datafeed.NewFileDataFeed(&datafeed.FileConfig{
+		Path:          filepath.Join(test.VectorsDirectoryPath, "datafeed", file),
+		MissingPolicy: policy,
+		MaxGap:        maxGap,
+	})
+	if err := feed.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+func TestFileDataFeed_FindPastAssetPrice_WithExactPoint_ReturnsCorrectValue(t *testing.T) {
+	for _, file := range replayFiles {
+		feed := NewTestFileDataFeed(t, file, datafeed.MissingPointReject, 0)
+		actual, err := feed.FindPastAssetPrice("BTC", "usd", replayStart.Add(2*time.Minute))
+		if assert.NoError(t, err, file) {
+			assert.Equal(t, 9100.0, *actual, file)
+		}
+		actual, err = feed.FindPastAssetPrice("eth", "usd", replayStart)
+		if assert.NoError(t, err, file) {
+			assert.Equal(t, 230.5, *actual, file)
+		}
+	}
+}
+
+func TestFileDataFeed_FindPastAssetPrice_WithMissingPoint_AppliesPolicy(t *testing.T) {
+	date := replayStart.Add(time.Minute)
+	tests := []struct {
+		policy   string
+		maxGap   time.Duration
+		expected *float64
+	}{
+		{datafeed.MissingPointReject, 0, nil},
+		{datafeed.MissingPointPrevious, 0, floatPtr(9000)},
+		{datafeed.MissingPointPrevious, 30 * time.Second, nil},
+		{datafeed.MissingPointInterpolate, 0, floatPtr(9050)},
+		{datafeed.MissingPointInterpolate, 30 * time.Second, nil},
+	}
+	for _, test := range tests {
+		feed := NewTestFileDataFeed(t, "replay.csv", test.policy, test.maxGap)
+		actual, err := feed.FindPastAssetPrice("btc", "usd", date)
+		if test.expected == nil {
+			assert.Error(t, err, test.policy)
+		} else if assert.NoError(t, err, test.policy) {
+			assert.Equal(t, *test.expected, *actual, test.policy)
+		}
+	}
+}
+
+func TestFileDataFeed_FindPastAssetPrice_OutOfSeries_ReturnsError(t *testing.T) {
+	feed := NewTestFileDataFeed(t, "replay.csv", datafeed.MissingPointInterpolate, 0)
+	_, err := feed.FindPastAssetPrice("btc", "usd", replayStart.Add(time.Hour))
+	assert.Error(t, err)
+	_, err = feed.FindPastAssetPrice("btc", "jpy", replayStart)
+	assert.Error(t, err)
+}
+
+func TestFileDataFeed_FindPastAssetCandles_ReturnsPointsInWindow(t *testing.T) {
+	feed := NewTestFileDataFeed(t, "replay.csv", datafeed.MissingPointReject, 0)
+	actual, err := feed.FindPastAssetCandles("btc", "usd", replayStart, replayStart.Add(4*time.Minute))
+	if assert.NoError(t, err) && assert.Len(t, actual, 2) {
+		assert.Equal(t, datafeed.Candle{Time: replayStart.Add(2 * time.Minute), Open: 9100, High: 9100, Low: 9100, Close: 9100, Volume: 1}, actual[0])
+		assert.Equal(t, 9200.0, actual[1].Close)
+	}
+}
+
+func TestFileDataFeed_NotInitialized_ReturnsError(t *testing.T) {
+	feed := datafeed.NewFileDataFeed(&datafeed.FileConfig{Path: "replay.csv"})
+	assert.False(t, feed.IsInitialized())
+	_, err := feed.FindPastAssetPrice("btc", "usd", replayStart)
+	assert.Error(t, err)
+}
+
+func TestFileDataFeed_WithModifiedFile_ReloadsSeries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "replay.csv")
+	writeFile := func(content string, modTime time.Time) {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("asset,currency,time,value\nbtc,usd,2020-03-01T10:00:00Z,1\n", replayStart)
+
+	feed := datafeed.NewFileDataFeed(&datafeed.FileConfig{Path: path, ReloadInterval: 10 * time.Millisecond})
+	if err := feed.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	defer feed.Finalize()
+
+	writeFile("asset,currency,time,value\nbtc,usd,2020-03-01T10:00:00Z,2\n", replayStart.Add(time.Hour))
+	assert.Eventually(t, func() bool {
+		value, err := feed.FindPastAssetPrice("btc", "usd", replayStart)
+		return err == nil && *value == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// an invalid file is reported and the previous series are kept
+	writeFile("invalid\n", replayStart.Add(2*time.Hour))
+	assert.Eventually(t, func() bool {
+		return feed.ReloadError() != nil
+	}, time.Second, 10*time.Millisecond)
+	value, err := feed.FindPastAssetPrice("btc", "usd", replayStart)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2.0, *value)
+	}
+}
+
+func TestFileDataFeed_ConcurrentReads_WhileInitializing_DoNotRace(t *testing.T) {
+	feed := datafeed.NewFileDataFeed(&datafeed.FileConfig{
+		Path:           filepath.Join(test.VectorsDirectoryPath, "datafeed", "replay.csv"),
+		MissingPolicy:  datafeed.MissingPointReject,
+		ReloadInterval: time.Millisecond,
+	})
+	defer feed.Finalize()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				feed.IsInitialized()
+				feed.FindPastAssetPrice("btc", "usd", replayStart)
+			}
+		}()
+	}
+	assert.NoError(t, feed.Initialize())
+	wg.Wait()
+
+	actual, err := feed.FindPastAssetPrice("btc", "usd", replayStart)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 9000.0, *actual)
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
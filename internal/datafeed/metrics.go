@@ -0,0 +1,27 @@
+package datafeed
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	callDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "oracle_datafeed_request_duration_seconds",
+		Help: "Duration of the datafeed provider calls.",
+	}, []string{"provider"})
+	callErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oracle_datafeed_errors_total",
+		Help: "Number of failed datafeed provider calls.",
+	}, []string{"provider"})
+)
+
+// ObserveCall records the duration of a datafeed provider call started at the given time, and its failure if err is not nil
+func ObserveCall(provider string, started time.Time, err error) {
+	callDuration.WithLabelValues(provider).Observe(time.Since(started).Seconds())
+	if err != nil {
+		callErrors.WithLabelValues(provider).Inc()
+	}
+}
@@ -0,0 +1,44 @@
+package datafeed
+
+import (
+	"strings"
+	"time"
+)
+
+// NewMultiDataFeed returns a datafeed routing each asset to its dedicated datafeed (indexed by lower case asset symbol),
+// falling back to the default datafeed for the other assets
+func NewMultiDataFeed(defaultFeed DataFeed, feeds map[string]DataFeed) *MultiDataFeed {
+	return &MultiDataFeed{
+		defaultFeed: defaultFeed,
+		feeds:       feeds,
+	}
+}
+
+// MultiDataFeed represents a datafeed routing requests per asset
+type MultiDataFeed struct {
+	DataFeed
+	defaultFeed DataFeed
+	feeds       map[string]DataFeed
+}
+
+// FindCurrentAssetPrice returns the current price of the asset from its datafeed
+func (m *MultiDataFeed) FindCurrentAssetPrice(assetID string, currency string) (*float64, error) {
+	return m.feedOf(assetID).FindCurrentAssetPrice(assetID, currency)
+}
+
+// FindPastAssetPrice returns a past price of the asset from its datafeed
+func (m *MultiDataFeed) FindPastAssetPrice(assetID string, currency string, date time.Time) (*float64, error) {
+	return m.feedOf(assetID).FindPastAssetPrice(assetID, currency, date)
+}
+
+// FindPastAssetCandles returns the candles of the asset from its datafeed
+func (m *MultiDataFeed) FindPastAssetCandles(assetID string, currency string, from time.Time, to time.Time) ([]Candle, error) {
+	return m.feedOf(assetID).FindPastAssetCandles(assetID, currency, from, to)
+}
+
+func (m *MultiDataFeed) feedOf(assetID string) DataFeed {
+	if feed, ok := m.feeds[strings.ToLower(assetID)]; ok {
+		return feed
+	}
+	return m.defaultFeed
+}
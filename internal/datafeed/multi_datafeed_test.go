@@ -0,0 +1,29 @@
+package datafeed_test
+
+import (
+	"p2pderivatives-oracle/internal/datafeed"
+	mock_datafeed "p2pderivatives-oracle/test/mock/datafeed"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiDataFeed_FindPastAssetPrice_RoutesPerAsset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	defaultFeed := mock_datafeed.NewMockDataFeed(ctrl)
+	btcFeed := mock_datafeed.NewMockDataFeed(ctrl)
+	btcValue, ethValue := 9000.0, 230.0
+	btcFeed.EXPECT().FindPastAssetPrice("BTC", "usd", testDate).Return(&btcValue, nil)
+	defaultFeed.EXPECT().FindPastAssetPrice("eth", "usd", testDate).Return(&ethValue, nil)
+
+	feed := datafeed.NewMultiDataFeed(defaultFeed, map[string]datafeed.DataFeed{"btc": btcFeed})
+
+	val, err := feed.FindPastAssetPrice("BTC", "usd", testDate)
+	assert.NoError(t, err)
+	assert.Equal(t, btcValue, *val)
+	val, err = feed.FindPastAssetPrice("eth", "usd", testDate)
+	assert.NoError(t, err)
+	assert.Equal(t, ethValue, *val)
+}
@@ -0,0 +1,140 @@
+package datafeed
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/utils/iso8601"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SettlementClose uses the closing price at the settlement date
+	SettlementClose = "close"
+	// SettlementTWAP uses the average of the candle closes over the window preceding the settlement date
+	// (unweighted, the missing candles are not accounted for)
+	SettlementTWAP = "twap"
+	// SettlementVWAP uses the volume weighted average price over the window preceding the settlement date
+	SettlementVWAP = "vwap"
+	// SettlementMedian uses the median price over the window preceding the settlement date
+	SettlementMedian = "median"
+)
+
+var settlementPattern = regexp.MustCompile(`^(\w+)(?:\(([^()]+)\))?$`)
+
+// SettlementMethod represents how the settlement value of an asset is computed from the datafeed
+type SettlementMethod struct {
+	Kind   string
+	Window time.Duration
+}
+
+// ParseSettlementMethod parses a settlement method with format `close`, `twap(<window>)`,
+// `vwap(<window>)` or `median(<window>)`, the window being an ISO8601 duration (ex: twap(PT30M))
+// an empty string is considered as `close`
+func ParseSettlementMethod(method string) (*SettlementMethod, error) {
+	if method == "" {
+		return &SettlementMethod{Kind: SettlementClose}, nil
+	}
+	match := settlementPattern.FindStringSubmatch(method)
+	if match == nil {
+		return nil, errors.Errorf("invalid settlement method format %s", method)
+	}
+	kind, windowStr := match[1], match[2]
+	switch kind {
+	case SettlementClose:
+		if windowStr != "" {
+			return nil, errors.Errorf("settlement method %s does not accept a window", kind)
+		}
+		return &SettlementMethod{Kind: kind}, nil
+	case SettlementTWAP, SettlementVWAP, SettlementMedian:
+		if windowStr == "" {
+			return nil, errors.Errorf("settlement method %s requires a window", kind)
+		}
+		window, err := iso8601.ParseDuration(windowStr)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid settlement window %s", windowStr)
+		}
+		if window <= 0 {
+			return nil, errors.Errorf("settlement window should be positive %s", windowStr)
+		}
+		return &SettlementMethod{Kind: kind, Window: window}, nil
+	default:
+		return nil, errors.Errorf("unknown settlement method %s", kind)
+	}
+}
+
+// String returns the settlement method in its parsable format
+func (m *SettlementMethod) String() string {
+	if m.Kind == SettlementClose {
+		return m.Kind
+	}
+	return fmt.Sprintf("%s(%s)", m.Kind, iso8601.EncodeDuration(m.Window))
+}
+
+// FindValue computes the settlement value of an asset at the given date using the datafeed
+func (m *SettlementMethod) FindValue(feed DataFeed, assetID string, currency string, date time.Time) (*float64, error) {
+	if m.Kind == SettlementClose {
+		return feed.FindPastAssetPrice(assetID, currency, date)
+	}
+
+	candles, err := feed.FindPastAssetCandles(assetID, currency, date.Add(-m.Window), date)
+	if err != nil {
+		return nil, err
+	}
+	if len(candles) == 0 {
+		return nil, errors.Errorf("no candle found for %s%s in the %s window before %s", assetID, currency, m.String(), date.String())
+	}
+
+	var value float64
+	switch m.Kind {
+	case SettlementTWAP:
+		value = twap(candles)
+	case SettlementVWAP:
+		value, err = vwap(candles)
+	case SettlementMedian:
+		value = median(candles)
+	default:
+		err = errors.Errorf("unknown settlement method %s", m.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// twap returns the unweighted mean of the candle closes, the candles being assumed evenly spaced
+// (a missing candle is ignored instead of being filled with the previous close)
+func twap(candles []Candle) float64 {
+	sum := 0.0
+	for _, candle := range candles {
+		sum += candle.Close
+	}
+	return sum / float64(len(candles))
+}
+
+func vwap(candles []Candle) (float64, error) {
+	sum, volume := 0.0, 0.0
+	for _, candle := range candles {
+		sum += candle.Close * candle.Volume
+		volume += candle.Volume
+	}
+	if volume == 0 {
+		return 0, errors.New("cannot compute vwap, no volume in the window")
+	}
+	return sum / volume, nil
+}
+
+func median(candles []Candle) float64 {
+	closes := make([]float64, len(candles))
+	for i, candle := range candles {
+		closes[i] = candle.Close
+	}
+	sort.Float64s(closes)
+	middle := len(closes) / 2
+	if len(closes)%2 == 0 {
+		return (closes[middle-1] + closes[middle]) / 2
+	}
+	return closes[middle]
+}
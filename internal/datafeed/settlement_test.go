@@ -0,0 +1,93 @@
+package datafeed_test
+
+import (
+	"p2pderivatives-oracle/internal/datafeed"
+	mock_datafeed "p2pderivatives-oracle/test/mock/datafeed"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testDate    = time.Date(2020, time.March, 1, 10, 0, 0, 0, time.UTC)
+	testCandles = []datafeed.Candle{
+		{Time: testDate.Add(-3 * time.Minute), Close: 10, Volume: 1},
+		{Time: testDate.Add(-2 * time.Minute), Close: 40, Volume: 2},
+		{Time: testDate.Add(-1 * time.Minute), Close: 20, Volume: 1},
+		{Time: testDate, Close: 30, Volume: 0},
+	}
+)
+
+func TestParseSettlementMethod_WithValidMethods_ReturnsCorrectValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *datafeed.SettlementMethod
+		str      string
+	}{
+		{"", &datafeed.SettlementMethod{Kind: datafeed.SettlementClose}, "close"},
+		{"close", &datafeed.SettlementMethod{Kind: datafeed.SettlementClose}, "close"},
+		{"twap(PT30M)", &datafeed.SettlementMethod{Kind: datafeed.SettlementTWAP, Window: 30 * time.Minute}, "twap(PT30M)"},
+		{"vwap(PT1H)", &datafeed.SettlementMethod{Kind: datafeed.SettlementVWAP, Window: time.Hour}, "vwap(PT1H)"},
+		{"median(PT5M)", &datafeed.SettlementMethod{Kind: datafeed.SettlementMedian, Window: 5 * time.Minute}, "median(PT5M)"},
+	}
+	for _, test := range tests {
+		actual, err := datafeed.ParseSettlementMethod(test.input)
+		if assert.NoError(t, err, test.input) {
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, test.str, actual.String())
+		}
+	}
+}
+
+func TestParseSettlementMethod_WithInvalidMethods_ReturnsError(t *testing.T) {
+	for _, input := range []string{"twap", "close(PT1M)", "unknown(PT1M)", "twap(1 minute)", "twap(PT1M", "twap(PT0S)"} {
+		_, err := datafeed.ParseSettlementMethod(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestSettlementMethod_FindValue_WithClose_UsesPastPrice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	price := 42.0
+	feed.EXPECT().FindPastAssetPrice("btc", "usd", testDate).Return(&price, nil)
+	method, _ := datafeed.ParseSettlementMethod("close")
+	actual, err := method.FindValue(feed, "btc", "usd", testDate)
+	if assert.NoError(t, err) {
+		assert.Equal(t, price, *actual)
+	}
+}
+
+func TestSettlementMethod_FindValue_WithWindowMethods_ReturnsCorrectValue(t *testing.T) {
+	tests := []struct {
+		method   string
+		expected float64
+	}{
+		{"twap(PT4M)", 25},
+		{"vwap(PT4M)", 27.5},
+		{"median(PT4M)", 25},
+	}
+	for _, test := range tests {
+		ctrl := gomock.NewController(t)
+		feed := mock_datafeed.NewMockDataFeed(ctrl)
+		feed.EXPECT().FindPastAssetCandles("btc", "usd", testDate.Add(-4*time.Minute), testDate).Return(testCandles, nil)
+		method, err := datafeed.ParseSettlementMethod(test.method)
+		if assert.NoError(t, err) {
+			actual, err := method.FindValue(feed, "btc", "usd", testDate)
+			if assert.NoError(t, err, test.method) {
+				assert.Equal(t, test.expected, *actual, test.method)
+			}
+		}
+	}
+}
+
+func TestSettlementMethod_FindValue_WithNoCandle_ReturnsError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	feed.EXPECT().FindPastAssetCandles("btc", "usd", testDate.Add(-time.Minute), testDate).Return([]datafeed.Candle{}, nil)
+	method, _ := datafeed.ParseSettlementMethod("twap(PT1M)")
+	_, err := method.FindValue(feed, "btc", "usd", testDate)
+	assert.Error(t, err)
+}
@@ -0,0 +1,254 @@
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/datafeed"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/oracle"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/cryptogarageinc/server-common-go/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	defaultStreamPollInterval = time.Second
+	defaultStreamGapTimeout   = 10 * time.Second
+	defaultStreamHeartbeat    = 15 * time.Second
+)
+
+var (
+	grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oracle_grpc_requests_total",
+		Help: "Number of gRPC calls.",
+	}, []string{"method", "code"})
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "oracle_grpc_request_duration_seconds",
+		Help: "Duration of the gRPC calls.",
+	}, []string{"method"})
+)
+
+// Config contains the gRPC server configuration
+type Config struct {
+	// Address address the gRPC server listens on, the gRPC server is not started if not set
+	Address string `configkey:"grpc.address"`
+}
+
+// callKey key of the call of a request context
+type callKey struct{}
+
+// call represents a call being served, with the asset service of its principal
+type call struct {
+	service *api.AssetService
+}
+
+// callOf returns the call of a request context, set by the server interceptors
+func callOf(ctx context.Context) *call {
+	return ctx.Value(callKey{}).(*call)
+}
+
+// Server implements the oracle gRPC service (OracleServer), sharing the api authentication, rate limits and business logic
+type Server struct {
+	authenticator *api.Authenticator
+	limiter       *api.RateLimiter
+	logger        *log.Log
+	oracle        *oracle.Oracle
+	orm           *orm.ORM
+	cache         *entity.DLCDataCache
+	assets        api.AssetProvider
+	crypto        dlccrypto.CryptoService
+	feed          datafeed.DataFeed
+	pollInterval  time.Duration
+	heartbeat     time.Duration
+	gapTimeout    time.Duration
+}
+
+// NewServer returns a gRPC server of the api configuration and services
+func NewServer(
+	config *api.Config,
+	log *log.Log,
+	oracle *oracle.Oracle,
+	orm *orm.ORM,
+	assets api.AssetProvider,
+	cryptoService dlccrypto.CryptoService,
+	feed datafeed.DataFeed) (*Server, error) {
+	authenticator, err := api.NewAuthenticator(config)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Invalid authentication configuration")
+	}
+	server := &Server{
+		authenticator: authenticator,
+		limiter:       api.NewConfigRateLimiter(config),
+		logger:        log,
+		oracle:        oracle,
+		orm:           orm,
+		cache:         entity.NewDLCDataCache(config.CacheSize),
+		assets:        assets,
+		crypto:        cryptoService,
+		feed:          feed,
+		pollInterval:  config.StreamPollInterval,
+		heartbeat:     config.StreamHeartbeat,
+		gapTimeout:    config.StreamGapTimeout,
+	}
+	if server.pollInterval <= 0 {
+		server.pollInterval = defaultStreamPollInterval
+	}
+	if server.heartbeat <= 0 {
+		server.heartbeat = defaultStreamHeartbeat
+	}
+	if server.gapTimeout <= 0 {
+		server.gapTimeout = defaultStreamGapTimeout
+	}
+	return server, nil
+}
+
+// NewGRPCServer returns a gRPC server serving the oracle service, the calls require the reader role
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.UnaryInterceptor(s.interceptUnary), grpc.StreamInterceptor(s.interceptStream))
+	grpcServer := grpc.NewServer(opts...)
+	RegisterOracleServer(grpcServer, s)
+	return grpcServer
+}
+
+// interceptUnary serves a unary call with its call context
+func (s *Server) interceptUnary(
+	ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var response interface{}
+	err := s.serve(ctx, info.FullMethod, func(callCtx context.Context) error {
+		var err error
+		response, err = handler(callCtx, request)
+		return err
+	}, func(trailer metadata.MD) { _ = grpc.SetTrailer(ctx, trailer) })
+	return response, err
+}
+
+// interceptStream serves a server streaming call with its call context
+func (s *Server) interceptStream(
+	srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return s.serve(stream.Context(), info.FullMethod, func(callCtx context.Context) error {
+		return handler(srv, &callStream{ServerStream: stream, ctx: callCtx})
+	}, stream.SetTrailer)
+}
+
+// callStream is a server stream with the call context
+type callStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (c *callStream) Context() context.Context {
+	return c.ctx
+}
+
+// serve authenticates and rate limits a call, then handles it recording its usage and metrics,
+// the errors are returned as statuses (with the api error code in the trailers) and the panics as internal errors
+func (s *Server) serve(ctx context.Context, fullMethod string, handle func(context.Context) error, setTrailer func(metadata.MD)) error {
+	started := time.Now()
+	name := path.Base(fullMethod)
+	logger := s.logger.Logger.WithField("grpc-method", name)
+
+	err := func() (err error) {
+		// a panic fails the call with an internal error instead of crashing the server, as the gin recovery does
+		defer func() {
+			if r := recover(); r != nil {
+				err = errors.Errorf("panic: %v\n%s", r, debug.Stack())
+			}
+		}()
+		call, usage, err := s.newCall(ctx, logger)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := usage.Record(); err != nil {
+				logger.Errorf("Could not record the usage: %v", err)
+			}
+		}()
+		return handle(context.WithValue(ctx, callKey{}, call))
+	}()
+
+	code := codes.OK
+	if err != nil {
+		callStatus, trailer := statusOf(err)
+		if callStatus.Code() == codes.Internal {
+			logger.Errorf("gRPC call failed: %v", err)
+		}
+		if trailer != nil {
+			setTrailer(trailer)
+		}
+		code = callStatus.Code()
+		err = callStatus.Err()
+	}
+	grpcRequests.WithLabelValues(name, strconv.Itoa(int(code))).Inc()
+	grpcDuration.WithLabelValues(name).Observe(time.Since(started).Seconds())
+	return err
+}
+
+// newCall authenticates and rate limits a call, returning the call and its usage (nil if not metered)
+func (s *Server) newCall(ctx context.Context, logger *logrus.Entry) (*call, *api.Usage, error) {
+	db := s.orm.GetDB()
+	now := time.Now().UTC()
+	r := newAuthRequest(ctx)
+	principal, err := api.AuthenticateRequest(s.authenticator, db, r, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !principal.Role.Includes(api.RoleReader) {
+		cause := errors.Errorf("%s role required", api.RoleReader)
+		if principal.Method == api.AuthMethodAnonymous {
+			return nil, nil, api.NewUnauthorizedError(cause)
+		}
+		return nil, nil, api.NewForbiddenError(cause)
+	}
+	clientIP := api.ClientIP(r)
+	if ok, retryAfter := s.limiter.Allow(principal, clientIP, now); !ok {
+		return nil, nil, api.NewTooManyRequestsError(errors.New("rate limit exceeded"), retryAfter)
+	}
+	usage := s.limiter.NewUsage(db, principal, clientIP, now)
+	return &call{
+		service: &api.AssetService{
+			Assets: s.assets,
+			Oracle: s.oracle,
+			DB:     db,
+			Cache:  s.cache,
+			Crypto: s.crypto,
+			Feed:   s.feed,
+			Logger: logger.WithField("principal", principal.ID),
+			Usage:  usage,
+		},
+	}, usage, nil
+}
+
+// newAuthRequest returns a request with the metadata of a call as headers and the TLS state of its connection,
+// to authenticate the call as a REST request
+func newAuthRequest(ctx context.Context) *http.Request {
+	r := &http.Request{Header: http.Header{}}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			for _, value := range values {
+				r.Header.Add(key, value)
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+	return r
+}
@@ -0,0 +1,206 @@
+package grpcapi_test
+
+import (
+	"context"
+	"net"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/grpcapi"
+	"p2pderivatives-oracle/internal/oracle"
+	"p2pderivatives-oracle/test"
+	mock_datafeed "p2pderivatives-oracle/test/mock/datafeed"
+	mock_dlccrypto "p2pderivatives-oracle/test/mock/dlccrypto"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testPublicKey = "c06fd4dee6502848b937840019effbab0856a227d984785367b079969471a6ed"
+	testKvalue    = "d1e3dcdda619833ec2b91d4fd304e9be0ad85326c9f524dfbc53f443ab54063e"
+	testRvalue    = "44b1350439fc9a098db6edd5bd417eb1aeaa17ec60f9e5a799605feebd5c19eb"
+	testSignature = "44b1350439fc9a098db6edd5bd417eb1aeaa17ec60f9e5a799605feebd5c19ebf742bea67ff64f738c0426d04a22b30fe61258e074c0c90a1b13ce29d11f4b67"
+	testAPIKey    = "reader secret key"
+)
+
+var testAssetConfig = api.AssetConfig{
+	Asset:     "btc",
+	Currency:  "usd",
+	StartDate: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+	Frequency: time.Hour,
+	RangeD:    48 * time.Hour,
+}
+
+func SetupGRPCServer(t *testing.T, ctrl *gomock.Controller) (grpcapi.OracleClient, *mock_dlccrypto.MockCryptoService, *mock_datafeed.MockDataFeed) {
+	privateKey, err := dlccrypto.NewPrivateKey(testKvalue)
+	require.NoError(t, err)
+	publicKey, err := dlccrypto.NewSchnorrPublicKey(testPublicKey)
+	require.NoError(t, err)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	orm := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{}, &entity.APIKey{}, &entity.APIUsage{})
+	require.NoError(t, api.SeedAssets(orm.GetDB(), map[string]api.AssetConfig{"btcusd": testAssetConfig}))
+
+	config := &api.Config{
+		AuthAnonymousRole:  string(api.RoleNone),
+		AuthAPIKeys:        map[string]api.APIKeyConfig{"client": {SHA256: api.HashAPIKey(testAPIKey), Role: string(api.RoleReader)}},
+		StreamPollInterval: 10 * time.Millisecond,
+	}
+	server, err := grpcapi.NewServer(config, test.NewLogger(), &oracle.Oracle{PrivateKey: privateKey, PublicKey: publicKey},
+		orm, api.StaticAssets{"btcusd": testAssetConfig}, crypto, feed)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcServer := server.NewGRPCServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return grpcapi.NewOracleClient(conn), crypto, feed
+}
+
+// withKey returns a context sending the api key of the test client
+func withKey() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), api.HeaderAPIKey, testAPIKey)
+}
+
+// assertStatus asserts the status code of a failed call and returns its api error code
+func assertStatus(t *testing.T, expected codes.Code, err error, trailer metadata.MD) string {
+	assert.Equal(t, expected, status.Code(err), err)
+	if errorCodes := trailer.Get(grpcapi.TrailerErrorCode); len(errorCodes) > 0 {
+		return errorCodes[0]
+	}
+	return ""
+}
+
+func TestServer_Unauthenticated_ReturnsUnauthenticated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client, _, _ := SetupGRPCServer(t, ctrl)
+
+	var trailer metadata.MD
+	publicKey, err := client.GetPublicKey(context.Background(), &grpcapi.GetPublicKeyRequest{}, grpc.Trailer(&trailer))
+
+	errorCode := assertStatus(t, codes.Unauthenticated, err, trailer)
+	assert.Equal(t, strconv.Itoa(api.UnauthorizedErrorCode), errorCode)
+	assert.Nil(t, publicKey)
+}
+
+func TestServer_AssetMethods_ReturnAssetsAndConfig(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client, _, _ := SetupGRPCServer(t, ctrl)
+
+	publicKey, err := client.GetPublicKey(withKey(), &grpcapi.GetPublicKeyRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, testPublicKey, publicKey.PublicKey)
+
+	assets, err := client.ListAssets(withKey(), &grpcapi.ListAssetsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"btcusd"}, assets.AssetIds)
+
+	config, err := client.GetAssetConfig(withKey(), &grpcapi.GetAssetConfigRequest{AssetId: "btcusd"})
+	require.NoError(t, err)
+	assert.Equal(t, "btc", config.Asset)
+	assert.Equal(t, "PT1H", config.Frequency)
+	assert.Equal(t, "close", config.Settlement)
+
+	var trailer metadata.MD
+	_, err = client.GetAssetConfig(withKey(), &grpcapi.GetAssetConfigRequest{AssetId: "unknown"}, grpc.Trailer(&trailer))
+	errorCode := assertStatus(t, codes.NotFound, err, trailer)
+	assert.Equal(t, strconv.Itoa(api.RecordNotFoundDBErrorCode), errorCode)
+}
+
+func TestServer_EventMethods_AnnounceAttestListAndStream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client, crypto, feed := SetupGRPCServer(t, ctrl)
+	kvalue, _ := dlccrypto.NewPrivateKey(testKvalue)
+	rvalue, _ := dlccrypto.NewSchnorrPublicKey(testRvalue)
+	signature, _ := dlccrypto.NewSignature(testSignature)
+	value := 100.06
+	crypto.EXPECT().GenerateSchnorrKeyPair().Return(kvalue, rvalue, nil).Times(1)
+	crypto.EXPECT().ComputeSchnorrSignature(gomock.Any(), gomock.Any(), "100").Return(signature, nil).Times(1)
+	feed.EXPECT().FindPastAssetPrice("btc", "usd", gomock.Any()).Return(&value, nil).Times(1)
+
+	// requested between two publications, the event of the next publication is returned
+	requested, _ := ptypes.TimestampProto(testAssetConfig.StartDate.Add(90 * time.Minute))
+	event, err := client.GetRvalue(withKey(), &grpcapi.EventRequest{AssetId: "btcusd", PublishDate: requested})
+	require.NoError(t, err)
+	assert.Equal(t, testRvalue, event.Rvalue)
+	assert.Equal(t, "digits", event.EventType)
+	assert.Equal(t, api.EventStatusUnsigned, event.Status)
+	assert.Equal(t, "btcusd:digits:2020-01-01T02:00:00Z", event.EventId)
+
+	event, err = client.GetSignature(withKey(), &grpcapi.EventRequest{AssetId: "btcusd", PublishDate: requested})
+	require.NoError(t, err)
+	assert.Equal(t, testSignature, event.Signature)
+	assert.Equal(t, "100", event.Value)
+	assert.Equal(t, api.EventStatusSigned, event.Status)
+
+	page, err := client.ListEvents(withKey(), &grpcapi.ListEventsRequest{AssetId: "btcusd", Status: api.EventStatusSigned})
+	require.NoError(t, err)
+	if assert.Len(t, page.Events, 1) {
+		assert.Equal(t, testSignature, page.Events[0].Signature)
+	}
+	var trailer metadata.MD
+	_, err = client.ListEvents(withKey(), &grpcapi.ListEventsRequest{AssetId: "btcusd", Status: "unknown"}, grpc.Trailer(&trailer))
+	assertStatus(t, codes.InvalidArgument, err, trailer)
+
+	// the announcement is skipped and the stream ends with the deadline of the call
+	ctx, cancel := context.WithTimeout(withKey(), 200*time.Millisecond)
+	defer cancel()
+	stream, err := client.StreamAttestations(ctx, &grpcapi.StreamAttestationsRequest{Cursor: "0"})
+	require.NoError(t, err)
+	notification, err := stream.Recv()
+	if assert.NoError(t, err) {
+		assert.Equal(t, entity.EventNotificationAttested, notification.Type)
+		assert.Equal(t, testSignature, notification.Event.Signature)
+	}
+	_, err = stream.Recv()
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestServer_GetSignature_BeforePublishDate_ReturnsFailedPrecondition(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client, crypto, _ := SetupGRPCServer(t, ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Times(0)
+
+	future, _ := ptypes.TimestampProto(time.Now().Add(2 * time.Hour))
+	var trailer metadata.MD
+	_, err := client.GetSignature(withKey(), &grpcapi.EventRequest{AssetId: "btcusd", PublishDate: future}, grpc.Trailer(&trailer))
+
+	errorCode := assertStatus(t, codes.FailedPrecondition, err, trailer)
+	assert.Equal(t, strconv.Itoa(api.InvalidTimeTooEarlyBadRequestErrorCode), errorCode)
+	assert.Contains(t, status.Convert(err).Message(), "retry after")
+}
+
+func TestServer_HandlerPanics_ReturnsInternalAndKeepsServing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client, crypto, _ := SetupGRPCServer(t, ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Do(func() { panic("key generation failed") }).Times(1)
+
+	requested, _ := ptypes.TimestampProto(testAssetConfig.StartDate)
+	var trailer metadata.MD
+	_, err := client.GetRvalue(withKey(), &grpcapi.EventRequest{AssetId: "btcusd", PublishDate: requested}, grpc.Trailer(&trailer))
+
+	assertStatus(t, codes.Internal, err, trailer)
+	assert.Equal(t, "internal error", status.Convert(err).Message())
+	publicKey, err := client.GetPublicKey(withKey(), &grpcapi.GetPublicKeyRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, testPublicKey, publicKey.PublicKey)
+}
@@ -0,0 +1,209 @@
+package grpcapi
+
+import (
+	"context"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/pkg/errors"
+)
+
+var _ OracleServer = (*Server)(nil)
+
+// GetPublicKey returns the public key of the oracle
+func (s *Server) GetPublicKey(ctx context.Context, _ *GetPublicKeyRequest) (*PublicKey, error) {
+	return &PublicKey{PublicKey: callOf(ctx).service.Oracle.PublicKey.EncodeToString()}, nil
+}
+
+// ListAssets returns the ids of the available assets
+func (s *Server) ListAssets(ctx context.Context, _ *ListAssetsRequest) (*ListAssetsResponse, error) {
+	assetIDs, err := callOf(ctx).service.AssetIDs()
+	if err != nil {
+		return nil, err
+	}
+	return &ListAssetsResponse{AssetIds: assetIDs}, nil
+}
+
+// GetAssetConfig returns the configuration of an asset
+func (s *Server) GetAssetConfig(ctx context.Context, request *GetAssetConfigRequest) (*AssetConfig, error) {
+	config, err := callOf(ctx).service.AssetConfig(request.AssetId)
+	if err != nil {
+		return nil, err
+	}
+	return newAssetConfig(api.NewAssetConfigResponse(config)), nil
+}
+
+// GetRvalue returns the event published at the first publish date at or after the requested date, announcing it if needed
+func (s *Server) GetRvalue(ctx context.Context, request *EventRequest) (*Event, error) {
+	service := callOf(ctx).service
+	config, publishDate, err := parseEventRequest(service, request)
+	if err != nil {
+		return nil, err
+	}
+	dlcData, err := service.Rvalue(ctx, request.AssetId, config, request.EventType, publishDate)
+	if err != nil {
+		return nil, err
+	}
+	return newEvent(api.NewDLCDataResponse(service.Oracle.PublicKey, dlcData)), nil
+}
+
+// GetSignature returns the attested event published at the first publish date at or after the requested date
+func (s *Server) GetSignature(ctx context.Context, request *EventRequest) (*Event, error) {
+	service := callOf(ctx).service
+	config, publishDate, err := parseEventRequest(service, request)
+	if err != nil {
+		return nil, err
+	}
+	wait, err := api.ParseWait(request.Wait)
+	if err != nil {
+		return nil, err
+	}
+	dlcData, err := service.Signature(ctx, request.AssetId, config, request.EventType, publishDate, wait)
+	if err != nil {
+		return nil, err
+	}
+	return newEvent(api.NewDLCDataResponse(service.Oracle.PublicKey, dlcData)), nil
+}
+
+// ListEvents returns a page of the announced events of an asset sorted by publish date
+func (s *Server) ListEvents(ctx context.Context, request *ListEventsRequest) (*ListEventsResponse, error) {
+	service := callOf(ctx).service
+	if _, err := service.AssetConfig(request.AssetId); err != nil {
+		return nil, err
+	}
+	query := &api.EventsQuery{
+		EventType: request.EventType,
+		Status:    request.Status,
+		Cursor:    request.Cursor,
+		Limit:     int(request.Limit),
+	}
+	var err error
+	if query.From, err = optionalTime(request.From, api.URLQueryTagFrom); err != nil {
+		return nil, err
+	}
+	if query.To, err = optionalTime(request.To, api.URLQueryTagTo); err != nil {
+		return nil, err
+	}
+	dlcDataList, nextCursor, err := service.Events(request.AssetId, query)
+	if err != nil {
+		return nil, err
+	}
+	response := &ListEventsResponse{Events: make([]*Event, len(dlcDataList)), NextCursor: nextCursor}
+	for i := range dlcDataList {
+		response.Events[i] = newEvent(api.NewDLCDataResponse(service.Oracle.PublicKey, &dlcDataList[i]))
+	}
+	return response, nil
+}
+
+// StreamAttestations sends the attestation notifications until the call is done, the announcements are skipped
+func (s *Server) StreamAttestations(request *StreamAttestationsRequest, stream Oracle_StreamAttestationsServer) error {
+	ctx := stream.Context()
+	service := callOf(ctx).service
+	filter, err := api.NewStreamFilter(s.assets, service.DB, request.AssetIds, request.EventTypes, request.Cursor)
+	if err != nil {
+		return err
+	}
+	sendAttestation := func(notification *api.EventNotificationResponse) error {
+		if notification.Type != entity.EventNotificationAttested {
+			return nil
+		}
+		createdAt, err := ptypes.TimestampProto(notification.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return stream.Send(&EventNotification{
+			Sequence:  notification.Sequence,
+			Type:      notification.Type,
+			CreatedAt: createdAt,
+			Event:     newEvent(notification.Event),
+		})
+	}
+	// the HTTP/2 connection is kept alive by the transport
+	heartbeat := func() error { return nil }
+	err = api.StreamNotifications(
+		ctx, service.DB, service.Oracle.PublicKey, filter, s.pollInterval, s.heartbeat, s.gapTimeout, sendAttestation, heartbeat)
+	if err != nil {
+		return err
+	}
+	// the stream ends when the call is cancelled or its deadline is exceeded
+	return ctx.Err()
+}
+
+// parseEventRequest returns the configuration of the requested asset and the requested publish date
+func parseEventRequest(service *api.AssetService, request *EventRequest) (api.AssetConfig, time.Time, error) {
+	config, err := service.AssetConfig(request.AssetId)
+	if err != nil {
+		return config, time.Time{}, err
+	}
+	if request.PublishDate == nil {
+		cause := errors.New("publish_date is required")
+		return config, time.Time{}, api.NewBadRequestError(api.InvalidTimeFormatBadRequestErrorCode, cause, "publish_date")
+	}
+	publishDate, err := optionalTime(request.PublishDate, "publish_date")
+	return config, publishDate, err
+}
+
+// optionalTime returns the UTC time of a timestamp, zero if not set
+func optionalTime(ts *timestamp.Timestamp, field string) (time.Time, error) {
+	if ts == nil {
+		return time.Time{}, nil
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return time.Time{}, api.NewBadRequestError(api.InvalidTimeFormatBadRequestErrorCode, err, field)
+	}
+	return t.UTC(), nil
+}
+
+// newEvent transforms the api representation of an event to its gRPC message
+func newEvent(event *api.DLCDataResponse) *Event {
+	status := api.EventStatusUnsigned
+	if event.Signature != "" {
+		status = api.EventStatusSigned
+	}
+	publishDate, _ := ptypes.TimestampProto(event.PublishedDate)
+	return &Event{
+		EventId:         api.NewEventID(event.AssetID, event.EventType, event.PublishedDate),
+		OraclePublicKey: event.OraclePublicKey,
+		PublishDate:     publishDate,
+		AssetId:         event.AssetID,
+		EventType:       event.EventType,
+		Rvalue:          event.Rvalue,
+		Signature:       event.Signature,
+		Value:           event.Value,
+		Settlement:      event.Settlement,
+		Status:          status,
+	}
+}
+
+// newAssetConfig transforms the api representation of an asset configuration to its gRPC message
+func newAssetConfig(config *api.AssetConfigResponse) *AssetConfig {
+	startDate, _ := ptypes.TimestampProto(config.StartDate)
+	message := &AssetConfig{
+		Asset:       config.Asset,
+		Currency:    config.Currency,
+		HasDecimals: config.HasDecimals,
+		StartDate:   startDate,
+		Frequency:   config.Frequency,
+		Range:       config.RangeD,
+		EventTypes:  config.EventTypes,
+		Settlement:  config.Settlement,
+	}
+	for _, composition := range config.Index {
+		effectiveFrom, _ := ptypes.TimestampProto(composition.EffectiveFrom)
+		version := &IndexComposition{Version: composition.Version, EffectiveFrom: effectiveFrom}
+		for _, component := range composition.Components {
+			version.Components = append(version.Components, &IndexComponent{
+				Name:     component.Name,
+				Asset:    component.Asset,
+				Currency: component.Currency,
+				Weight:   component.Weight,
+			})
+		}
+		message.Index = append(message.Index, version)
+	}
+	return message
+}
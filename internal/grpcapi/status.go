@@ -0,0 +1,55 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"p2pderivatives-oracle/internal/api"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TrailerErrorCode trailer containing the api error code of a failed call
+const TrailerErrorCode = "oracle-error-code"
+
+// statusCodes status codes of the api errors by HTTP status code
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:      codes.InvalidArgument,
+	http.StatusUnauthorized:    codes.Unauthenticated,
+	http.StatusForbidden:       codes.PermissionDenied,
+	http.StatusNotFound:        codes.NotFound,
+	http.StatusConflict:        codes.AlreadyExists,
+	http.StatusTooEarly:        codes.FailedPrecondition,
+	http.StatusTooManyRequests: codes.ResourceExhausted,
+}
+
+// statusOf returns the status of a call which failed with err,
+// and the trailer containing the api error code if the call failed with an api error
+func statusOf(err error) (*status.Status, metadata.MD) {
+	if s, ok := status.FromError(err); ok {
+		return s, nil
+	}
+	if cause, ok := err.(*api.Error); ok {
+		code, ok := statusCodes[cause.HTTPStatusCode]
+		if !ok {
+			code = codes.Internal
+		}
+		message := cause.ClientMessage
+		if cause.RetryAfter > 0 {
+			message = fmt.Sprintf("%s, retry after %s", message, cause.RetryAfter.Round(time.Second))
+		}
+		return status.New(code, message), metadata.Pairs(TrailerErrorCode, strconv.Itoa(cause.ErrorCode))
+	}
+	switch errors.Cause(err) {
+	case context.Canceled:
+		return status.New(codes.Canceled, err.Error()), nil
+	case context.DeadlineExceeded:
+		return status.New(codes.DeadlineExceeded, err.Error()), nil
+	}
+	return status.New(codes.Internal, "internal error"), nil
+}
@@ -0,0 +1,178 @@
+package httpfeed
+
+import (
+	"bytes"
+	"encoding/json"
+	"p2pderivatives-oracle/internal/datafeed"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+)
+
+// NewClient returns a new generic json http Client for a source (not initialized)
+func NewClient(name string, config *SourceConfig) *Client {
+	return &Client{
+		name:        name,
+		config:      config,
+		initialized: false,
+	}
+}
+
+// NewDataFeeds returns an initialized client for each asset symbol served by the configured sources
+// an asset can only be served by a single source
+func NewDataFeeds(config *Config) (map[string]datafeed.DataFeed, error) {
+	feeds := map[string]datafeed.DataFeed{}
+	for name, sourceConfig := range config.Sources {
+		sourceConfig := sourceConfig
+		client := NewClient(name, &sourceConfig)
+		client.Initialize()
+		for _, asset := range sourceConfig.Assets {
+			asset = strings.ToLower(asset)
+			if _, ok := feeds[asset]; ok {
+				return nil, errors.Errorf("asset %s is served by more than one http source", asset)
+			}
+			feeds[asset] = client
+		}
+	}
+	return feeds, nil
+}
+
+// Client represents a generic json http client extracting values using path expressions
+type Client struct {
+	datafeed.DataFeed
+	name        string
+	config      *SourceConfig
+	httpClient  *resty.Client
+	initialized bool
+}
+
+// Initialize initializes the http client
+func (c *Client) Initialize() {
+	c.httpClient = resty.New()
+	c.httpClient.SetHeader("Accept", "application/json")
+	if c.config.APIKeyHeader != "" {
+		c.httpClient.SetHeader(c.config.APIKeyHeader, c.config.APIKey)
+	}
+	c.initialized = true
+}
+
+// IsInitialized returns true if the Client has been initialized
+func (c *Client) IsInitialized() bool {
+	return c.initialized
+}
+
+// FindCurrentAssetPrice sends a GET request to the current url of the source to retrieve the current value of an asset
+func (c *Client) FindCurrentAssetPrice(assetID string, currency string) (*float64, error) {
+	url := c.config.CurrentURL
+	if url == "" {
+		url = c.config.URL
+	}
+	return c.getValue(url, assetID, currency, time.Now().UTC())
+}
+
+// FindPastAssetPrice sends a GET request to the url of the source to retrieve a past value of an asset
+func (c *Client) FindPastAssetPrice(assetID string, currency string, date time.Time) (*float64, error) {
+	if time.Now().Before(date) {
+		return nil, errors.New("date should be before now")
+	}
+	return c.getValue(c.config.URL, assetID, currency, date)
+}
+
+// FindPastAssetCandles samples the source at the configured candle interval
+// between from (excluded) and to (included), each sample being a candle without volume
+func (c *Client) FindPastAssetCandles(assetID string, currency string, from time.Time, to time.Time) ([]datafeed.Candle, error) {
+	if c.config.CandleInterval <= 0 {
+		return nil, errors.Errorf("candles are not supported by http source %s", c.name)
+	}
+	if time.Now().Before(to) {
+		return nil, errors.New("date should be before now")
+	}
+	if !from.Before(to) {
+		return nil, errors.New("from date should be before to date")
+	}
+	candles := []datafeed.Candle{}
+	for date := to; date.After(from); date = date.Add(-c.config.CandleInterval) {
+		value, err := c.getValue(c.config.URL, assetID, currency, date)
+		if err != nil {
+			return nil, err
+		}
+		candles = append([]datafeed.Candle{{
+			Time:  date,
+			Open:  *value,
+			High:  *value,
+			Low:   *value,
+			Close: *value,
+		}}, candles...)
+	}
+	return candles, nil
+}
+
+// metricsProvider returns the provider name of the datafeed metrics
+func (c *Client) metricsProvider() string {
+	return "http:" + c.name
+}
+
+func (c *Client) getValue(urlTemplate string, assetID string, currency string, date time.Time) (*float64, error) {
+	if !c.IsInitialized() {
+		return nil, errors.Errorf("http source %s client is not initialized", c.name)
+	}
+	url := expandTemplate(urlTemplate, assetID, currency, date)
+	started := time.Now()
+	resp, err := c.httpClient.R().Get(url)
+	if err != nil {
+		datafeed.ObserveCall(c.metricsProvider(), started, err)
+		return nil, errors.WithMessagef(err, "error while sending a request to http source %s", c.name)
+	}
+	if resp.IsError() {
+		err := errors.Errorf("http source %s returned status %d: %s", c.name, resp.StatusCode(), resp.String())
+		datafeed.ObserveCall(c.metricsProvider(), started, err)
+		return nil, err
+	}
+	datafeed.ObserveCall(c.metricsProvider(), started, nil)
+
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(resp.Body()))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, errors.WithMessagef(err, "invalid json response from http source %s", c.name)
+	}
+
+	if c.config.UnitPath != "" {
+		unitPath := expandTemplate(c.config.UnitPath, assetID, currency, date)
+		unit, err := extractString(document, unitPath)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "http source %s", c.name)
+		}
+		expected := expandTemplate(c.config.Unit, assetID, currency, date)
+		if !strings.EqualFold(unit, expected) {
+			return nil, errors.Errorf("http source %s returned unit %s, expected %s", c.name, unit, expected)
+		}
+	}
+
+	valuePath := expandTemplate(c.config.ValuePath, assetID, currency, date)
+	value, err := extractFloat(document, valuePath)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "http source %s", c.name)
+	}
+	if c.config.Scale != 0 {
+		value *= c.config.Scale
+	}
+	return &value, nil
+}
+
+func expandTemplate(template string, assetID string, currency string, date time.Time) string {
+	date = date.UTC()
+	return strings.NewReplacer(
+		"{asset}", strings.ToLower(assetID),
+		"{currency}", strings.ToLower(currency),
+		"{ASSET}", strings.ToUpper(assetID),
+		"{CURRENCY}", strings.ToUpper(currency),
+		"{unixMs}", strconv.FormatInt(date.UnixNano()/int64(time.Millisecond), 10),
+		"{unix}", strconv.FormatInt(date.Unix(), 10),
+		"{date}", date.Format(time.RFC3339),
+		"{day}", date.Format("2006-01-02"),
+	).Replace(template)
+}
@@ -0,0 +1,32 @@
+package httpfeed
+
+import "time"
+
+// Config represents the generic http datafeed configuration, a set of sources indexed by name
+type Config struct {
+	Sources map[string]SourceConfig `configkey:"http" validate:"required,min=1,dive"`
+}
+
+// SourceConfig represents a json http data source
+// the url, value path and unit can contain the placeholders:
+// {asset}, {currency} (lower case), {ASSET}, {CURRENCY} (upper case),
+// {unix} (unix seconds), {unixMs} (unix milliseconds), {date} (RFC3339) and {day} (YYYY-MM-DD)
+type SourceConfig struct {
+	// Assets list of the asset symbols served by this source
+	Assets []string `configkey:"assets" validate:"required,min=1"`
+	// URL templated url used to retrieve a past value
+	URL string `configkey:"url" validate:"required"`
+	// CurrentURL templated url used to retrieve the current value (URL at the current time is used if not set)
+	CurrentURL string `configkey:"currentUrl"`
+	// ValuePath path expression (ex: data.0.close) of the value in the json response
+	ValuePath string `configkey:"valuePath" validate:"required"`
+	// UnitPath path expression of the unit in the json response, validated against Unit
+	UnitPath string `configkey:"unitPath"`
+	Unit     string `configkey:"unit" validate:"required_with=UnitPath"`
+	// Scale factor applied to the extracted value (1 if not set)
+	Scale        float64 `configkey:"scale"`
+	APIKeyHeader string  `configkey:"apiKeyHeader"`
+	APIKey       string  `configkey:"apiKey" validate:"required_with=APIKeyHeader"`
+	// CandleInterval interval used to sample the source to build candles (candles not supported if not set)
+	CandleInterval time.Duration `configkey:"candleInterval,duration,iso8601"`
+}
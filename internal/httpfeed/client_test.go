@@ -0,0 +1,184 @@
+package httpfeed_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/httpfeed"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+var testDate = time.Date(2020, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+func NewTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+		switch r.URL.Path {
+		case "/price/btc/usd":
+			fmt.Fprintf(w, `{"data":{"unit":"USD","rates":[{"value":"9000.5"},{"value":9100}]}}`)
+		case fmt.Sprintf("/history/BTC/%d", testDate.Unix()):
+			fmt.Fprintf(w, `{"data":{"unit":"USD","rates":[{"value":8000}]}}`)
+		case fmt.Sprintf("/history/BTC/%d", testDate.Add(-time.Minute).Unix()):
+			fmt.Fprintf(w, `{"data":{"unit":"USD","rates":[{"value":7000}]}}`)
+		case "/history/ETH/" + fmt.Sprint(testDate.Unix()):
+			fmt.Fprintf(w, `{"data":{"unit":"EUR","rates":[{"value":200}]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func NewTestSourceConfig(serverURL string) *httpfeed.SourceConfig {
+	return &httpfeed.SourceConfig{
+		Assets:         []string{"btc", "eth"},
+		URL:            serverURL + "/history/{ASSET}/{unix}",
+		CurrentURL:     serverURL + "/price/{asset}/{currency}",
+		ValuePath:      "data.rates.-1.value",
+		UnitPath:       "data.unit",
+		Unit:           "{CURRENCY}",
+		APIKeyHeader:   "X-Api-Key",
+		APIKey:         "secret",
+		CandleInterval: time.Minute,
+	}
+}
+
+func TestClient_FindCurrentAssetPrice_NotInitialized_ReturnsError(t *testing.T) {
+	client := httpfeed.NewClient("test", NewTestSourceConfig("http://localhost"))
+	assert.False(t, client.IsInitialized())
+	val, err := client.FindCurrentAssetPrice("btc", "usd")
+	assert.Error(t, err)
+	assert.Nil(t, val)
+}
+
+func TestClient_FindCurrentAssetPrice_ReturnsLastArrayElement(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	client := httpfeed.NewClient("test", NewTestSourceConfig(server.URL))
+	client.Initialize()
+
+	val, err := client.FindCurrentAssetPrice("btc", "usd")
+	assert.NoError(t, err)
+	assert.Equal(t, 9100.0, *val)
+}
+
+func TestClient_FindCurrentAssetPrice_WithNumericString_ReturnsValue(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	config := NewTestSourceConfig(server.URL)
+	config.ValuePath = "data.rates.0.value"
+	client := httpfeed.NewClient("test", config)
+	client.Initialize()
+
+	val, err := client.FindCurrentAssetPrice("btc", "usd")
+	assert.NoError(t, err)
+	assert.Equal(t, 9000.5, *val)
+}
+
+func TestClient_FindPastAssetPrice_WithScale_ReturnsScaledValue(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	config := NewTestSourceConfig(server.URL)
+	config.Scale = 0.5
+	client := httpfeed.NewClient("test", config)
+	client.Initialize()
+
+	val, err := client.FindPastAssetPrice("btc", "usd", testDate)
+	assert.NoError(t, err)
+	assert.Equal(t, 4000.0, *val)
+}
+
+func TestClient_FindPastAssetPrice_UnitMismatch_ReturnsError(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	client := httpfeed.NewClient("test", NewTestSourceConfig(server.URL))
+	client.Initialize()
+
+	val, err := client.FindPastAssetPrice("eth", "usd", testDate)
+	assert.Error(t, err)
+	assert.Nil(t, val)
+}
+
+func TestClient_FindPastAssetPrice_InvalidPath_ReturnsError(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	config := NewTestSourceConfig(server.URL)
+	config.ValuePath = "data.rates.3.value"
+	client := httpfeed.NewClient("test", config)
+	client.Initialize()
+
+	val, err := client.FindPastAssetPrice("btc", "usd", testDate)
+	assert.Error(t, err)
+	assert.Nil(t, val)
+}
+
+func TestClient_FindPastAssetPrice_ErrorStatus_ReturnsError(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	client := httpfeed.NewClient("errors", NewTestSourceConfig(server.URL))
+	client.Initialize()
+
+	val, err := client.FindPastAssetPrice("link", "usd", testDate)
+	assert.Error(t, err)
+	assert.Nil(t, val)
+
+	resp := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, resp.Body.String(), `oracle_datafeed_errors_total{provider="http:errors"} 1`)
+	assert.Contains(t, resp.Body.String(), `oracle_datafeed_request_duration_seconds_count{provider="http:errors"} 1`)
+}
+
+func TestClient_FindPastAssetCandles_SamplesAtCandleInterval(t *testing.T) {
+	server := NewTestServer(t)
+	defer server.Close()
+	client := httpfeed.NewClient("test", NewTestSourceConfig(server.URL))
+	client.Initialize()
+
+	candles, err := client.FindPastAssetCandles("btc", "usd", testDate.Add(-2*time.Minute), testDate)
+	assert.NoError(t, err)
+	if assert.Len(t, candles, 2) {
+		assert.Equal(t, testDate.Add(-time.Minute), candles[0].Time)
+		assert.Equal(t, 7000.0, candles[0].Close)
+		assert.Equal(t, testDate, candles[1].Time)
+		assert.Equal(t, 8000.0, candles[1].Close)
+	}
+}
+
+func TestClient_FindPastAssetCandles_NoCandleInterval_ReturnsError(t *testing.T) {
+	config := NewTestSourceConfig("http://localhost")
+	config.CandleInterval = 0
+	client := httpfeed.NewClient("test", config)
+	client.Initialize()
+
+	candles, err := client.FindPastAssetCandles("btc", "usd", testDate.Add(-time.Hour), testDate)
+	assert.Error(t, err)
+	assert.Nil(t, candles)
+}
+
+func TestNewDataFeeds_DuplicatedAsset_ReturnsError(t *testing.T) {
+	config := &httpfeed.Config{
+		Sources: map[string]httpfeed.SourceConfig{
+			"first":  *NewTestSourceConfig("http://localhost"),
+			"second": *NewTestSourceConfig("http://localhost"),
+		},
+	}
+	feeds, err := httpfeed.NewDataFeeds(config)
+	assert.Error(t, err)
+	assert.Nil(t, feeds)
+}
+
+func TestNewDataFeeds_ReturnsFeedPerAsset(t *testing.T) {
+	config := &httpfeed.Config{
+		Sources: map[string]httpfeed.SourceConfig{
+			"first": *NewTestSourceConfig("http://localhost"),
+		},
+	}
+	feeds, err := httpfeed.NewDataFeeds(config)
+	assert.NoError(t, err)
+	assert.Len(t, feeds, 2)
+	assert.Contains(t, feeds, "btc")
+	assert.Contains(t, feeds, "eth")
+}
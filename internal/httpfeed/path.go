@@ -0,0 +1,101 @@
+package httpfeed
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// extractPath returns the element of a decoded json document matching the path expression
+// the expression is a dot separated list of object keys and array indexes
+// (negative indexes start from the end of the array, a dot in a key can be escaped with \.)
+// ex: data.rates.USD, data.0.close, result.-1.value
+func extractPath(document interface{}, path string) (interface{}, error) {
+	current := document
+	for _, key := range splitPath(path) {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, errors.Errorf("key %s not found for path %s", key, path)
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, errors.Errorf("invalid array index %s for path %s", key, path)
+			}
+			if index < 0 {
+				index += len(node)
+			}
+			if index < 0 || index >= len(node) {
+				return nil, errors.Errorf("array index %s out of range for path %s", key, path)
+			}
+			current = node[index]
+		default:
+			return nil, errors.Errorf("cannot access %s of a scalar value for path %s", key, path)
+		}
+	}
+	return current, nil
+}
+
+// extractFloat returns the number matching the path expression (numeric strings are accepted)
+func extractFloat(document interface{}, path string) (float64, error) {
+	value, err := extractPath(document, path)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case json.Number:
+		return v.Float64()
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, errors.Errorf("value %s at path %s is not a number", v, path)
+		}
+		return f, nil
+	default:
+		return 0, errors.Errorf("value at path %s is not a number", path)
+	}
+}
+
+// extractString returns the string matching the path expression
+func extractString(document interface{}, path string) (string, error) {
+	value, err := extractPath(document, path)
+	if err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return "", errors.Errorf("value at path %s is not a string", path)
+	}
+}
+
+func splitPath(path string) []string {
+	keys := []string{}
+	var current strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			current.WriteByte('.')
+			i++
+		case path[i] == '.':
+			keys = append(keys, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(path[i])
+		}
+	}
+	if path != "" {
+		keys = append(keys, current.String())
+	}
+	return keys
+}
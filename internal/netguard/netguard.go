@@ -0,0 +1,109 @@
+package netguard
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// nonPublicNetworks networks of the private, shared (carrier-grade NAT) and unique local addresses
+var nonPublicNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// Resolver resolves the addresses of a host name (implemented by net.Resolver)
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Guard restricts the outgoing requests to urls provided by the api callers (webhooks) to public addresses,
+// so that they cannot reach the services of the oracle network (loopback, private and link-local addresses)
+type Guard struct {
+	// AllowPrivate disables the checks (to reach local services in development)
+	AllowPrivate bool
+	// Resolver resolver of the host names, net.DefaultResolver if nil
+	Resolver Resolver
+}
+
+// CheckIP returns an error if the address is not a public address
+func (g *Guard) CheckIP(ip net.IP) error {
+	if g.AllowPrivate {
+		return nil
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return errors.Errorf("address %s is not a public address", ip.String())
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return errors.Errorf("address %s is not a public address", ip.String())
+		}
+	}
+	return nil
+}
+
+// CheckURL returns an error if the host of the url does not resolve only to public addresses
+func (g *Guard) CheckURL(ctx context.Context, rawURL string) error {
+	if g.AllowPrivate {
+		return nil
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := parsedURL.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return g.CheckIP(ip)
+	}
+	resolver := g.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return errors.WithMessagef(err, "could not resolve %s", host)
+	}
+	for _, addr := range addrs {
+		if err := g.CheckIP(addr.IP); err != nil {
+			return errors.WithMessagef(err, "host %s", host)
+		}
+	}
+	return nil
+}
+
+// Dialer returns a dialer refusing the connections to non public addresses,
+// the address is checked once resolved so that the check cannot be bypassed by a dns change after CheckURL
+func (g *Guard) Dialer(dialer *net.Dialer) *net.Dialer {
+	guarded := *dialer
+	guarded.Control = func(network string, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return errors.Errorf("invalid address %s", address)
+		}
+		return g.CheckIP(ip)
+	}
+	return &guarded
+}
@@ -0,0 +1,60 @@
+package netguard_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/netguard"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testResolver map[string][]string
+
+func (r testResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs := []net.IPAddr{}
+	for _, ip := range r[host] {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func TestGuard_CheckIP_NonPublic_ReturnsError(t *testing.T) {
+	guard := &netguard.Guard{}
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1"} {
+		assert.Error(t, guard.CheckIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"93.184.216.34", "8.8.8.8", "2606:4700::1111"} {
+		assert.NoError(t, guard.CheckIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestGuard_CheckURL_ResolvesHost(t *testing.T) {
+	guard := &netguard.Guard{Resolver: testResolver{
+		"public.example":   {"93.184.216.34"},
+		"internal.example": {"93.184.216.34", "10.0.0.1"},
+	}}
+
+	assert.NoError(t, guard.CheckURL(context.Background(), "https://public.example/hook"))
+	assert.Error(t, guard.CheckURL(context.Background(), "https://internal.example/hook"))
+	assert.Error(t, guard.CheckURL(context.Background(), "http://127.0.0.1:8080/hook"))
+	assert.Error(t, guard.CheckURL(context.Background(), "http://[::1]/hook"))
+}
+
+func TestGuard_Dialer_LoopbackServer_RefusesConnection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	denied := (&netguard.Guard{}).Dialer(&net.Dialer{Timeout: time.Second})
+	allowed := (&netguard.Guard{AllowPrivate: true}).Dialer(&net.Dialer{Timeout: time.Second})
+
+	_, err := denied.Dial("tcp", server.Listener.Addr().String())
+	assert.Error(t, err)
+	conn, err := allowed.Dial("tcp", server.Listener.Addr().String())
+	if assert.NoError(t, err) {
+		conn.Close()
+	}
+}
@@ -0,0 +1,130 @@
+package oracle
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AssetConfig represents one asset configuration delivered by the oracle
+type AssetConfig struct {
+	Asset       string            `configkey:"asset" validate:"required"`
+	Currency    string            `configkey:"currency" validate:"required"`
+	HasDecimals bool              `configkey:"hasDecimals"`
+	StartDate   time.Time         `configkey:"startDate" validate:"required"`
+	Frequency   time.Duration     `configkey:"frequency,duration,iso8601" validate:"required"`
+	RangeD      time.Duration     `configkey:"range,duration,iso8601" validate:"required"`
+	Test        map[string]string `configkey:"test"`
+	EventTypes  map[string]bool   `configkey:"eventTypes"`
+	// Settlement method used to compute the attested value (close, twap(<window>), vwap(<window>), median(<window>))
+	// the window is an ISO8601 duration, if not set close is used
+	Settlement string `configkey:"settlement"`
+	// Index defines the asset as a weighted basket of other assets,
+	// each entry is a composition version (rebalancing) indexed by its version name
+	Index map[string]IndexVersionConfig `configkey:"index"`
+}
+
+// IsIndex returns true if the asset is an index of other assets
+func (c *AssetConfig) IsIndex() bool {
+	return len(c.Index) > 0
+}
+
+// IsAttestable returns false if the asset value cannot be computed by the oracle from a datafeed
+func (c *AssetConfig) IsAttestable() bool {
+	return c.Asset != "election"
+}
+
+// IndexVersionConfig represents a version of an index composition,
+// applicable from EffectiveFrom until the next version becomes effective
+type IndexVersionConfig struct {
+	EffectiveFrom time.Time                       `configkey:"effectiveFrom" validate:"required"`
+	Components    map[string]IndexComponentConfig `configkey:"components" validate:"required,min=1"`
+}
+
+// IndexComponentConfig represents one component of an index composition
+// (the index currency is used if Currency is not set)
+type IndexComponentConfig struct {
+	Asset    string  `configkey:"asset" validate:"required"`
+	Currency string  `configkey:"currency"`
+	Weight   float64 `configkey:"weight" validate:"required"`
+}
+
+// AssetProvider provides the configurations of the assets served by the oracle
+type AssetProvider interface {
+	// AssetConfigs returns the configurations of the assets indexed by asset id
+	AssetConfigs() (map[string]AssetConfig, error)
+}
+
+// StaticAssets is an AssetProvider of a fixed set of assets
+type StaticAssets map[string]AssetConfig
+
+// AssetConfigs returns the configurations of the assets indexed by asset id
+func (s StaticAssets) AssetConfigs() (map[string]AssetConfig, error) {
+	return s, nil
+}
+
+// IndexComposition represents a composition version of an index asset, stored with the attestations using it
+type IndexComposition struct {
+	Version       string            `json:"version"`
+	EffectiveFrom time.Time         `json:"effectiveFrom"`
+	Components    []*IndexComponent `json:"components"`
+}
+
+// IndexComponent represents a weighted component of an index asset
+type IndexComponent struct {
+	Name     string  `json:"name"`
+	Asset    string  `json:"asset"`
+	Currency string  `json:"currency"`
+	Weight   float64 `json:"weight"`
+}
+
+// IndexCompositions returns the index composition versions of an asset sorted by effective date,
+// the components being sorted by name
+func IndexCompositions(config AssetConfig) []*IndexComposition {
+	compositions := make([]*IndexComposition, 0, len(config.Index))
+	for version, versionConfig := range config.Index {
+		components := make([]*IndexComponent, 0, len(versionConfig.Components))
+		for name, component := range versionConfig.Components {
+			currency := component.Currency
+			if currency == "" {
+				currency = config.Currency
+			}
+			components = append(components, &IndexComponent{
+				Name:     name,
+				Asset:    component.Asset,
+				Currency: currency,
+				Weight:   component.Weight,
+			})
+		}
+		// sorted to have a deterministic encoding
+		sort.Slice(components, func(i, j int) bool {
+			return components[i].Name < components[j].Name
+		})
+		compositions = append(compositions, &IndexComposition{
+			Version:       version,
+			EffectiveFrom: versionConfig.EffectiveFrom,
+			Components:    components,
+		})
+	}
+	sort.Slice(compositions, func(i, j int) bool {
+		return compositions[i].EffectiveFrom.Before(compositions[j].EffectiveFrom)
+	})
+	return compositions
+}
+
+// IndexCompositionAt returns the composition version effective at the given date
+// (the most recent one which became effective before or at the date)
+func IndexCompositionAt(config AssetConfig, date time.Time) (*IndexComposition, error) {
+	var effective *IndexComposition
+	for _, composition := range IndexCompositions(config) {
+		if composition.EffectiveFrom.After(date) {
+			break
+		}
+		effective = composition
+	}
+	if effective == nil {
+		return nil, errors.Errorf("no index composition effective at %s", date.String())
+	}
+	return effective, nil
+}
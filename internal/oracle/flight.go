@@ -0,0 +1,51 @@
+package oracle
+
+import (
+	"p2pderivatives-oracle/internal/database/entity"
+	"sync"
+)
+
+// announcements single-flight of the event creations of the process,
+// shared by the services as they are created per request
+var announcements = newFlightGroup()
+
+// flightGroup runs a single creation per event at a time, the concurrent callers waiting for its result
+type flightGroup struct {
+	mutex   sync.Mutex
+	flights map[string]*flight
+}
+
+// flight represents an event creation in progress
+type flight struct {
+	done    chan struct{}
+	dlcData *entity.DLCData
+	err     error
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: map[string]*flight{}}
+}
+
+// do runs create unless a creation of the same key is in progress, in which case its result is returned,
+// created is only true for the caller which ran the creation (and if create reports the event as created)
+func (g *flightGroup) do(key string, create func() (*entity.DLCData, bool, error)) (*entity.DLCData, bool, error) {
+	g.mutex.Lock()
+	if inProgress, ok := g.flights[key]; ok {
+		g.mutex.Unlock()
+		<-inProgress.done
+		return inProgress.dlcData, false, inProgress.err
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mutex.Unlock()
+
+	defer func() {
+		g.mutex.Lock()
+		delete(g.flights, key)
+		g.mutex.Unlock()
+		close(f.done)
+	}()
+	var created bool
+	f.dlcData, created, f.err = create()
+	return f.dlcData, created, f.err
+}
@@ -0,0 +1,401 @@
+package oracle
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// DefaultEventType event type of the requests without event type
+const DefaultEventType = "digits"
+
+// Asset represents the publication schedule of the events of an asset and how their outcome is valued
+type Asset struct {
+	ID string
+	// StartDate publish date of the first event, the next ones are published every Frequency
+	StartDate time.Time
+	Frequency time.Duration
+	// Range duration after now up to which the events can be announced
+	Range       time.Duration
+	HasDecimals bool
+	// Valuate returns the value of the asset at a publish date, the asset cannot be attested if not set
+	Valuate func(date time.Time) (*Valuation, error)
+}
+
+// Valuation represents the value of an asset at a date and how it has been computed
+type Valuation struct {
+	Value            float64
+	SettlementMethod string
+	IndexVersion     string
+}
+
+// PublishDate returns the publish date of the first event published at or after the requested date
+func (a *Asset) PublishDate(requestedDate time.Time, now time.Time) (time.Time, error) {
+	// round to the closest publication, then up to the next one if rounded down
+	publishDate := a.StartDate.Add(requestedDate.Sub(a.StartDate).Round(a.Frequency))
+	if publishDate.Before(requestedDate) {
+		publishDate = publishDate.Add(a.Frequency)
+	}
+
+	upTo := now.Add(a.Range)
+	if publishDate.After(upTo) {
+		cause := errors.Errorf(
+			"Requested Date not in oracle range, you cannot request a DLC Data that will be published after %s",
+			upTo.String())
+		return publishDate, &Error{Kind: ErrorKindOutOfRange, PublishDate: publishDate, Err: cause}
+	}
+	return publishDate, nil
+}
+
+// PublishDatesBetween returns the publish dates of the asset between from and to (included)
+func (a *Asset) PublishDatesBetween(from time.Time, to time.Time) []time.Time {
+	if a.Frequency <= 0 || to.Before(from) {
+		return nil
+	}
+	first := a.StartDate
+	if first.Before(from) {
+		// first multiple of the frequency from the start date at or after from
+		n := from.Sub(first) / a.Frequency
+		first = first.Add(n * a.Frequency)
+		if first.Before(from) {
+			first = first.Add(a.Frequency)
+		}
+	}
+	dates := []time.Time{}
+	for date := first; !date.After(to); date = date.Add(a.Frequency) {
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// ErrorKind identifies why a service operation failed
+type ErrorKind int
+
+const (
+	// ErrorKindDatabase the database could not be queried
+	ErrorKindDatabase ErrorKind = iota
+	// ErrorKindCrypto a key could not be generated or a signature computed
+	ErrorKindCrypto
+	// ErrorKindOutOfRange the event is published after the range of the asset
+	ErrorKindOutOfRange
+	// ErrorKindNotPublished the event is not published yet
+	ErrorKindNotPublished
+	// ErrorKindNotAttestable the value of the asset cannot be computed by the oracle
+	ErrorKindNotAttestable
+	// ErrorKindDataFeed the value of the asset could not be retrieved from the datafeed
+	ErrorKindDataFeed
+	// ErrorKindNoIndexComposition no composition of the index asset is effective at the publish date
+	ErrorKindNoIndexComposition
+	// ErrorKindInternal the asset configuration or the valuation is invalid
+	ErrorKindInternal
+	// ErrorKindInvalidParameter the event type is unknown or its parameters are invalid
+	ErrorKindInvalidParameter
+)
+
+// Error represents a failed service operation
+type Error struct {
+	Kind ErrorKind
+	// PublishDate publish date of the requested event (if known)
+	PublishDate time.Time
+	Err         error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Meter counts the announcements and attestations made on behalf of a caller
+type Meter interface {
+	// CanAnnounce is called before an event is created, an error prevents the announcement (quota exceeded)
+	CanAnnounce() error
+	// Announce is called after an event has been created for the caller,
+	// the callers getting an event created concurrently are not counted
+	Announce()
+	// Attest is called after an event has been attested
+	Attest()
+}
+
+type meterKey struct{}
+
+// WithMeter returns a context counting the announcements and attestations with the meter
+func WithMeter(ctx context.Context, meter Meter) context.Context {
+	return context.WithValue(ctx, meterKey{}, meter)
+}
+
+// meterOf returns the meter of the context, nil if not metered
+func meterOf(ctx context.Context) Meter {
+	meter, _ := ctx.Value(meterKey{}).(Meter)
+	return meter
+}
+
+// Service announces and attests the events of the oracle,
+// it holds the business logic shared by the api transports, the schedulers and the cli
+type Service struct {
+	oracle *Oracle
+	db     *gorm.DB
+	cache  *entity.DLCDataCache
+	crypto dlccrypto.CryptoService
+	now    func() time.Time
+}
+
+// NewService returns a service announcing and attesting the events with the oracle key,
+// the signed events are read from the cache if not nil
+func NewService(oracle *Oracle, db *gorm.DB, cache *entity.DLCDataCache, crypto dlccrypto.CryptoService) *Service {
+	return &Service{
+		oracle: oracle,
+		db:     db,
+		cache:  cache,
+		crypto: crypto,
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// PublishDate returns the publish date of the asset event requested at the given date
+func (s *Service) PublishDate(asset *Asset, requestedDate time.Time) (time.Time, error) {
+	return asset.PublishDate(requestedDate, s.now())
+}
+
+// Announce returns the event published at the first publish date at or after the requested date,
+// generating and storing its nonce if not announced yet
+func (s *Service) Announce(ctx context.Context, asset *Asset, eventType string, requestedDate time.Time) (*entity.DLCData, error) {
+	if _, _, err := ParseEventType(eventType); err != nil {
+		return nil, err
+	}
+	publishDate, err := s.PublishDate(asset, requestedDate)
+	if err != nil {
+		return nil, err
+	}
+	return s.findOrCreate(ctx, asset.ID, eventTypeOrDefault(eventType), publishDate)
+}
+
+// Attest returns the attested event published at the first publish date at or after the requested date,
+// announcing it and signing its outcome if needed
+func (s *Service) Attest(ctx context.Context, asset *Asset, eventType string, requestedDate time.Time) (*entity.DLCData, error) {
+	if _, _, err := ParseEventType(eventType); err != nil {
+		return nil, err
+	}
+	publishDate, err := s.PublishDate(asset, requestedDate)
+	if err != nil {
+		return nil, err
+	}
+	if publishDate.After(s.now()) {
+		cause := errors.Errorf("Oracle cannot sign a value not yet known, retry after %s", publishDate.String())
+		return nil, &Error{Kind: ErrorKindNotPublished, PublishDate: publishDate, Err: cause}
+	}
+	dlcData, err := s.findOrCreate(ctx, asset.ID, eventTypeOrDefault(eventType), publishDate)
+	if err != nil || dlcData.IsSigned() {
+		return dlcData, err
+	}
+	return s.AttestEvent(ctx, asset, dlcData)
+}
+
+// AttestEvent values the outcome of an announced event, signs it and stores the attestation
+// (if the event has been signed concurrently, the stored attestation is returned)
+func (s *Service) AttestEvent(ctx context.Context, asset *Asset, dlcData *entity.DLCData) (*entity.DLCData, error) {
+	if asset.Valuate == nil {
+		cause := errors.Errorf("asset %s cannot be attested by the oracle", asset.ID)
+		return nil, &Error{Kind: ErrorKindNotAttestable, PublishDate: dlcData.PublishedDate, Err: cause}
+	}
+	valuation, err := asset.Valuate(dlcData.PublishedDate)
+	if err != nil {
+		return nil, err
+	}
+	outcome, err := Outcome(dlcData.EventType, asset.HasDecimals, valuation.Value)
+	if err != nil {
+		return nil, &Error{Kind: ErrorKindCrypto, PublishDate: dlcData.PublishedDate, Err: err}
+	}
+	signature, err := s.sign(dlcData, outcome)
+	if err != nil {
+		return nil, err
+	}
+
+	publishDate := dlcData.PublishedDate
+	dlcData, err = entity.UpdateDLCDataAttestation(
+		s.db,
+		dlcData.AssetID,
+		dlcData.PublishedDate,
+		dlcData.EventType,
+		entity.DLCData{
+			Signature:        signature,
+			Value:            outcome,
+			SettlementMethod: valuation.SettlementMethod,
+			IndexVersion:     valuation.IndexVersion,
+		})
+	if err != nil {
+		return nil, &Error{Kind: ErrorKindDatabase, PublishDate: publishDate, Err: err}
+	}
+	s.cache.Add(dlcData)
+	if meter := meterOf(ctx); meter != nil {
+		meter.Attest()
+	}
+	return dlcData, nil
+}
+
+// AttestOutcome signs the given outcome of an announced event and stores the attestation,
+// for the events which cannot be valued by the oracle (the outcome is provided by an operator)
+func (s *Service) AttestOutcome(ctx context.Context, dlcData *entity.DLCData, outcome string) (*entity.DLCData, error) {
+	signature, err := s.sign(dlcData, outcome)
+	if err != nil {
+		return nil, err
+	}
+	publishDate := dlcData.PublishedDate
+	dlcData, err = entity.UpdateDLCDataSignatureAndValue(
+		s.db, dlcData.AssetID, publishDate, dlcData.EventType, signature, outcome)
+	if err != nil {
+		return nil, &Error{Kind: ErrorKindDatabase, PublishDate: publishDate, Err: err}
+	}
+	s.cache.Add(dlcData)
+	if meter := meterOf(ctx); meter != nil {
+		meter.Attest()
+	}
+	return dlcData, nil
+}
+
+// findOrCreate returns the stored event or announces it, the announcement is counted by the context meter
+// only if the event is created by this call.
+// A single nonce is generated per event in the process: the concurrent announcements of an event wait for the first one
+func (s *Service) findOrCreate(ctx context.Context, assetID, eventType string, publishDate time.Time) (*entity.DLCData, error) {
+	dlcData, err := s.cache.FindDLCDataPublishedAt(s.db, assetID, publishDate, eventType)
+	if err == nil {
+		return dlcData, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, &Error{Kind: ErrorKindDatabase, PublishDate: publishDate, Err: err}
+	}
+
+	meter := meterOf(ctx)
+	if meter != nil {
+		if err := meter.CanAnnounce(); err != nil {
+			return nil, err
+		}
+	}
+	key := fmt.Sprintf("%s:%s:%d", assetID, eventType, publishDate.UnixNano())
+	dlcData, created, err := announcements.do(key, func() (*entity.DLCData, bool, error) {
+		return s.create(assetID, eventType, publishDate)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if created && meter != nil {
+		meter.Announce()
+	}
+	return dlcData, nil
+}
+
+// create generates the nonce of an event and stores it, created is false
+// if the stored event is returned as it has been announced concurrently (by a previous flight or another process)
+func (s *Service) create(assetID, eventType string, publishDate time.Time) (dlcData *entity.DLCData, created bool, err error) {
+	dlcData, err = entity.FindDLCDataPublishedAt(s.db, assetID, publishDate, eventType)
+	if err == nil {
+		return dlcData, false, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, false, &Error{Kind: ErrorKindDatabase, PublishDate: publishDate, Err: err}
+	}
+
+	signingK, rvalue, err := s.crypto.GenerateSchnorrKeyPair()
+	if err != nil {
+		return nil, false, &Error{Kind: ErrorKindCrypto, PublishDate: publishDate, Err: err}
+	}
+	dlcData, created, err = entity.CreateDLCDataIfNotExists(
+		s.db,
+		assetID,
+		publishDate,
+		eventType,
+		signingK.EncodeToString(),
+		rvalue.EncodeToString())
+	if err != nil {
+		return nil, false, &Error{Kind: ErrorKindDatabase, PublishDate: publishDate, Err: err}
+	}
+	return dlcData, created, nil
+}
+
+// sign returns the hex encoded signature of the outcome with the nonce of the event
+func (s *Service) sign(dlcData *entity.DLCData, outcome string) (string, error) {
+	kvalue, err := dlccrypto.NewPrivateKey(dlcData.Kvalue)
+	if err != nil {
+		return "", &Error{Kind: ErrorKindCrypto, PublishDate: dlcData.PublishedDate, Err: err}
+	}
+	signature, err := s.crypto.ComputeSchnorrSignature(s.oracle.PrivateKey, kvalue, outcome)
+	if err != nil {
+		return "", &Error{Kind: ErrorKindCrypto, PublishDate: dlcData.PublishedDate, Err: err}
+	}
+	return signature.EncodeToString(), nil
+}
+
+func eventTypeOrDefault(eventType string) string {
+	if eventType == "" {
+		return DefaultEventType
+	}
+	return eventType
+}
+
+// eventTypePattern matches an event type with its optional parameter, ex: above(8000.5)
+var eventTypePattern = regexp.MustCompile("^(\\w+)(\\((.*)\\))?$")
+
+// ParseEventType returns the event type without parameters and its parameters (digits if empty),
+// an ErrorKindInvalidParameter error is returned if the event type is unknown or its parameters are invalid:
+// the supported event types are digits and above(<threshold>)
+func ParseEventType(eventType string) (string, []string, error) {
+	if eventType == "" {
+		return DefaultEventType, []string{}, nil
+	}
+
+	match := eventTypePattern.FindStringSubmatch(eventType)
+	if match == nil {
+		return eventType, []string{}, invalidEventTypeError("invalid event type %s", eventType)
+	}
+	rawEventType, hasParams := match[1], match[2] != ""
+	params := []string{}
+	if hasParams {
+		params = []string{match[3]}
+	}
+	switch rawEventType {
+	case "digits":
+		if hasParams {
+			return rawEventType, params, invalidEventTypeError("event type %s has no parameter", rawEventType)
+		}
+	case "above":
+		if !hasParams {
+			return rawEventType, params, invalidEventTypeError("event type %s requires a threshold", rawEventType)
+		}
+		if _, err := strconv.ParseFloat(params[0], 64); err != nil {
+			return rawEventType, params, invalidEventTypeError("invalid threshold %s", params[0])
+		}
+	default:
+		return rawEventType, params, invalidEventTypeError("unknown event type %s", rawEventType)
+	}
+	return rawEventType, params, nil
+}
+
+func invalidEventTypeError(format string, args ...interface{}) error {
+	return &Error{Kind: ErrorKindInvalidParameter, Err: errors.Errorf(format, args...)}
+}
+
+// Outcome returns the message signed for an event of the given type and asset value:
+// the value for digits events (rounded to an integer or 2 decimals), true or false for above(<threshold>) events
+func Outcome(eventType string, hasDecimals bool, value float64) (string, error) {
+	rawEventType, eventParams, err := ParseEventType(eventType)
+	if err != nil {
+		return "", err
+	}
+	if rawEventType == "above" {
+		threshold, err := strconv.ParseFloat(eventParams[0], 64)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(threshold < value), nil
+	}
+	if hasDecimals {
+		return fmt.Sprintf("%.2f", value), nil
+	}
+	return fmt.Sprintf("%d", int(math.Round(value))), nil
+}
@@ -0,0 +1,322 @@
+package oracle_test
+
+import (
+	"context"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/oracle"
+	"p2pderivatives-oracle/test"
+	mock_dlccrypto "p2pderivatives-oracle/test/mock/dlccrypto"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKvalue    = "d1e3dcdda619833ec2b91d4fd304e9be0ad85326c9f524dfbc53f443ab54063e"
+	testRvalue    = "44b1350439fc9a098db6edd5bd417eb1aeaa17ec60f9e5a799605feebd5c19eb"
+	testSignature = "44b1350439fc9a098db6edd5bd417eb1aeaa17ec60f9e5a799605feebd5c19ebf742bea67ff64f738c0426d04a22b30fe61258e074c0c90a1b13ce29d11f4b67"
+)
+
+// testMeter counts the announcements and attestations, refusing the announcements once remaining is 0
+type testMeter struct {
+	remaining     int
+	announcements int
+	attestations  int
+}
+
+func (m *testMeter) CanAnnounce() error {
+	if m.remaining == 0 {
+		return errors.New("quota exceeded")
+	}
+	return nil
+}
+
+func (m *testMeter) Announce() {
+	m.remaining--
+	m.announcements++
+}
+
+func (m *testMeter) Attest() {
+	m.attestations++
+}
+
+func newTestAsset(value float64) *oracle.Asset {
+	return &oracle.Asset{
+		ID:        "btcusd",
+		StartDate: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Frequency: time.Hour,
+		Range:     48 * time.Hour,
+		Valuate: func(date time.Time) (*oracle.Valuation, error) {
+			return &oracle.Valuation{Value: value, SettlementMethod: "close"}, nil
+		},
+	}
+}
+
+func setupService(t *testing.T, ctrl *gomock.Controller) (*oracle.Service, *gorm.DB, *mock_dlccrypto.MockCryptoService) {
+	privateKey, err := dlccrypto.NewPrivateKey(ExpectedKeyPair.privateKey)
+	require.NoError(t, err)
+	db := test.NewOrm(&entity.DLCData{}, &entity.EventNotification{}).GetDB()
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	return oracle.NewService(&oracle.Oracle{PrivateKey: privateKey}, db, nil, crypto), db, crypto
+}
+
+func expectKeyPair(crypto *mock_dlccrypto.MockCryptoService) *gomock.Call {
+	kvalue, _ := dlccrypto.NewPrivateKey(testKvalue)
+	rvalue, _ := dlccrypto.NewSchnorrPublicKey(testRvalue)
+	return crypto.EXPECT().GenerateSchnorrKeyPair().Return(kvalue, rvalue, nil)
+}
+
+func TestAsset_PublishDate_ReturnsNextPublication(t *testing.T) {
+	asset := newTestAsset(0)
+	now := time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		requested time.Time
+		expected  time.Time
+	}{
+		{asset.StartDate, asset.StartDate},
+		{asset.StartDate.Add(time.Minute), asset.StartDate.Add(time.Hour)},
+		{asset.StartDate.Add(59 * time.Minute), asset.StartDate.Add(time.Hour)},
+		{asset.StartDate.Add(time.Hour), asset.StartDate.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		actual, err := asset.PublishDate(tt.requested, now)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, actual)
+	}
+}
+
+func TestAsset_PublishDate_AfterRange_ReturnsOutOfRangeError(t *testing.T) {
+	asset := newTestAsset(0)
+	now := time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC)
+
+	_, err := asset.PublishDate(now.Add(asset.Range).Add(time.Minute), now)
+
+	if assert.IsType(t, &oracle.Error{}, err) {
+		assert.Equal(t, oracle.ErrorKindOutOfRange, err.(*oracle.Error).Kind)
+		assert.Equal(t, now.Add(asset.Range).Add(time.Hour), err.(*oracle.Error).PublishDate)
+	}
+}
+
+func TestAsset_PublishDatesBetween_ReturnsDatesOnFrequency(t *testing.T) {
+	asset := newTestAsset(0)
+	start := asset.StartDate
+
+	tests := []struct {
+		from     time.Time
+		to       time.Time
+		expected []time.Time
+	}{
+		{start.Add(-2 * time.Hour), start.Add(time.Hour), []time.Time{start, start.Add(time.Hour)}},
+		{start.Add(time.Hour), start.Add(2 * time.Hour), []time.Time{start.Add(time.Hour), start.Add(2 * time.Hour)}},
+		{start.Add(90 * time.Minute), start.Add(150 * time.Minute), []time.Time{start.Add(2 * time.Hour)}},
+		{start.Add(90 * time.Minute), start.Add(100 * time.Minute), []time.Time{}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, asset.PublishDatesBetween(tt.from, tt.to))
+	}
+}
+
+func TestService_Announce_CreatesEventOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service, _, crypto := setupService(t, ctrl)
+	expectKeyPair(crypto).Times(1)
+	meter := &testMeter{remaining: -1}
+	ctx := oracle.WithMeter(context.Background(), meter)
+	asset := newTestAsset(0)
+	requested := asset.StartDate.Add(90 * time.Minute)
+
+	first, err := service.Announce(ctx, asset, "", requested)
+	require.NoError(t, err)
+	second, err := service.Announce(ctx, asset, "digits", requested)
+	require.NoError(t, err)
+
+	assert.Equal(t, asset.StartDate.Add(2*time.Hour), first.PublishedDate)
+	assert.Equal(t, oracle.DefaultEventType, first.EventType)
+	assert.Equal(t, testRvalue, first.Rvalue)
+	assert.Equal(t, first.Rvalue, second.Rvalue)
+	assert.Equal(t, 1, meter.announcements)
+}
+
+func TestService_Announce_Concurrent_GeneratesOneNonce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service, _, crypto := setupService(t, ctrl)
+	const concurrency = 10
+	// the key generation is held until all the announcements are started
+	var entered sync.WaitGroup
+	entered.Add(concurrency)
+	expectKeyPair(crypto).Do(func() { entered.Wait() }).Times(1)
+	asset := newTestAsset(0)
+
+	var wg sync.WaitGroup
+	results := make(chan *entity.DLCData, concurrency)
+	meters := make([]*testMeter, concurrency)
+	for i := 0; i < concurrency; i++ {
+		meters[i] = &testMeter{remaining: -1}
+		ctx := oracle.WithMeter(context.Background(), meters[i])
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entered.Done()
+			dlcData, err := service.Announce(ctx, asset, "", asset.StartDate)
+			assert.NoError(t, err)
+			results <- dlcData
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	for dlcData := range results {
+		if assert.NotNil(t, dlcData) {
+			assert.Equal(t, testRvalue, dlcData.Rvalue)
+		}
+	}
+	// only the caller which created the event is charged
+	announcements := 0
+	for _, meter := range meters {
+		announcements += meter.announcements
+	}
+	assert.Equal(t, 1, announcements)
+}
+
+func TestService_Announce_MeterRefuses_ReturnsMeterError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service, _, crypto := setupService(t, ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Times(0)
+	asset := newTestAsset(0)
+
+	ctx := oracle.WithMeter(context.Background(), &testMeter{remaining: 0})
+	_, err := service.Announce(ctx, asset, "", asset.StartDate)
+
+	assert.EqualError(t, err, "quota exceeded")
+}
+
+func TestService_Announce_InvalidEventType_ReturnsInvalidParameterError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service, _, crypto := setupService(t, ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Times(0)
+	asset := newTestAsset(0)
+
+	for _, eventType := range []string{"above", "above()", "above(abc)", "digits(2)", "election", "above(1"} {
+		_, err := service.Announce(context.Background(), asset, eventType, asset.StartDate)
+
+		if assert.IsType(t, &oracle.Error{}, err, eventType) {
+			assert.Equal(t, oracle.ErrorKindInvalidParameter, err.(*oracle.Error).Kind, eventType)
+		}
+	}
+}
+
+func TestService_Attest_SignsValuedOutcome(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service, db, crypto := setupService(t, ctrl)
+	signature, _ := dlccrypto.NewSignature(testSignature)
+	expectKeyPair(crypto).Times(1)
+	crypto.EXPECT().ComputeSchnorrSignature(gomock.Any(), gomock.Any(), "8001").Return(signature, nil).Times(1)
+	meter := &testMeter{remaining: -1}
+	ctx := oracle.WithMeter(context.Background(), meter)
+	asset := newTestAsset(8000.6)
+
+	dlcData, err := service.Attest(ctx, asset, "", asset.StartDate)
+	require.NoError(t, err)
+	// already signed, returned as stored
+	again, err := service.Attest(ctx, asset, "", asset.StartDate)
+	require.NoError(t, err)
+
+	assert.Equal(t, testSignature, dlcData.Signature)
+	assert.Equal(t, "8001", dlcData.Value)
+	assert.Equal(t, "close", dlcData.SettlementMethod)
+	assert.Equal(t, dlcData.Signature, again.Signature)
+	assert.Equal(t, 1, meter.attestations)
+	stored, err := entity.FindDLCDataPublishedAt(db, asset.ID, asset.StartDate, oracle.DefaultEventType)
+	require.NoError(t, err)
+	assert.True(t, stored.IsSigned())
+}
+
+func TestService_Attest_NotPublished_ReturnsNotPublishedError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service, _, crypto := setupService(t, ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Times(0)
+	asset := newTestAsset(0)
+
+	_, err := service.Attest(context.Background(), asset, "", time.Now().Add(time.Hour))
+
+	if assert.IsType(t, &oracle.Error{}, err) {
+		assert.Equal(t, oracle.ErrorKindNotPublished, err.(*oracle.Error).Kind)
+	}
+}
+
+func TestService_AttestEvent_NotAttestable_ReturnsNotAttestableError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service, _, crypto := setupService(t, ctrl)
+	expectKeyPair(crypto).Times(1)
+	crypto.EXPECT().ComputeSchnorrSignature(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	asset := newTestAsset(0)
+	asset.Valuate = nil
+	dlcData, err := service.Announce(context.Background(), asset, "", asset.StartDate)
+	require.NoError(t, err)
+
+	_, err = service.AttestEvent(context.Background(), asset, dlcData)
+
+	if assert.IsType(t, &oracle.Error{}, err) {
+		assert.Equal(t, oracle.ErrorKindNotAttestable, err.(*oracle.Error).Kind)
+	}
+}
+
+func TestService_AttestOutcome_SignsGivenOutcome(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	service, _, crypto := setupService(t, ctrl)
+	signature, _ := dlccrypto.NewSignature(testSignature)
+	expectKeyPair(crypto).Times(1)
+	crypto.EXPECT().ComputeSchnorrSignature(gomock.Any(), gomock.Any(), "yes").Return(signature, nil).Times(1)
+	asset := newTestAsset(0)
+	dlcData, err := service.Announce(context.Background(), asset, "", asset.StartDate)
+	require.NoError(t, err)
+
+	dlcData, err = service.AttestOutcome(context.Background(), dlcData, "yes")
+
+	require.NoError(t, err)
+	assert.Equal(t, "yes", dlcData.Value)
+	assert.Equal(t, testSignature, dlcData.Signature)
+}
+
+func TestOutcome_ReturnsSignedMessage(t *testing.T) {
+	tests := []struct {
+		eventType   string
+		hasDecimals bool
+		value       float64
+		expected    string
+	}{
+		{"digits", false, 8000.5, "8001"},
+		{"digits", true, 8000.456, "8000.46"},
+		{"above(8000)", false, 8000.5, "true"},
+		{"above(8000.5)", false, 8000.5, "false"},
+	}
+	for _, tt := range tests {
+		actual, err := oracle.Outcome(tt.eventType, tt.hasDecimals, tt.value)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, actual, tt.eventType)
+	}
+}
+
+func TestOutcome_InvalidEventType_ReturnsError(t *testing.T) {
+	for _, eventType := range []string{"above", "unknown"} {
+		actual, err := oracle.Outcome(eventType, false, 1)
+		assert.Error(t, err, eventType)
+		assert.Empty(t, actual)
+	}
+}
@@ -0,0 +1,88 @@
+package oracle
+
+import (
+	"encoding/json"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/datafeed"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// NewAsset returns the publication schedule of an asset, which cannot be attested (no valuation)
+func NewAsset(assetID string, config AssetConfig) *Asset {
+	return &Asset{
+		ID:          assetID,
+		StartDate:   config.StartDate,
+		Frequency:   config.Frequency,
+		Range:       config.RangeD,
+		HasDecimals: config.HasDecimals,
+	}
+}
+
+// NewValuedAsset returns an asset valued from the datafeed with its settlement method
+// (and its index composition if it is an index), not attestable if the asset cannot be valued by the oracle
+func NewValuedAsset(assetID string, config AssetConfig, db *gorm.DB, feed datafeed.DataFeed) *Asset {
+	asset := NewAsset(assetID, config)
+	if config.IsAttestable() {
+		asset.Valuate = func(date time.Time) (*Valuation, error) {
+			return findAssetValue(db, feed, assetID, config, date)
+		}
+	}
+	return asset
+}
+
+// findAssetValue returns the value of the asset at the given date from the datafeed
+// using the asset settlement method (and the index composition if the asset is an index)
+func findAssetValue(db *gorm.DB, feed datafeed.DataFeed, assetID string, config AssetConfig, date time.Time) (*Valuation, error) {
+	method, err := datafeed.ParseSettlementMethod(config.Settlement)
+	if err != nil {
+		return nil, &Error{Kind: ErrorKindInternal, PublishDate: date, Err: err}
+	}
+	valuation := &Valuation{SettlementMethod: method.String()}
+	if config.IsIndex() {
+		value, indexVersion, err := findIndexValue(db, feed, method, assetID, config, date)
+		if err != nil {
+			return nil, err
+		}
+		valuation.Value, valuation.IndexVersion = *value, indexVersion
+		return valuation, nil
+	}
+	value, err := method.FindValue(feed, config.Asset, config.Currency, date)
+	if err != nil {
+		return nil, &Error{Kind: ErrorKindDataFeed, PublishDate: date, Err: err}
+	}
+	valuation.Value = *value
+	return valuation, nil
+}
+
+// findIndexValue computes the value of an index asset as the weighted sum of its component settlement prices
+// the composition version used is stored so that the attestation remains explainable
+func findIndexValue(db *gorm.DB, feed datafeed.DataFeed, method *datafeed.SettlementMethod, assetID string, config AssetConfig, date time.Time) (*float64, string, error) {
+	composition, err := IndexCompositionAt(config, date)
+	if err != nil {
+		return nil, "", &Error{Kind: ErrorKindNoIndexComposition, PublishDate: date, Err: err}
+	}
+
+	components, err := json.Marshal(composition.Components)
+	if err != nil {
+		return nil, "", &Error{Kind: ErrorKindInternal, PublishDate: date, Err: err}
+	}
+	_, err = entity.SaveIndexComposition(db, assetID, composition.Version, composition.EffectiveFrom, string(components))
+	if err != nil {
+		return nil, "", &Error{Kind: ErrorKindDatabase, PublishDate: date, Err: err}
+	}
+
+	value := 0.0
+	for _, component := range composition.Components {
+		price, err := method.FindValue(feed, component.Asset, component.Currency, date)
+		if err != nil {
+			cause := errors.WithMessagef(err, "index component %s", component.Name)
+			return nil, "", &Error{Kind: ErrorKindDataFeed, PublishDate: date, Err: cause}
+		}
+		value += component.Weight * *price
+	}
+
+	return &value, composition.Version, nil
+}
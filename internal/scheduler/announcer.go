@@ -0,0 +1,322 @@
+package scheduler
+
+import (
+	"context"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/oracle"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/cryptogarageinc/server-common-go/pkg/log"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const announcerLeaseName = "announcer"
+
+// NewAnnouncer returns a new nonce pre-announcement scheduler (not started)
+func NewAnnouncer(config *AnnouncerConfig, assets oracle.AssetProvider, orm *orm.ORM, crypto dlccrypto.CryptoService, log *log.Log) *Announcer {
+	return &Announcer{
+		config:  config,
+		assets:  assets,
+		orm:     orm,
+		crypto:  crypto,
+		logger:  log,
+		leader:  newLeader(announcerLeaseName, config.LeaseDuration),
+		metrics: newAnnouncerMetrics(),
+	}
+}
+
+// Announcer keeps the events of every asset and enabled event type announced (rvalue generated)
+// over the asset range, so that the rvalues are created ahead of time instead of on first request
+// only the replica holding the announcer lease runs it
+type Announcer struct {
+	config  *AnnouncerConfig
+	assets  oracle.AssetProvider
+	orm     *orm.ORM
+	crypto  dlccrypto.CryptoService
+	logger  *log.Log
+	leader  *leader
+	metrics *AnnouncerMetrics
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Start runs the announcer periodically in background until Stop is called
+func (a *Announcer) Start() {
+	if a.stop != nil {
+		return
+	}
+	a.stop, a.done = make(chan struct{}), make(chan struct{})
+	go a.run(a.stop, a.done)
+}
+
+// Stop stops the announcer and releases its lease
+func (a *Announcer) Stop() {
+	if a.stop == nil {
+		return
+	}
+	close(a.stop)
+	<-a.done
+	a.stop, a.done = nil, nil
+	if err := a.leader.release(a.orm.GetDB()); err != nil {
+		a.logger.Logger.Warnf("Could not release the announcer lease: %v", err)
+	}
+}
+
+// Metrics returns the announcer metrics
+func (a *Announcer) Metrics() *AnnouncerMetrics {
+	return a.metrics
+}
+
+func (a *Announcer) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(a.config.Interval)
+	defer ticker.Stop()
+	for {
+		if err := a.RunOnce(time.Now().UTC()); err != nil {
+			a.logger.Logger.Errorf("Announcement run failed: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce announces the missing events of all assets up to now + asset range
+// if the lease is held by another replica nothing is done
+func (a *Announcer) RunOnce(now time.Time) error {
+	db := a.orm.GetDB()
+	isLeader, err := a.leader.acquire(db, now)
+	if err != nil {
+		a.metrics.addError()
+		return errors.WithMessage(err, "could not acquire the announcer lease")
+	}
+	a.metrics.setLeader(isLeader)
+	if !isLeader {
+		return nil
+	}
+
+	assets, err := a.assets.AssetConfigs()
+	if err != nil {
+		a.metrics.addError()
+		return errors.WithMessage(err, "could not load the assets")
+	}
+	var runErr error
+	for assetID, config := range assets {
+		for _, eventType := range announcedEventTypes(config) {
+			if err := a.announce(db, assetID, eventType, config, now); err != nil {
+				a.metrics.addError()
+				runErr = errors.WithMessagef(err, "asset %s event type %s", assetID, eventType)
+				a.logger.Logger.Errorf("Could not announce events: %v", runErr)
+			}
+		}
+	}
+	a.metrics.setLastRun(now)
+	return runErr
+}
+
+// announcedEventTypes returns the event types enabled for the asset sorted,
+// the assets without enabled event types are not announced
+func announcedEventTypes(config oracle.AssetConfig) []string {
+	eventTypes := []string{}
+	for eventType, enabled := range config.EventTypes {
+		if enabled {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	sort.Strings(eventTypes)
+	return eventTypes
+}
+
+// announce creates the missing events of an asset and event type, renewing the lease every batch
+func (a *Announcer) announce(db *gorm.DB, assetID string, eventType string, config oracle.AssetConfig, now time.Time) error {
+	started := time.Now()
+	dates := oracle.NewAsset(assetID, config).PublishDatesBetween(now, now.Add(config.RangeD))
+	if len(dates) == 0 {
+		return nil
+	}
+	existing, err := entity.FindDLCDataPublishedDates(db, assetID, eventType, dates[0], dates[len(dates)-1])
+	if err != nil {
+		return err
+	}
+	announced := make(map[int64]bool, len(existing))
+	for _, date := range existing {
+		announced[date.Unix()] = true
+	}
+	missing := []time.Time{}
+	for _, date := range dates {
+		if !announced[date.Unix()] {
+			missing = append(missing, date)
+		}
+	}
+
+	// lag between the range horizon and the last announced event before the run
+	lag := time.Duration(0)
+	if len(missing) > 0 {
+		lastAnnounced := now
+		if len(existing) > 0 && existing[len(existing)-1].After(now) {
+			lastAnnounced = existing[len(existing)-1]
+		}
+		lag = dates[len(dates)-1].Sub(lastAnnounced)
+	}
+	a.metrics.setLag(assetID, eventType, lag)
+
+	// the events are announced by the oracle service as the api does (no oracle key is needed as nothing is signed),
+	// the announcements counted being the created ones
+	service := oracle.NewService(nil, db, nil, a.crypto)
+	ctx := oracle.WithMeter(context.Background(), &announcementCounter{metrics: a.metrics})
+	asset := oracle.NewAsset(assetID, config)
+	for i, date := range missing {
+		if i > 0 && i%a.config.BatchSize == 0 {
+			// renew the lease between the batches so that a long run is not taken over by another replica
+			isLeader, err := a.leader.acquire(db, now.Add(time.Since(started)))
+			if err != nil {
+				return errors.WithMessage(err, "could not renew the announcer lease")
+			}
+			if !isLeader {
+				return errors.New("announcer lease lost")
+			}
+		}
+		if _, err := service.Announce(ctx, asset, eventType, date); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		a.logger.Logger.Debugf("Announced %d events for asset %s event type %s", len(missing), assetID, eventType)
+	}
+	return nil
+}
+
+// announcementCounter counts the events created by the announcer in its metrics
+type announcementCounter struct {
+	metrics *AnnouncerMetrics
+}
+
+func (c *announcementCounter) CanAnnounce() error {
+	return nil
+}
+
+func (c *announcementCounter) Announce() {
+	c.metrics.addAnnounced(1)
+}
+
+func (c *announcementCounter) Attest() {}
+
+// AnnouncerMetrics represents the metrics of the announcer (the last created one is exposed with prometheus)
+type AnnouncerMetrics struct {
+	mutex sync.RWMutex
+	stats AnnouncerStats
+	lags  map[announcerLagKey]time.Duration
+}
+
+type announcerLagKey struct {
+	assetID   string
+	eventType string
+}
+
+var (
+	announcerDescs   = newSchedulerDescs("announcer")
+	announcedDesc    = prometheus.NewDesc("oracle_scheduler_announced_total", "Number of events announced by the scheduler.", nil, nil)
+	announcerErrDesc = prometheus.NewDesc("oracle_scheduler_announcer_errors_total", "Number of failed announcer runs.", nil, nil)
+	announcerLagDesc = prometheus.NewDesc("oracle_scheduler_announcement_lag_seconds",
+		"Duration between the range horizon and the last announced event.", []string{"asset", "event_type"}, nil)
+)
+
+// AnnouncerStats represents a snapshot of the announcer metrics
+type AnnouncerStats struct {
+	IsLeader  bool
+	LastRun   time.Time
+	Announced int64
+	Errors    int64
+	// Lags duration between the range horizon and the last announced event before the last run,
+	// indexed by asset id and event type
+	Lags map[string]time.Duration
+}
+
+func newAnnouncerMetrics() *AnnouncerMetrics {
+	metrics := &AnnouncerMetrics{lags: map[announcerLagKey]time.Duration{}}
+	register(metrics)
+	return metrics
+}
+
+// Describe implements prometheus.Collector
+func (m *AnnouncerMetrics) Describe(ch chan<- *prometheus.Desc) {
+	announcerDescs.describe(ch)
+	ch <- announcedDesc
+	ch <- announcerErrDesc
+	ch <- announcerLagDesc
+}
+
+// Collect implements prometheus.Collector
+func (m *AnnouncerMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	announcerDescs.collect(ch, m.stats.IsLeader, m.stats.LastRun)
+	ch <- prometheus.MustNewConstMetric(announcedDesc, prometheus.CounterValue, float64(m.stats.Announced))
+	ch <- prometheus.MustNewConstMetric(announcerErrDesc, prometheus.CounterValue, float64(m.stats.Errors))
+	for key, lag := range m.lags {
+		ch <- prometheus.MustNewConstMetric(announcerLagDesc, prometheus.GaugeValue, lag.Seconds(), key.assetID, key.eventType)
+	}
+}
+
+// Stats returns a snapshot of the metrics
+func (m *AnnouncerMetrics) Stats() AnnouncerStats {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	stats := m.stats
+	stats.Lags = make(map[string]time.Duration, len(m.lags))
+	for key, lag := range m.lags {
+		stats.Lags[key.assetID+"/"+key.eventType] = lag
+	}
+	return stats
+}
+
+// MaxLag returns the highest announcement lag of all assets and event types
+func (m *AnnouncerMetrics) MaxLag() time.Duration {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	maxLag := time.Duration(0)
+	for _, lag := range m.lags {
+		if lag > maxLag {
+			maxLag = lag
+		}
+	}
+	return maxLag
+}
+
+func (m *AnnouncerMetrics) setLeader(isLeader bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.IsLeader = isLeader
+}
+
+func (m *AnnouncerMetrics) setLastRun(date time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.LastRun = date
+}
+
+func (m *AnnouncerMetrics) setLag(assetID string, eventType string, lag time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lags[announcerLagKey{assetID, eventType}] = lag
+}
+
+func (m *AnnouncerMetrics) addAnnounced(count int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.Announced += int64(count)
+}
+
+func (m *AnnouncerMetrics) addError() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.Errors++
+}
@@ -0,0 +1,229 @@
+package scheduler_test
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/oracle"
+	"p2pderivatives-oracle/internal/scheduler"
+	"p2pderivatives-oracle/test"
+	mock_dlccrypto "p2pderivatives-oracle/test/mock/dlccrypto"
+	"testing"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var TestAssetConfig = oracle.AssetConfig{
+	Asset:      "btc",
+	Currency:   "usd",
+	StartDate:  time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+	Frequency:  time.Hour,
+	RangeD:     48 * time.Hour,
+	EventTypes: map[string]bool{"digits": true},
+}
+
+// testNow is between two publish dates, 48 events are in the range
+var testNow = TestAssetConfig.StartDate.Add(10*time.Hour + 30*time.Minute)
+
+func NewTestAnnouncerConfig() *scheduler.AnnouncerConfig {
+	return &scheduler.AnnouncerConfig{
+		Enabled:       true,
+		Interval:      time.Minute,
+		BatchSize:     10,
+		LeaseDuration: 3 * time.Minute,
+	}
+}
+
+func NewTestOrm() *orm.ORM {
+	ormInstance := test.NewOrm(&entity.Asset{}, &entity.DLCData{}, &entity.EventNotification{}, &entity.Lease{})
+	ormInstance.GetDB().Create(&entity.Asset{AssetID: "btcusd"})
+	return ormInstance
+}
+
+// NewMockCryptoService returns a crypto service generating random key pairs
+func NewMockCryptoService(ctrl *gomock.Controller, times int) dlccrypto.CryptoService {
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Times(times).DoAndReturn(
+		func() (*dlccrypto.PrivateKey, *dlccrypto.SchnorrPublicKey, error) {
+			k, _ := dlccrypto.NewPrivateKey(randomHex())
+			r, _ := dlccrypto.NewSchnorrPublicKey(randomHex())
+			return k, r, nil
+		})
+	return crypto
+}
+
+func randomHex() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func countDLCData(t *testing.T, ormInstance *orm.ORM) int {
+	count := 0
+	err := ormInstance.GetDB().Model(&entity.DLCData{}).Count(&count).Error
+	assert.NoError(t, err)
+	return count
+}
+
+func TestAnnouncer_RunOnce_AnnouncesRangeInBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ormInstance := NewTestOrm()
+	announcer := scheduler.NewAnnouncer(
+		NewTestAnnouncerConfig(),
+		oracle.StaticAssets{"btcusd": TestAssetConfig},
+		ormInstance,
+		NewMockCryptoService(ctrl, 48),
+		test.NewLogger())
+
+	err := announcer.RunOnce(testNow)
+	assert.NoError(t, err)
+	assert.Equal(t, 48, countDLCData(t, ormInstance))
+	stats := announcer.Metrics().Stats()
+	assert.True(t, stats.IsLeader)
+	assert.Equal(t, int64(48), stats.Announced)
+	assert.Equal(t, 47*time.Hour+30*time.Minute, stats.Lags["btcusd/digits"])
+
+	// already announced, no new key pair generated
+	err = announcer.RunOnce(testNow.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 48, countDLCData(t, ormInstance))
+	assert.Equal(t, time.Duration(0), announcer.Metrics().MaxLag())
+}
+
+func TestAnnouncer_RunOnce_AnnouncesEnabledEventTypesOfEachAsset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ormInstance := NewTestOrm()
+	btcConfig := TestAssetConfig
+	btcConfig.EventTypes = map[string]bool{"digits": true, "above(10000)": false}
+	electionConfig := TestAssetConfig
+	electionConfig.Asset = "election"
+	electionConfig.EventTypes = nil
+	announcer := scheduler.NewAnnouncer(
+		NewTestAnnouncerConfig(),
+		oracle.StaticAssets{"btcusd": btcConfig, "election": electionConfig},
+		ormInstance,
+		NewMockCryptoService(ctrl, 48),
+		test.NewLogger())
+
+	err := announcer.RunOnce(testNow)
+
+	assert.NoError(t, err)
+	dlcDataList, err := entity.FindDLCDataList(ormInstance.GetDB(), &entity.DLCDataQuery{AssetID: "btcusd"}, 100)
+	assert.NoError(t, err)
+	assert.Len(t, dlcDataList, 48)
+	for _, dlcData := range dlcDataList {
+		assert.Equal(t, "digits", dlcData.EventType)
+	}
+	assert.Equal(t, 48, countDLCData(t, ormInstance))
+}
+
+func TestAnnouncer_RunOnce_WithExistingEvent_KeepsIt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ormInstance := NewTestOrm()
+	existingDate := TestAssetConfig.StartDate.Add(20 * time.Hour)
+	_, err := entity.CreateDLCData(ormInstance.GetDB(), "btcusd", existingDate, "digits", "kvalue", "rvalue")
+	assert.NoError(t, err)
+	announcer := scheduler.NewAnnouncer(
+		NewTestAnnouncerConfig(),
+		oracle.StaticAssets{"btcusd": TestAssetConfig},
+		ormInstance,
+		NewMockCryptoService(ctrl, 47),
+		test.NewLogger())
+
+	err = announcer.RunOnce(testNow)
+	assert.NoError(t, err)
+	assert.Equal(t, 48, countDLCData(t, ormInstance))
+	existing, err := entity.FindDLCDataPublishedAt(ormInstance.GetDB(), "btcusd", existingDate, "digits")
+	assert.NoError(t, err)
+	assert.Equal(t, "rvalue", existing.Rvalue)
+}
+
+func TestAnnouncer_RunOnce_LeaseHeldByOther_DoesNothing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ormInstance := NewTestOrm()
+	acquired, err := entity.AcquireLease(ormInstance.GetDB(), "announcer", "other", testNow, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	announcer := scheduler.NewAnnouncer(
+		NewTestAnnouncerConfig(),
+		oracle.StaticAssets{"btcusd": TestAssetConfig},
+		ormInstance,
+		NewMockCryptoService(ctrl, 0),
+		test.NewLogger())
+
+	err = announcer.RunOnce(testNow)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, countDLCData(t, ormInstance))
+	assert.False(t, announcer.Metrics().Stats().IsLeader)
+}
+
+func TestAnnouncer_RunOnce_LeaseTakenDuringRun_StopsAtNextBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ormInstance := NewTestOrm()
+	generated := 0
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	crypto.EXPECT().GenerateSchnorrKeyPair().Times(10).DoAndReturn(
+		func() (*dlccrypto.PrivateKey, *dlccrypto.SchnorrPublicKey, error) {
+			generated++
+			if generated == 5 {
+				// another replica takes the lease over as if it had expired during the run
+				acquired, err := entity.AcquireLease(ormInstance.GetDB(), "announcer", "other", testNow.Add(time.Hour), time.Hour)
+				assert.NoError(t, err)
+				assert.True(t, acquired)
+			}
+			k, _ := dlccrypto.NewPrivateKey(randomHex())
+			r, _ := dlccrypto.NewSchnorrPublicKey(randomHex())
+			return k, r, nil
+		})
+	announcer := scheduler.NewAnnouncer(
+		NewTestAnnouncerConfig(),
+		oracle.StaticAssets{"btcusd": TestAssetConfig},
+		ormInstance,
+		crypto,
+		test.NewLogger())
+
+	err := announcer.RunOnce(testNow)
+
+	assert.Error(t, err)
+	assert.Equal(t, 10, countDLCData(t, ormInstance))
+	assert.Equal(t, int64(10), announcer.Metrics().Stats().Announced)
+}
+
+func TestAnnouncer_StartStop_AnnouncesAndReleasesLease(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ormInstance := NewTestOrm()
+	config := TestAssetConfig
+	config.RangeD = 2 * time.Hour
+	announcer := scheduler.NewAnnouncer(
+		NewTestAnnouncerConfig(),
+		oracle.StaticAssets{"btcusd": config},
+		ormInstance,
+		NewMockCryptoService(ctrl, 2),
+		test.NewLogger())
+
+	announcer.Start()
+	announcer.Stop()
+	assert.Equal(t, 2, countDLCData(t, ormInstance))
+	_, err := entity.FindLease(ormInstance.GetDB(), "announcer")
+	assert.Error(t, err)
+}
+
+func TestAnnouncerConfig_NotConfigured_UsesDefaults(t *testing.T) {
+	config := &scheduler.AnnouncerConfig{}
+	err := test.InitializeConfig(config)
+	assert.NoError(t, err)
+	assert.False(t, config.Enabled)
+	assert.Equal(t, time.Minute, config.Interval)
+	assert.Equal(t, 100, config.BatchSize)
+	assert.Equal(t, 3*time.Minute, config.LeaseDuration)
+}
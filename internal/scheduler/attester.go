@@ -0,0 +1,252 @@
+package scheduler
+
+import (
+	"context"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/datafeed"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/oracle"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/cryptogarageinc/server-common-go/pkg/log"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const attesterLeaseName = "attester"
+
+// NewAttester returns a new automatic attestation scheduler (not started)
+func NewAttester(
+	config *AttesterConfig,
+	assets oracle.AssetProvider,
+	orm *orm.ORM,
+	crypto dlccrypto.CryptoService,
+	oracle *oracle.Oracle,
+	feed datafeed.DataFeed,
+	log *log.Log) *Attester {
+	return &Attester{
+		config:  config,
+		assets:  assets,
+		orm:     orm,
+		crypto:  crypto,
+		oracle:  oracle,
+		feed:    feed,
+		logger:  log,
+		leader:  newLeader(attesterLeaseName, config.LeaseDuration),
+		metrics: newAttesterMetrics(),
+	}
+}
+
+// Attester signs the announced events once their publish date plus the settle delay has passed,
+// failed attestations are retried with an exponential backoff
+// only the replica holding the attester lease runs it
+type Attester struct {
+	config  *AttesterConfig
+	assets  oracle.AssetProvider
+	orm     *orm.ORM
+	crypto  dlccrypto.CryptoService
+	oracle  *oracle.Oracle
+	feed    datafeed.DataFeed
+	logger  *log.Log
+	leader  *leader
+	metrics *AttesterMetrics
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Start runs the attester periodically in background until Stop is called
+func (a *Attester) Start() {
+	if a.stop != nil {
+		return
+	}
+	a.stop, a.done = make(chan struct{}), make(chan struct{})
+	go a.run(a.stop, a.done)
+}
+
+// Stop stops the attester and releases its lease
+func (a *Attester) Stop() {
+	if a.stop == nil {
+		return
+	}
+	close(a.stop)
+	<-a.done
+	a.stop, a.done = nil, nil
+	if err := a.leader.release(a.orm.GetDB()); err != nil {
+		a.logger.Logger.Warnf("Could not release the attester lease: %v", err)
+	}
+}
+
+// Metrics returns the attester metrics
+func (a *Attester) Metrics() *AttesterMetrics {
+	return a.metrics
+}
+
+func (a *Attester) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(a.config.Interval)
+	defer ticker.Stop()
+	for {
+		if err := a.RunOnce(time.Now().UTC()); err != nil {
+			a.logger.Logger.Errorf("Attestation run failed: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce attests the unsigned events published before now minus the settle delay
+// if the lease is held by another replica nothing is done
+func (a *Attester) RunOnce(now time.Time) error {
+	db := a.orm.GetDB()
+	isLeader, err := a.leader.acquire(db, now)
+	if err != nil {
+		return errors.WithMessage(err, "could not acquire the attester lease")
+	}
+	a.metrics.setLeader(isLeader)
+	if !isLeader {
+		return nil
+	}
+
+	assets, err := a.assets.AssetConfigs()
+	if err != nil {
+		return errors.WithMessage(err, "could not load the assets")
+	}
+	assetIDs := []string{}
+	for assetID, config := range assets {
+		if config.IsAttestable() {
+			assetIDs = append(assetIDs, assetID)
+		}
+	}
+	dlcDataList, err := entity.FindDLCDataToAttest(db, assetIDs, now.Add(-a.config.SettleDelay), now, a.config.BatchSize)
+	if err != nil {
+		return err
+	}
+	for i := range dlcDataList {
+		dlcData := &dlcDataList[i]
+		config := assets[dlcData.AssetID]
+		if err := a.attest(db, config, dlcData, now); err != nil {
+			a.logger.Logger.Warnf(
+				"Could not attest asset %s event %s at %s (attempt %d): %v",
+				dlcData.AssetID, dlcData.EventType, dlcData.PublishedDate.String(), dlcData.Attempts+1, err)
+		}
+	}
+	a.metrics.setLastRun(now)
+	return nil
+}
+
+func (a *Attester) attest(db *gorm.DB, config oracle.AssetConfig, dlcData *entity.DLCData, now time.Time) error {
+	err := a.sign(db, config, dlcData)
+	if err == nil {
+		a.metrics.addAttested(dlcData.PublishedDate)
+		return nil
+	}
+	a.metrics.addFailure()
+	attempts := dlcData.Attempts + 1
+	nextAttemptAt := now.Add(backoff(a.config.InitialBackoff, a.config.MaxBackoff, attempts))
+	if errUpdate := entity.UpdateDLCDataAttestationFailure(
+		db, dlcData.AssetID, dlcData.PublishedDate, dlcData.EventType, attempts, nextAttemptAt, err.Error()); errUpdate != nil {
+		return errors.WithMessagef(errUpdate, "could not store attestation failure %v", err)
+	}
+	return err
+}
+
+// sign attests an event, a panic is returned as an error so that the failure is stored like the others
+func (a *Attester) sign(db *gorm.DB, config oracle.AssetConfig, dlcData *entity.DLCData) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("panic: %v", r)
+			a.logger.Logger.Errorf("Attestation of asset %s event %s panicked: %v\n%s", dlcData.AssetID, dlcData.EventType, r, debug.Stack())
+		}
+	}()
+	asset := oracle.NewValuedAsset(dlcData.AssetID, config, db, a.feed)
+	_, err = oracle.NewService(a.oracle, db, nil, a.crypto).AttestEvent(context.Background(), asset, dlcData)
+	return err
+}
+
+// AttesterMetrics represents the metrics of the attester (the last created one is exposed with prometheus)
+type AttesterMetrics struct {
+	mutex sync.RWMutex
+	stats AttesterStats
+}
+
+// AttesterStats represents a snapshot of the attester metrics
+type AttesterStats struct {
+	IsLeader bool
+	LastRun  time.Time
+	Attested int64
+	Failures int64
+	// LastAttestedPublishDate publish date of the last event attested
+	LastAttestedPublishDate time.Time
+}
+
+func newAttesterMetrics() *AttesterMetrics {
+	metrics := &AttesterMetrics{}
+	register(metrics)
+	return metrics
+}
+
+var (
+	attesterDescs           = newSchedulerDescs("attester")
+	attestedDesc            = prometheus.NewDesc("oracle_scheduler_attested_total", "Number of events attested by the scheduler.", nil, nil)
+	attesterFailuresDesc    = prometheus.NewDesc("oracle_scheduler_attestation_failures_total", "Number of failed attestations.", nil, nil)
+	lastAttestedPublishDesc = prometheus.NewDesc("oracle_scheduler_last_attested_publish_timestamp_seconds",
+		"Publish date of the last event attested by the scheduler.", nil, nil)
+)
+
+// Describe implements prometheus.Collector
+func (m *AttesterMetrics) Describe(ch chan<- *prometheus.Desc) {
+	attesterDescs.describe(ch)
+	ch <- attestedDesc
+	ch <- attesterFailuresDesc
+	ch <- lastAttestedPublishDesc
+}
+
+// Collect implements prometheus.Collector
+func (m *AttesterMetrics) Collect(ch chan<- prometheus.Metric) {
+	stats := m.Stats()
+	attesterDescs.collect(ch, stats.IsLeader, stats.LastRun)
+	ch <- prometheus.MustNewConstMetric(attestedDesc, prometheus.CounterValue, float64(stats.Attested))
+	ch <- prometheus.MustNewConstMetric(attesterFailuresDesc, prometheus.CounterValue, float64(stats.Failures))
+	ch <- prometheus.MustNewConstMetric(lastAttestedPublishDesc, prometheus.GaugeValue, timestamp(stats.LastAttestedPublishDate))
+}
+
+// Stats returns a snapshot of the metrics
+func (m *AttesterMetrics) Stats() AttesterStats {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.stats
+}
+
+func (m *AttesterMetrics) setLeader(isLeader bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.IsLeader = isLeader
+}
+
+func (m *AttesterMetrics) setLastRun(date time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.LastRun = date
+}
+
+func (m *AttesterMetrics) addAttested(publishDate time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.Attested++
+	if publishDate.After(m.stats.LastAttestedPublishDate) {
+		m.stats.LastAttestedPublishDate = publishDate
+	}
+}
+
+func (m *AttesterMetrics) addFailure() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.Failures++
+}
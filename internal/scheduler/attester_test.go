@@ -0,0 +1,184 @@
+package scheduler_test
+
+import (
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/dlccrypto"
+	"p2pderivatives-oracle/internal/oracle"
+	"p2pderivatives-oracle/internal/scheduler"
+	"p2pderivatives-oracle/test"
+	mock_datafeed "p2pderivatives-oracle/test/mock/datafeed"
+	mock_dlccrypto "p2pderivatives-oracle/test/mock/dlccrypto"
+	"testing"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// testAttestNow is two minutes after a publish date
+var testAttestNow = TestAssetConfig.StartDate.Add(10*time.Hour + 2*time.Minute)
+
+func NewTestAttesterConfig() *scheduler.AttesterConfig {
+	return &scheduler.AttesterConfig{
+		Enabled:        true,
+		Interval:       10 * time.Second,
+		SettleDelay:    time.Minute,
+		BatchSize:      10,
+		InitialBackoff: 30 * time.Second,
+		MaxBackoff:     time.Minute,
+		LeaseDuration:  time.Minute,
+	}
+}
+
+func NewTestOracle() *oracle.Oracle {
+	privateKey, _ := dlccrypto.NewPrivateKey(randomHex())
+	publicKey, _ := dlccrypto.NewSchnorrPublicKey(randomHex())
+	return &oracle.Oracle{PrivateKey: privateKey, PublicKey: publicKey}
+}
+
+func NewTestAttester(ormInstance *orm.ORM, crypto dlccrypto.CryptoService, feed *mock_datafeed.MockDataFeed) *scheduler.Attester {
+	electionConfig := TestAssetConfig
+	electionConfig.Asset = "election"
+	return scheduler.NewAttester(
+		NewTestAttesterConfig(),
+		oracle.StaticAssets{"btcusd": TestAssetConfig, "election": electionConfig},
+		ormInstance,
+		crypto,
+		NewTestOracle(),
+		feed,
+		test.NewLogger())
+}
+
+func CreateTestEvent(t *testing.T, ormInstance *orm.ORM, assetID string, publishDate time.Time) {
+	_, err := entity.CreateDLCData(ormInstance.GetDB(), assetID, publishDate, "digits", randomHex(), randomHex())
+	assert.NoError(t, err)
+}
+
+func FindTestEvent(t *testing.T, ormInstance *orm.ORM, assetID string, publishDate time.Time) *entity.DLCData {
+	dlcData, err := entity.FindDLCDataPublishedAt(ormInstance.GetDB(), assetID, publishDate, "digits")
+	assert.NoError(t, err)
+	return dlcData
+}
+
+func NewMockSigningCryptoService(ctrl *gomock.Controller, times int) *mock_dlccrypto.MockCryptoService {
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	signature, _ := dlccrypto.NewSignature(randomHex() + randomHex())
+	crypto.EXPECT().ComputeSchnorrSignature(gomock.Any(), gomock.Any(), "9000").Times(times).Return(signature, nil)
+	return crypto
+}
+
+func TestAttester_RunOnce_AttestsEventsAfterSettleDelay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ormInstance := NewTestOrm()
+	settled := TestAssetConfig.StartDate.Add(10 * time.Hour)
+	notSettled := testAttestNow.Add(-30 * time.Second)
+	future := TestAssetConfig.StartDate.Add(11 * time.Hour)
+	CreateTestEvent(t, ormInstance, "btcusd", settled)
+	CreateTestEvent(t, ormInstance, "btcusd", notSettled)
+	CreateTestEvent(t, ormInstance, "btcusd", future)
+	CreateTestEvent(t, ormInstance, "election", settled)
+	value := 9000.0
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	feed.EXPECT().FindPastAssetPrice("btc", "usd", settled).Return(&value, nil)
+	attester := NewTestAttester(ormInstance, NewMockSigningCryptoService(ctrl, 1), feed)
+
+	err := attester.RunOnce(testAttestNow)
+
+	assert.NoError(t, err)
+	attested := FindTestEvent(t, ormInstance, "btcusd", settled)
+	assert.True(t, attested.IsSigned())
+	assert.Equal(t, "9000", attested.Value)
+	assert.Equal(t, entity.DLCDataStatusAttested, attested.Status)
+	assert.Equal(t, entity.DLCDataStatusAnnounced, FindTestEvent(t, ormInstance, "btcusd", notSettled).Status)
+	assert.Equal(t, entity.DLCDataStatusAnnounced, FindTestEvent(t, ormInstance, "btcusd", future).Status)
+	assert.False(t, FindTestEvent(t, ormInstance, "election", settled).IsSigned())
+	stats := attester.Metrics().Stats()
+	assert.Equal(t, int64(1), stats.Attested)
+	assert.Equal(t, settled, stats.LastAttestedPublishDate)
+}
+
+func TestAttester_RunOnce_Failure_RetriesWithBackoff(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ormInstance := NewTestOrm()
+	publishDate := TestAssetConfig.StartDate.Add(10 * time.Hour)
+	CreateTestEvent(t, ormInstance, "btcusd", publishDate)
+	value := 9000.0
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	gomock.InOrder(
+		feed.EXPECT().FindPastAssetPrice("btc", "usd", publishDate).Return(nil, errors.New("feed error")),
+		feed.EXPECT().FindPastAssetPrice("btc", "usd", publishDate).Return(nil, errors.New("feed error")),
+		feed.EXPECT().FindPastAssetPrice("btc", "usd", publishDate).Return(&value, nil),
+	)
+	attester := NewTestAttester(ormInstance, NewMockSigningCryptoService(ctrl, 1), feed)
+
+	// first failure, retried after the initial backoff
+	assert.NoError(t, attester.RunOnce(testAttestNow))
+	failed := FindTestEvent(t, ormInstance, "btcusd", publishDate)
+	assert.Equal(t, entity.DLCDataStatusFailed, failed.Status)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Contains(t, failed.LastError, "feed error")
+	assert.True(t, testAttestNow.Add(30*time.Second).Equal(*failed.NextAttemptAt))
+
+	// still in backoff, not retried
+	assert.NoError(t, attester.RunOnce(testAttestNow.Add(10*time.Second)))
+
+	// second failure, backoff doubled
+	secondAttempt := testAttestNow.Add(30 * time.Second)
+	assert.NoError(t, attester.RunOnce(secondAttempt))
+	failed = FindTestEvent(t, ormInstance, "btcusd", publishDate)
+	assert.Equal(t, 2, failed.Attempts)
+	assert.True(t, secondAttempt.Add(time.Minute).Equal(*failed.NextAttemptAt))
+
+	assert.NoError(t, attester.RunOnce(secondAttempt.Add(time.Minute)))
+	attested := FindTestEvent(t, ormInstance, "btcusd", publishDate)
+	assert.Equal(t, entity.DLCDataStatusAttested, attested.Status)
+	assert.Equal(t, int64(2), attester.Metrics().Stats().Failures)
+}
+
+func TestAttester_RunOnce_Panic_StoresFailureAndAttestsOthers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ormInstance := NewTestOrm()
+	first := TestAssetConfig.StartDate.Add(9 * time.Hour)
+	second := TestAssetConfig.StartDate.Add(10 * time.Hour)
+	CreateTestEvent(t, ormInstance, "btcusd", first)
+	CreateTestEvent(t, ormInstance, "btcusd", second)
+	value := 9000.0
+	feed := mock_datafeed.NewMockDataFeed(ctrl)
+	feed.EXPECT().FindPastAssetPrice("btc", "usd", gomock.Any()).Return(&value, nil).Times(2)
+	crypto := mock_dlccrypto.NewMockCryptoService(ctrl)
+	signature, _ := dlccrypto.NewSignature(randomHex() + randomHex())
+	gomock.InOrder(
+		crypto.EXPECT().ComputeSchnorrSignature(gomock.Any(), gomock.Any(), "9000").Do(
+			func(interface{}, interface{}, string) { panic("signing failed") }),
+		crypto.EXPECT().ComputeSchnorrSignature(gomock.Any(), gomock.Any(), "9000").Return(signature, nil),
+	)
+	attester := NewTestAttester(ormInstance, crypto, feed)
+
+	assert.NoError(t, attester.RunOnce(testAttestNow))
+
+	failed := FindTestEvent(t, ormInstance, "btcusd", first)
+	assert.Equal(t, entity.DLCDataStatusFailed, failed.Status)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Contains(t, failed.LastError, "signing failed")
+	assert.True(t, testAttestNow.Add(30*time.Second).Equal(*failed.NextAttemptAt))
+	assert.Equal(t, entity.DLCDataStatusAttested, FindTestEvent(t, ormInstance, "btcusd", second).Status)
+	assert.Equal(t, int64(1), attester.Metrics().Stats().Failures)
+}
+
+func TestAttester_RunOnce_LeaseHeldByOther_DoesNothing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ormInstance := NewTestOrm()
+	CreateTestEvent(t, ormInstance, "btcusd", TestAssetConfig.StartDate.Add(10*time.Hour))
+	_, err := entity.AcquireLease(ormInstance.GetDB(), "attester", "other", testAttestNow, time.Hour)
+	assert.NoError(t, err)
+	attester := NewTestAttester(ormInstance, mock_dlccrypto.NewMockCryptoService(ctrl), mock_datafeed.NewMockDataFeed(ctrl))
+
+	assert.NoError(t, attester.RunOnce(testAttestNow))
+	assert.False(t, attester.Metrics().Stats().IsLeader)
+}
@@ -0,0 +1,16 @@
+package scheduler
+
+import "time"
+
+// backoff returns the delay before the next attempt after the given number of failed attempts,
+// starting from the initial delay doubled at each attempt up to the max delay
+func backoff(initial time.Duration, max time.Duration, attempts int) time.Duration {
+	delay := initial
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"p2pderivatives-oracle/internal/database/entity"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// leader elects the replica running a job using a lease stored in database
+type leader struct {
+	name     string
+	holder   string
+	duration time.Duration
+}
+
+func newLeader(name string, duration time.Duration) *leader {
+	return &leader{
+		name:     name,
+		holder:   newHolderID(),
+		duration: duration,
+	}
+}
+
+// acquire acquires or renews the lease, returns false if another replica holds it
+func (l *leader) acquire(db *gorm.DB, now time.Time) (bool, error) {
+	return entity.AcquireLease(db, l.name, l.holder, now, l.duration)
+}
+
+func (l *leader) release(db *gorm.DB) error {
+	return entity.ReleaseLease(db, l.name, l.holder)
+}
+
+// newHolderID returns an identifier unique to this process
+func newHolderID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(b))
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var registerMutex sync.Mutex
+
+// register registers the collector of a scheduler with the prometheus default registry,
+// replacing the collector registered by a previously created scheduler of the same kind
+func register(collector prometheus.Collector) {
+	registerMutex.Lock()
+	defer registerMutex.Unlock()
+	err := prometheus.Register(collector)
+	if already, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		prometheus.Unregister(already.ExistingCollector)
+		err = prometheus.Register(collector)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+// schedulerDescs describes the metrics common to the schedulers
+type schedulerDescs struct {
+	leader  *prometheus.Desc
+	lastRun *prometheus.Desc
+}
+
+func newSchedulerDescs(scheduler string) schedulerDescs {
+	labels := prometheus.Labels{"scheduler": scheduler}
+	return schedulerDescs{
+		leader: prometheus.NewDesc("oracle_scheduler_leader",
+			"1 if the replica holds the scheduler lease.", nil, labels),
+		lastRun: prometheus.NewDesc("oracle_scheduler_last_run_timestamp_seconds",
+			"Time of the last scheduler run.", nil, labels),
+	}
+}
+
+func (d schedulerDescs) describe(ch chan<- *prometheus.Desc) {
+	ch <- d.leader
+	ch <- d.lastRun
+}
+
+func (d schedulerDescs) collect(ch chan<- prometheus.Metric, isLeader bool, lastRun time.Time) {
+	leader := 0.0
+	if isLeader {
+		leader = 1
+	}
+	ch <- prometheus.MustNewConstMetric(d.leader, prometheus.GaugeValue, leader)
+	ch <- prometheus.MustNewConstMetric(d.lastRun, prometheus.GaugeValue, timestamp(lastRun))
+}
+
+// timestamp returns the unix time of a date in seconds, 0 if the date is not set
+func timestamp(date time.Time) float64 {
+	if date.IsZero() {
+		return 0
+	}
+	return float64(date.UnixNano()) / float64(time.Second)
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import "time"
+
+// AnnouncerConfig contains the nonce pre-announcement scheduler configuration
+type AnnouncerConfig struct {
+	Enabled bool `configkey:"scheduler.announcement.enabled"`
+	// Interval interval between two announcement runs
+	Interval time.Duration `configkey:"scheduler.announcement.interval,duration,iso8601" validate:"required" default:"PT1M"`
+	// BatchSize number of events announced between two renewals of the lease
+	BatchSize int `configkey:"scheduler.announcement.batchSize" validate:"min=1" default:"100"`
+	// LeaseDuration duration of the leader lock, should be longer than the interval
+	LeaseDuration time.Duration `configkey:"scheduler.announcement.leaseDuration,duration,iso8601" validate:"required,gtfield=Interval" default:"PT3M"`
+}
+
+// AttesterConfig contains the automatic attestation scheduler configuration
+type AttesterConfig struct {
+	Enabled bool `configkey:"scheduler.attestation.enabled"`
+	// Interval interval between two attestation runs
+	Interval time.Duration `configkey:"scheduler.attestation.interval,duration,iso8601" validate:"required" default:"PT10S"`
+	// SettleDelay delay after the publish date before attesting an event (to let the datafeed settle)
+	SettleDelay time.Duration `configkey:"scheduler.attestation.settleDelay,duration,iso8601" default:"PT1M"`
+	// BatchSize maximum number of events attested in a single run
+	BatchSize int `configkey:"scheduler.attestation.batchSize" validate:"min=1" default:"100"`
+	// InitialBackoff delay before retrying a failed attestation, doubled at each failure up to MaxBackoff
+	InitialBackoff time.Duration `configkey:"scheduler.attestation.initialBackoff,duration,iso8601" validate:"required" default:"PT30S"`
+	MaxBackoff     time.Duration `configkey:"scheduler.attestation.maxBackoff,duration,iso8601" validate:"required,gtefield=InitialBackoff" default:"PT1H"`
+	// LeaseDuration duration of the leader lock, should be longer than the interval
+	LeaseDuration time.Duration `configkey:"scheduler.attestation.leaseDuration,duration,iso8601" validate:"required,gtfield=Interval" default:"PT1M"`
+}
+
+// WebhookConfig contains the webhook notification dispatcher configuration
+type WebhookConfig struct {
+	Enabled bool `configkey:"scheduler.webhook.enabled"`
+	// Interval interval between two dispatch runs
+	Interval time.Duration `configkey:"scheduler.webhook.interval,duration,iso8601" validate:"required" default:"PT5S"`
+	// BatchSize maximum number of notifications and deliveries processed in a single run
+	BatchSize int `configkey:"scheduler.webhook.batchSize" validate:"min=1" default:"100"`
+	// Timeout timeout of a delivery request
+	Timeout time.Duration `configkey:"scheduler.webhook.timeout,duration,iso8601" validate:"required" default:"PT10S"`
+	// InitialBackoff delay before retrying a failed delivery, doubled at each failure up to MaxBackoff
+	InitialBackoff time.Duration `configkey:"scheduler.webhook.initialBackoff,duration,iso8601" validate:"required" default:"PT10S"`
+	MaxBackoff     time.Duration `configkey:"scheduler.webhook.maxBackoff,duration,iso8601" validate:"required,gtefield=InitialBackoff" default:"PT1H"`
+	// MaxAttempts number of attempts after which a delivery is abandoned
+	MaxAttempts int `configkey:"scheduler.webhook.maxAttempts" validate:"min=1" default:"10"`
+	// GapTimeout delay after which a missing notification sequence is considered as rolled back,
+	// the following notifications are not dispatched before it is committed or this delay expires
+	GapTimeout time.Duration `configkey:"scheduler.webhook.gapTimeout,duration,iso8601" validate:"required" default:"PT1M"`
+	// AllowPrivateAddresses delivers to webhooks resolving to loopback, private or link-local addresses (for development only)
+	AllowPrivateAddresses bool `configkey:"scheduler.webhook.allowPrivateAddresses"`
+	// LeaseDuration duration of the leader lock, should be longer than the interval
+	LeaseDuration time.Duration `configkey:"scheduler.webhook.leaseDuration,duration,iso8601" validate:"required,gtfield=Interval" default:"PT1M"`
+}
@@ -0,0 +1,329 @@
+package scheduler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/netguard"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/cryptogarageinc/server-common-go/pkg/log"
+	"github.com/go-resty/resty/v2"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	webhookLeaseName  = "webhook"
+	webhookCursorName = "webhook"
+
+	// WebhookHeaderSignature header containing the HMAC-SHA256 signature of the delivery (sha256=<hex>)
+	WebhookHeaderSignature = "X-Oracle-Signature"
+	// WebhookHeaderTimestamp header containing the unix timestamp of the delivery attempt, part of the signed content
+	WebhookHeaderTimestamp = "X-Oracle-Timestamp"
+	// WebhookHeaderDelivery header containing the delivery id (identical for all attempts of a delivery)
+	WebhookHeaderDelivery = "X-Oracle-Delivery"
+	// WebhookHeaderEvent header containing the notification type (announced or attested)
+	WebhookHeaderEvent = "X-Oracle-Event"
+)
+
+// WebhookPayloadEncoder returns the json body posted to the webhooks for a delivery of a notification
+type WebhookPayloadEncoder func(deliveryID uint, notification *entity.EventNotification, dlcData *entity.DLCData) ([]byte, error)
+
+// SignWebhookPayload returns the signature of a delivery body as sent in the signature header,
+// the HMAC-SHA256 with the webhook secret of `<timestamp>.<body>`
+func SignWebhookPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewWebhookDispatcher returns a new webhook notification dispatcher (not started),
+// the deliveries to non public addresses are refused unless allowed by the configuration
+func NewWebhookDispatcher(config *WebhookConfig, orm *orm.ORM, encoder WebhookPayloadEncoder, log *log.Log) *WebhookDispatcher {
+	guard := &netguard.Guard{AllowPrivate: config.AllowPrivateAddresses}
+	httpClient := resty.New()
+	// the address is checked at each connection (including redirects), no proxy is used
+	httpClient.SetTransport(&http.Transport{
+		DialContext:         guard.Dialer(&net.Dialer{Timeout: config.Timeout}).DialContext,
+		TLSHandshakeTimeout: config.Timeout,
+	})
+	httpClient.SetTimeout(config.Timeout)
+	httpClient.SetHeader("Content-Type", "application/json")
+	httpClient.SetHeader("User-Agent", "p2pdoracle-webhook")
+	return &WebhookDispatcher{
+		config:     config,
+		orm:        orm,
+		encoder:    encoder,
+		logger:     log,
+		httpClient: httpClient,
+		leader:     newLeader(webhookLeaseName, config.LeaseDuration),
+		metrics:    newWebhookMetrics(),
+	}
+}
+
+// WebhookDispatcher creates a delivery for each event notification and matching webhook (delivery log)
+// and posts them, failed deliveries are retried with an exponential backoff until max attempts
+// only the replica holding the webhook lease runs it
+type WebhookDispatcher struct {
+	config     *WebhookConfig
+	orm        *orm.ORM
+	encoder    WebhookPayloadEncoder
+	logger     *log.Log
+	httpClient *resty.Client
+	leader     *leader
+	metrics    *WebhookMetrics
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// Start runs the dispatcher periodically in background until Stop is called
+func (d *WebhookDispatcher) Start() {
+	if d.stop != nil {
+		return
+	}
+	d.stop, d.done = make(chan struct{}), make(chan struct{})
+	go d.run(d.stop, d.done)
+}
+
+// Stop stops the dispatcher and releases its lease
+func (d *WebhookDispatcher) Stop() {
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	<-d.done
+	d.stop, d.done = nil, nil
+	if err := d.leader.release(d.orm.GetDB()); err != nil {
+		d.logger.Logger.Warnf("Could not release the webhook lease: %v", err)
+	}
+}
+
+// Metrics returns the dispatcher metrics
+func (d *WebhookDispatcher) Metrics() *WebhookMetrics {
+	return d.metrics
+}
+
+func (d *WebhookDispatcher) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+	for {
+		if err := d.RunOnce(time.Now().UTC()); err != nil {
+			d.logger.Logger.Errorf("Webhook dispatch run failed: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce creates the deliveries of the new notifications and sends the deliveries due at now
+// if the lease is held by another replica nothing is done
+func (d *WebhookDispatcher) RunOnce(now time.Time) error {
+	db := d.orm.GetDB()
+	isLeader, err := d.leader.acquire(db, now)
+	if err != nil {
+		return errors.WithMessage(err, "could not acquire the webhook lease")
+	}
+	d.metrics.setLeader(isLeader)
+	if !isLeader {
+		return nil
+	}
+
+	if err := d.createDeliveries(db, now); err != nil {
+		return err
+	}
+
+	deliveries, err := entity.FindWebhookDeliveriesToSend(db, now, d.config.BatchSize)
+	if err != nil {
+		return err
+	}
+	for i := range deliveries {
+		if err := d.deliver(db, &deliveries[i], now); err != nil {
+			return err
+		}
+	}
+	d.metrics.setLastRun(now)
+	return nil
+}
+
+// createDeliveries creates the deliveries of the notifications after the dispatch cursor,
+// the cursor is not moved past a missing notification sequence until it is committed or the gap timeout expires
+func (d *WebhookDispatcher) createDeliveries(db *gorm.DB, now time.Time) error {
+	sequence, err := entity.FindCursorSequence(db, webhookCursorName)
+	if err != nil {
+		return err
+	}
+	notifications, err := entity.FindEventNotificationsAfter(db, sequence, now.Add(-d.config.GapTimeout), d.config.BatchSize)
+	if err != nil || len(notifications) == 0 {
+		return err
+	}
+	webhooks, err := entity.FindWebhooks(db)
+	if err != nil {
+		return err
+	}
+	return entity.CreateWebhookDeliveries(db, webhookCursorName, webhooks, notifications, now)
+}
+
+// deliver sends a delivery and stores the attempt result, an error is only returned if it cannot be stored
+func (d *WebhookDispatcher) deliver(db *gorm.DB, delivery *entity.WebhookDelivery, now time.Time) error {
+	delivery.Attempts++
+	webhook, err := entity.FindWebhook(db, delivery.WebhookID)
+	if err == nil {
+		var status int
+		status, err = d.send(db, webhook, delivery)
+		delivery.ResponseStatus = status
+	} else if gorm.IsRecordNotFoundError(err) {
+		// deleted webhook
+		delivery.Attempts = d.config.MaxAttempts
+	}
+
+	if err == nil {
+		delivery.Status = entity.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		d.metrics.addDelivered()
+	} else {
+		delivery.LastError = err.Error()
+		d.metrics.addFailure()
+		if delivery.Attempts >= d.config.MaxAttempts {
+			delivery.Status = entity.WebhookDeliveryAbandoned
+			d.metrics.addAbandoned()
+			d.logger.Logger.Warnf("Abandoned webhook delivery %d after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+		} else {
+			delivery.Status = entity.WebhookDeliveryFailed
+			delivery.NextAttemptAt = now.Add(backoff(d.config.InitialBackoff, d.config.MaxBackoff, delivery.Attempts))
+		}
+	}
+	return entity.UpdateWebhookDelivery(db, delivery)
+}
+
+// send posts the notification of the delivery, returns the response status
+func (d *WebhookDispatcher) send(db *gorm.DB, webhook *entity.Webhook, delivery *entity.WebhookDelivery) (int, error) {
+	body, notificationType, err := d.newPayload(db, delivery)
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	resp, err := d.httpClient.R().
+		SetHeader(WebhookHeaderSignature, SignWebhookPayload(webhook.Secret, timestamp, body)).
+		SetHeader(WebhookHeaderTimestamp, timestamp).
+		SetHeader(WebhookHeaderDelivery, fmt.Sprint(delivery.ID)).
+		SetHeader(WebhookHeaderEvent, notificationType).
+		SetBody(body).
+		Post(webhook.URL)
+	if err != nil {
+		return 0, errors.WithMessage(err, "could not send the webhook request")
+	}
+	if resp.IsError() {
+		return resp.StatusCode(), errors.Errorf("webhook responded with status %d", resp.StatusCode())
+	}
+	return resp.StatusCode(), nil
+}
+
+func (d *WebhookDispatcher) newPayload(db *gorm.DB, delivery *entity.WebhookDelivery) ([]byte, string, error) {
+	notification, err := entity.FindEventNotification(db, delivery.NotificationSequence)
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "could not find the notification")
+	}
+	dlcData, err := entity.FindDLCDataPublishedAt(db, notification.AssetID, notification.PublishedDate, notification.EventType)
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "could not find the notified event")
+	}
+	body, err := d.encoder(delivery.ID, notification, dlcData)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, notification.Type, nil
+}
+
+// WebhookMetrics represents the metrics of the webhook dispatcher (the last created one is exposed with prometheus)
+type WebhookMetrics struct {
+	mutex sync.RWMutex
+	stats WebhookStats
+}
+
+// WebhookStats represents a snapshot of the webhook dispatcher metrics
+type WebhookStats struct {
+	IsLeader  bool
+	LastRun   time.Time
+	Delivered int64
+	Failures  int64
+	Abandoned int64
+}
+
+func newWebhookMetrics() *WebhookMetrics {
+	metrics := &WebhookMetrics{}
+	register(metrics)
+	return metrics
+}
+
+var (
+	webhookDescs          = newSchedulerDescs("webhook")
+	webhookDeliveriesDesc = prometheus.NewDesc("oracle_scheduler_webhook_deliveries_total",
+		"Number of webhook delivery attempts by result (delivered, failure, abandoned).", []string{"result"}, nil)
+)
+
+// Describe implements prometheus.Collector
+func (m *WebhookMetrics) Describe(ch chan<- *prometheus.Desc) {
+	webhookDescs.describe(ch)
+	ch <- webhookDeliveriesDesc
+}
+
+// Collect implements prometheus.Collector
+func (m *WebhookMetrics) Collect(ch chan<- prometheus.Metric) {
+	stats := m.Stats()
+	webhookDescs.collect(ch, stats.IsLeader, stats.LastRun)
+	ch <- prometheus.MustNewConstMetric(webhookDeliveriesDesc, prometheus.CounterValue, float64(stats.Delivered), "delivered")
+	ch <- prometheus.MustNewConstMetric(webhookDeliveriesDesc, prometheus.CounterValue, float64(stats.Failures), "failure")
+	ch <- prometheus.MustNewConstMetric(webhookDeliveriesDesc, prometheus.CounterValue, float64(stats.Abandoned), "abandoned")
+}
+
+// Stats returns a snapshot of the metrics
+func (m *WebhookMetrics) Stats() WebhookStats {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.stats
+}
+
+func (m *WebhookMetrics) setLeader(isLeader bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.IsLeader = isLeader
+}
+
+func (m *WebhookMetrics) setLastRun(date time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.LastRun = date
+}
+
+func (m *WebhookMetrics) addDelivered() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.Delivered++
+}
+
+func (m *WebhookMetrics) addFailure() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.Failures++
+}
+
+func (m *WebhookMetrics) addAbandoned() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.stats.Abandoned++
+}
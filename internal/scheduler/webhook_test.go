@@ -0,0 +1,200 @@
+package scheduler_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"p2pderivatives-oracle/internal/api"
+	"p2pderivatives-oracle/internal/database/entity"
+	"p2pderivatives-oracle/internal/scheduler"
+	"p2pderivatives-oracle/test"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	"github.com/stretchr/testify/assert"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+type receivedWebhook struct {
+	header  http.Header
+	body    []byte
+	payload api.WebhookPayload
+}
+
+// NewTestWebhookReceiver returns a webhook receiver responding with the given status
+func NewTestWebhookReceiver(t *testing.T, status int) (*httptest.Server, func() []receivedWebhook) {
+	var mutex sync.Mutex
+	received := []receivedWebhook{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		payload := api.WebhookPayload{}
+		assert.NoError(t, json.Unmarshal(body, &payload))
+		mutex.Lock()
+		received = append(received, receivedWebhook{header: r.Header, body: body, payload: payload})
+		mutex.Unlock()
+		w.WriteHeader(status)
+	}))
+	return server, func() []receivedWebhook {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return received
+	}
+}
+
+func NewTestWebhookConfig() *scheduler.WebhookConfig {
+	return &scheduler.WebhookConfig{
+		Enabled:        true,
+		Interval:       5 * time.Second,
+		BatchSize:      10,
+		Timeout:        time.Second,
+		InitialBackoff: 10 * time.Second,
+		MaxBackoff:     time.Minute,
+		MaxAttempts:    2,
+		GapTimeout:     time.Minute,
+		// the test receivers listen on the loopback
+		AllowPrivateAddresses: true,
+		LeaseDuration:         time.Minute,
+	}
+}
+
+func NewTestWebhookOrm() *orm.ORM {
+	return test.NewOrm(
+		&entity.Asset{},
+		&entity.DLCData{},
+		&entity.EventNotification{},
+		&entity.Lease{},
+		&entity.Cursor{},
+		&entity.Webhook{},
+		&entity.WebhookDelivery{})
+}
+
+func NewTestWebhookDispatcher(ormInstance *orm.ORM) *scheduler.WebhookDispatcher {
+	return NewTestWebhookDispatcherWithConfig(ormInstance, NewTestWebhookConfig())
+}
+
+func NewTestWebhookDispatcherWithConfig(ormInstance *orm.ORM, config *scheduler.WebhookConfig) *scheduler.WebhookDispatcher {
+	return scheduler.NewWebhookDispatcher(
+		config, ormInstance, api.NewWebhookPayloadEncoder(NewTestOracle().PublicKey), test.NewLogger())
+}
+
+func TestWebhookDispatcher_RunOnce_DeliversSignedNotifications(t *testing.T) {
+	ormInstance := NewTestWebhookOrm()
+	db := ormInstance.GetDB()
+	server, received := NewTestWebhookReceiver(t, http.StatusOK)
+	defer server.Close()
+	webhook, err := entity.CreateWebhook(db, "apikey:test", server.URL, []string{"btcusd"}, testWebhookSecret)
+	assert.NoError(t, err)
+	publishDate := TestAssetConfig.StartDate.Add(10 * time.Hour)
+	CreateTestEvent(t, ormInstance, "btcusd", publishDate)
+	CreateTestEvent(t, ormInstance, "ethusd", publishDate)
+	_, err = entity.UpdateDLCDataAttestation(db, "btcusd", publishDate, "digits", entity.DLCData{Signature: "sig", Value: "9000"})
+	assert.NoError(t, err)
+	dispatcher := NewTestWebhookDispatcher(ormInstance)
+
+	assert.NoError(t, dispatcher.RunOnce(testAttestNow))
+
+	deliveries := received()
+	if assert.Len(t, deliveries, 2) {
+		announced, attested := deliveries[0], deliveries[1]
+		assert.Equal(t, entity.EventNotificationAnnounced, announced.payload.Type)
+		assert.Equal(t, "btcusd", announced.payload.Event.AssetID)
+		assert.Empty(t, announced.payload.Event.Signature)
+		assert.Equal(t, entity.EventNotificationAttested, attested.payload.Type)
+		assert.Equal(t, "sig", attested.payload.Event.Signature)
+		assert.Equal(t, "9000", attested.payload.Event.Value)
+		assert.Equal(t, entity.EventNotificationAttested, attested.header.Get(scheduler.WebhookHeaderEvent))
+		expectedSignature := scheduler.SignWebhookPayload(testWebhookSecret, attested.header.Get(scheduler.WebhookHeaderTimestamp), attested.body)
+		assert.Equal(t, expectedSignature, attested.header.Get(scheduler.WebhookHeaderSignature))
+	}
+	log, err := entity.FindWebhookDeliveries(db, webhook.ID, entity.WebhookDeliveryDelivered, 10)
+	assert.NoError(t, err)
+	assert.Len(t, log, 2)
+
+	// already delivered, nothing sent again
+	assert.NoError(t, dispatcher.RunOnce(testAttestNow.Add(time.Minute)))
+	assert.Len(t, received(), 2)
+	assert.Equal(t, int64(2), dispatcher.Metrics().Stats().Delivered)
+}
+
+func TestWebhookDispatcher_RunOnce_Failure_RetriesThenAbandons(t *testing.T) {
+	ormInstance := NewTestWebhookOrm()
+	db := ormInstance.GetDB()
+	server, received := NewTestWebhookReceiver(t, http.StatusInternalServerError)
+	defer server.Close()
+	webhook, err := entity.CreateWebhook(db, "apikey:test", server.URL, []string{}, testWebhookSecret)
+	assert.NoError(t, err)
+	CreateTestEvent(t, ormInstance, "btcusd", TestAssetConfig.StartDate.Add(10*time.Hour))
+	dispatcher := NewTestWebhookDispatcher(ormInstance)
+
+	assert.NoError(t, dispatcher.RunOnce(testAttestNow))
+	log, err := entity.FindWebhookDeliveries(db, webhook.ID, "", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, log, 1) {
+		assert.Equal(t, entity.WebhookDeliveryFailed, log[0].Status)
+		assert.Equal(t, 1, log[0].Attempts)
+		assert.Equal(t, http.StatusInternalServerError, log[0].ResponseStatus)
+		assert.True(t, testAttestNow.Add(10*time.Second).Equal(log[0].NextAttemptAt))
+	}
+
+	// in backoff
+	assert.NoError(t, dispatcher.RunOnce(testAttestNow.Add(5*time.Second)))
+	assert.Len(t, received(), 1)
+
+	// max attempts reached
+	assert.NoError(t, dispatcher.RunOnce(testAttestNow.Add(10*time.Second)))
+	assert.Len(t, received(), 2)
+	log, err = entity.FindWebhookDeliveries(db, webhook.ID, "", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, log, 1) {
+		assert.Equal(t, entity.WebhookDeliveryAbandoned, log[0].Status)
+		assert.Equal(t, 2, log[0].Attempts)
+	}
+	assert.Equal(t, int64(1), dispatcher.Metrics().Stats().Abandoned)
+}
+
+func TestWebhookDispatcher_RunOnce_LoopbackWebhook_RefusesDelivery(t *testing.T) {
+	ormInstance := NewTestWebhookOrm()
+	db := ormInstance.GetDB()
+	server, received := NewTestWebhookReceiver(t, http.StatusOK)
+	defer server.Close()
+	webhook, err := entity.CreateWebhook(db, "apikey:test", server.URL, []string{}, testWebhookSecret)
+	assert.NoError(t, err)
+	CreateTestEvent(t, ormInstance, "btcusd", TestAssetConfig.StartDate.Add(10*time.Hour))
+	config := NewTestWebhookConfig()
+	config.AllowPrivateAddresses = false
+	dispatcher := NewTestWebhookDispatcherWithConfig(ormInstance, config)
+
+	assert.NoError(t, dispatcher.RunOnce(testAttestNow))
+
+	assert.Empty(t, received())
+	log, err := entity.FindWebhookDeliveries(db, webhook.ID, "", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, log, 1) {
+		assert.Equal(t, entity.WebhookDeliveryFailed, log[0].Status)
+		assert.Contains(t, log[0].LastError, "not a public address")
+	}
+}
+
+func TestWebhookDispatcher_RunOnce_WebhookCreatedAfterNotification_NotNotified(t *testing.T) {
+	ormInstance := NewTestWebhookOrm()
+	db := ormInstance.GetDB()
+	server, received := NewTestWebhookReceiver(t, http.StatusOK)
+	defer server.Close()
+	CreateTestEvent(t, ormInstance, "btcusd", TestAssetConfig.StartDate.Add(10*time.Hour))
+	dispatcher := NewTestWebhookDispatcher(ormInstance)
+	assert.NoError(t, dispatcher.RunOnce(testAttestNow))
+
+	_, err := entity.CreateWebhook(db, "apikey:test", server.URL, []string{}, testWebhookSecret)
+	assert.NoError(t, err)
+	CreateTestEvent(t, ormInstance, "btcusd", TestAssetConfig.StartDate.Add(11*time.Hour))
+	assert.NoError(t, dispatcher.RunOnce(testAttestNow.Add(time.Second)))
+
+	if assert.Len(t, received(), 1) {
+		assert.Equal(t, TestAssetConfig.StartDate.Add(11*time.Hour), received()[0].payload.Event.PublishedDate)
+	}
+}
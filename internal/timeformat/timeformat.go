@@ -0,0 +1,47 @@
+package timeformat
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ISO8601 canonical time format of the oracle (UTC, second precision)
+	ISO8601 = "2006-01-02T15:04:05Z"
+)
+
+// unix timestamps with an absolute value at least this big are considered as milliseconds
+// (as seconds it would be later than year 5000)
+const unixMillisecondsThreshold = 100000000000
+
+// Parse parses a time given as RFC3339 (with any offset and optional fractional seconds),
+// unix seconds or unix milliseconds and returns it in UTC
+func Parse(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		var timestamp time.Time
+		if unix >= unixMillisecondsThreshold || unix <= -unixMillisecondsThreshold {
+			timestamp = time.Unix(0, unix*int64(time.Millisecond))
+		} else {
+			timestamp = time.Unix(unix, 0)
+		}
+		utc := timestamp.UTC()
+		return &utc, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil && strings.Contains(value, " ") {
+		// unescaped + of a positive offset in a query string decoded as a space
+		timestamp, err = time.Parse(time.RFC3339Nano, strings.Replace(value, " ", "+", 1))
+	}
+	if err != nil {
+		return nil, errors.Errorf(
+			"Invalid time format %s ! You should use RFC3339/ISO8601 ex: %s, unix seconds or milliseconds",
+			value, ISO8601)
+	}
+	utc := timestamp.UTC()
+	return &utc, nil
+}
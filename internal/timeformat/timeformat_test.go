@@ -0,0 +1,47 @@
+package timeformat_test
+
+import (
+	"p2pderivatives-oracle/internal/timeformat"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_ValidFormats_ReturnsUTC(t *testing.T) {
+	expected := time.Date(2020, time.May, 12, 8, 0, 0, 0, time.UTC)
+	values := []string{
+		"2020-05-12T08:00:00Z",
+		"2020-05-12T08:00:00+00:00",
+		"2020-05-12T10:00:00+02:00",
+		"2020-05-12T03:00:00-05:00",
+		"2020-05-12T17:00:00 09:00",
+		"1589270400",
+		"1589270400000",
+	}
+	for _, value := range values {
+		actual, err := timeformat.Parse(value)
+		if assert.NoError(t, err, value) {
+			assert.Equal(t, expected, *actual, value)
+			assert.Equal(t, time.UTC, actual.Location(), value)
+		}
+	}
+}
+
+func TestParse_FractionalSeconds_KeepsPrecision(t *testing.T) {
+	actual, err := timeformat.Parse("2020-05-12T08:00:00.250Z")
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Date(2020, time.May, 12, 8, 0, 0, 250000000, time.UTC), *actual)
+	}
+	actual, err = timeformat.Parse("1589270400250")
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Date(2020, time.May, 12, 8, 0, 0, 250000000, time.UTC), *actual)
+	}
+}
+
+func TestParse_InvalidFormats_ReturnsError(t *testing.T) {
+	for _, value := range []string{"", "2020-05-12", "2020-05-12 08:00:00", "12/05/2020", "1589270400.5", "now"} {
+		_, err := timeformat.Parse(value)
+		assert.Error(t, err, value)
+	}
+}
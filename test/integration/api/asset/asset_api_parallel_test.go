@@ -3,10 +3,13 @@
 package assetapi_test
 
 import (
+	"bufio"
 	"fmt"
 	"net/http"
 	"p2pderivatives-oracle/internal/api"
 	helper "p2pderivatives-oracle/test/integration"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -123,3 +126,64 @@ func TestGetAssetSignature_Concurrent_WithValidTime_ReturnsCorrectValue(t *testi
 		})
 	}
 }
+
+const keygensMetric = `oracle_keygens_total{result="success"}`
+
+// GetKeygens returns the number of key pairs generated by the oracle (from /metrics)
+func GetKeygens(t *testing.T) int {
+	resp, err := helper.CreateDefaultClient().R().SetHeader("Accept", "text/plain").Get(api.RouteGETMetrics)
+	if err != nil || resp.StatusCode() != http.StatusOK {
+		t.Fatalf("Could not get the oracle metrics: %v %s", err, resp.String())
+	}
+	scanner := bufio.NewScanner(strings.NewReader(resp.String()))
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, keygensMetric+" ") {
+			value, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(line, keygensMetric)), 64)
+			if err != nil {
+				t.Fatalf("Invalid keygens metric %s", line)
+			}
+			return int(value)
+		}
+	}
+	return 0
+}
+
+// not parallel so that no other test of the package announces events meanwhile
+func TestGetAssetRvalue_ConcurrentNewEvents_GeneratesOneKeyPairPerEvent(t *testing.T) {
+	assertSub := assert.New(t)
+	// event type not requested before, so that all the events are new
+	eventType := fmt.Sprintf("above(%d)", time.Now().UnixNano())
+	keygensBefore := GetKeygens(t)
+
+	handler := helper.NewHttpConcurrentHandler()
+	for asset := range helper.APIConfig.AssetConfigs {
+		for _, dur := range TestDuration {
+			route := GetRouteAssetRvalue(asset, Now.Add(dur))
+			for i := 0; i < 10*RepeatRequestCounter; i++ {
+				client := helper.CreateDefaultClient()
+				req := client.R().SetResult(&api.DLCDataResponse{}).SetQueryParam(api.URLQueryTagEventType, eventType)
+				handler.RegisterRequest(req, resty.MethodGet, route)
+			}
+		}
+	}
+
+	results := handler.RunAndWait()
+
+	// rvalue of each event
+	events := map[string]string{}
+	for _, r := range results {
+		if assertSub.NoError(r.Error, "Error while sending the request") {
+			resp := r.Response
+			if assertSub.Equal(http.StatusOK, resp.StatusCode(), resp.String()) {
+				actual := resp.Result().(*api.DLCDataResponse)
+				eventID := api.NewEventID(actual.AssetID, eventType, actual.PublishedDate)
+				if rvalue, ok := events[eventID]; ok {
+					assertSub.Equal(rvalue, actual.Rvalue, eventID)
+				} else {
+					events[eventID] = actual.Rvalue
+				}
+			}
+		}
+	}
+	assertSub.Equal(len(events), GetKeygens(t)-keygensBefore, "one key pair should be generated per event")
+}
@@ -0,0 +1,64 @@
+// +build integration
+
+package database_test
+
+import (
+	"fmt"
+	"os"
+	"p2pderivatives-oracle/internal/database/entity"
+	helper "p2pderivatives-oracle/test/integration"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	helper.InitHelper()
+	os.Exit(m.Run())
+}
+
+func Test_CreateDLCDataIfNotExists_Concurrent_CreatesOnceAndReturnsStored(t *testing.T) {
+	db := helper.NewOrm().GetDB()
+	defer db.Close()
+	// a publish date not used by the other tests, the second insert is ignored by the ON CONFLICT clause
+	publishDate := time.Now().UTC().Add(100 * 24 * time.Hour).Truncate(time.Second)
+
+	const concurrency = 2
+	type result struct {
+		dlcData *entity.DLCData
+		created bool
+		err     error
+	}
+	var started sync.WaitGroup
+	started.Add(1)
+	var wg sync.WaitGroup
+	results := make(chan result, concurrency)
+	for i := 0; i < concurrency; i++ {
+		rvalue := fmt.Sprintf("concurrent-r%d-%d", publishDate.Unix(), i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Wait()
+			dlcData, created, err := entity.CreateDLCDataIfNotExists(db, "btcusd", publishDate, "concurrent", "k"+rvalue, rvalue)
+			results <- result{dlcData, created, err}
+		}()
+	}
+	started.Done()
+	wg.Wait()
+	close(results)
+
+	created := 0
+	rvalues := map[string]bool{}
+	for r := range results {
+		require.NoError(t, r.err)
+		if r.created {
+			created++
+		}
+		rvalues[r.dlcData.Rvalue] = true
+	}
+	assert.Equal(t, 1, created)
+	assert.Len(t, rvalues, 1, "all the callers return the stored event")
+}
@@ -6,6 +6,9 @@ import (
 	"flag"
 	"fmt"
 	conf "github.com/cryptogarageinc/server-common-go/pkg/configuration"
+	"github.com/cryptogarageinc/server-common-go/pkg/database/orm"
+	oraclelog "github.com/cryptogarageinc/server-common-go/pkg/log"
+	"io/ioutil"
 	"log"
 	"os"
 	"p2pderivatives-oracle/internal/api"
@@ -118,3 +121,26 @@ func CreateDefaultClient() *resty.Client {
 	client.SetHeader("Accept", "application/json")
 	return client
 }
+
+// NewOrm returns an orm connected to the database of the oracle (migrated by the oracle server)
+func NewOrm() *orm.ORM {
+	logConfig := &oraclelog.Config{}
+	if err := Config.InitializeComponentConfig(logConfig); err != nil {
+		log.Fatal("Could not read log configuration.")
+	}
+	logger := oraclelog.NewLog(logConfig)
+	if err := logger.Initialize(); err != nil {
+		log.Fatalf("Could not initialize log. %v", err)
+	}
+	logger.Logger.SetOutput(ioutil.Discard)
+
+	ormConfig := &orm.Config{}
+	if err := Config.InitializeComponentConfig(ormConfig); err != nil {
+		log.Fatal("Could not read database configuration.")
+	}
+	ormInstance := orm.NewORM(ormConfig, logger)
+	if err := ormInstance.Initialize(); err != nil {
+		log.Fatalf("Could not initialize database. %v", err)
+	}
+	return ormInstance
+}
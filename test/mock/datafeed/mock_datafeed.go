@@ -6,6 +6,7 @@ package mock_datafeed
 
 import (
 	gomock "github.com/golang/mock/gomock"
+	datafeed "p2pderivatives-oracle/internal/datafeed"
 	reflect "reflect"
 	time "time"
 )
@@ -63,6 +64,21 @@ func (mr *MockDataFeedMockRecorder) FindPastAssetPrice(assetID, currency, date i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPastAssetPrice", reflect.TypeOf((*MockDataFeed)(nil).FindPastAssetPrice), assetID, currency, date)
 }
 
+// FindPastAssetCandles mocks base method.
+func (m *MockDataFeed) FindPastAssetCandles(assetID, currency string, from, to time.Time) ([]datafeed.Candle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPastAssetCandles", assetID, currency, from, to)
+	ret0, _ := ret[0].([]datafeed.Candle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPastAssetCandles indicates an expected call of FindPastAssetCandles.
+func (mr *MockDataFeedMockRecorder) FindPastAssetCandles(assetID, currency, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPastAssetCandles", reflect.TypeOf((*MockDataFeed)(nil).FindPastAssetCandles), assetID, currency, from, to)
+}
+
 // MockAssetPriceFeed is a mock of AssetPriceFeed interface.
 type MockAssetPriceFeed struct {
 	ctrl     *gomock.Controller
@@ -115,3 +131,41 @@ func (mr *MockAssetPriceFeedMockRecorder) FindPastAssetPrice(assetID, currency,
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPastAssetPrice", reflect.TypeOf((*MockAssetPriceFeed)(nil).FindPastAssetPrice), assetID, currency, date)
 }
+
+// MockAssetCandleFeed is a mock of AssetCandleFeed interface.
+type MockAssetCandleFeed struct {
+	ctrl     *gomock.Controller
+	recorder *MockAssetCandleFeedMockRecorder
+}
+
+// MockAssetCandleFeedMockRecorder is the mock recorder for MockAssetCandleFeed.
+type MockAssetCandleFeedMockRecorder struct {
+	mock *MockAssetCandleFeed
+}
+
+// NewMockAssetCandleFeed creates a new mock instance.
+func NewMockAssetCandleFeed(ctrl *gomock.Controller) *MockAssetCandleFeed {
+	mock := &MockAssetCandleFeed{ctrl: ctrl}
+	mock.recorder = &MockAssetCandleFeedMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAssetCandleFeed) EXPECT() *MockAssetCandleFeedMockRecorder {
+	return m.recorder
+}
+
+// FindPastAssetCandles mocks base method.
+func (m *MockAssetCandleFeed) FindPastAssetCandles(assetID, currency string, from, to time.Time) ([]datafeed.Candle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPastAssetCandles", assetID, currency, from, to)
+	ret0, _ := ret[0].([]datafeed.Candle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPastAssetCandles indicates an expected call of FindPastAssetCandles.
+func (mr *MockAssetCandleFeedMockRecorder) FindPastAssetCandles(assetID, currency, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPastAssetCandles", reflect.TypeOf((*MockAssetCandleFeed)(nil).FindPastAssetCandles), assetID, currency, from, to)
+}
@@ -6,5 +6,6 @@ package tools
 import (
 	_ "github.com/golang/mock/gomock"
 	_ "github.com/golang/mock/mockgen"
+	_ "github.com/golang/protobuf/protoc-gen-go"
 	_ "gotest.tools/gotestsum"
 )